@@ -24,7 +24,10 @@ var selectRate = 0.1
 func TestEnginePerf() {
 
 	f := new(read.TsFileSequenceReader)
-	f.Open(filePath)
+	if err := f.Open(filePath); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
 	engine := new(engine.Engine)
 	engine.Open(f)
 	defer func() {
@@ -33,7 +33,7 @@ func writeTsFile(fileName string, fileInFile string, strDeviceID string, strSens
 	iType constant.TSDataType, iEncode constant.TSEncoding, iCachSize int) time.Duration {
 	defer func() {
 		if err := recover(); err != nil {
-			log.Info("Error: ", err)
+			log.Info("Error: %v", err)
 		}
 	}()
 	if _, err := os.Stat(fileName); !os.IsNotExist(err) {
@@ -39,8 +39,8 @@ func TestRead(t *testing.T) {
 		for i := 0; i < int(groupHeader.GetNumberOfChunks()); i++ {
 			chunkHeader := f.ReadChunkHeader()
 			log.Println("  chunk: " + chunkHeader.GetSensor() + ", page number: " + strconv.Itoa(chunkHeader.GetNumberOfPages()) + ", end posistion: " + strconv.FormatInt(f.Pos(), 10))
-			defaultTimeDecoder := decoder.CreateDecoder(constant.PLAIN, constant.INT64)
-			valueDecoder := decoder.CreateDecoder(chunkHeader.GetEncodingType(), chunkHeader.GetDataType())
+			defaultTimeDecoder, _ := decoder.CreateDecoder(constant.PLAIN, constant.INT64)
+			valueDecoder, _ := decoder.CreateDecoder(chunkHeader.GetEncodingType(), chunkHeader.GetDataType())
 			for j := 0; j < chunkHeader.GetNumberOfPages(); j++ {
 				pageHeader := f.ReadPageHeader(chunkHeader.GetDataType())
 				log.Println("    page dps: " + strconv.Itoa(int(pageHeader.GetNumberOfValues())) + ", page data size: " + strconv.Itoa(int(pageHeader.GetCompressedSize())) + ", end posistion: " + strconv.FormatInt(f.Pos(), 10))
@@ -21,7 +21,10 @@ func TestRead(strPath string) {
 
 	//file := "goout/output1.ts"
 	f := new(read.TsFileSequenceReader)
-	f.Open(strPath)
+	if err := f.Open(strPath); err != nil {
+		log.Println("Error:", err)
+		return
+	}
 	defer f.Close()
 
 	headerString := f.ReadHeadMagic()
@@ -39,14 +42,22 @@ func TestRead(strPath string) {
 		for i := 0; i < int(groupHeader.GetNumberOfChunks()); i++ {
 			chunkHeader := f.ReadChunkHeader()
 			log.Println("  chunk: " + chunkHeader.GetSensor() + ", page number: " + strconv.Itoa(chunkHeader.GetNumberOfPages()) + ", end posistion: " + strconv.FormatInt(f.Pos(), 10))
-			defaultTimeDecoder := decoder.CreateDecoder(constant.TS_2DIFF, constant.INT64)
-			valueDecoder := decoder.CreateDecoder(chunkHeader.GetEncodingType(), chunkHeader.GetDataType())
+			timeDecoder, err := decoder.CreateDecoder(chunkHeader.GetTimeEncodingType(), constant.INT64)
+			if err != nil {
+				log.Println("Error:", err)
+				return
+			}
+			valueDecoder, err := decoder.CreateDecoder(chunkHeader.GetEncodingType(), chunkHeader.GetDataType())
+			if err != nil {
+				log.Println("Error:", err)
+				return
+			}
 			for j := 0; j < chunkHeader.GetNumberOfPages(); j++ {
 				pageHeader := f.ReadPageHeader(chunkHeader.GetDataType())
 				log.Println("    page dps: " + strconv.Itoa(int(pageHeader.GetNumberOfValues())) + ", page data size: " + strconv.Itoa(int(pageHeader.GetCompressedSize())) + ", end posistion: " + strconv.FormatInt(f.Pos(), 10))
 
 				pageData := f.ReadPage(pageHeader, chunkHeader.GetCompressionType())
-				reader1 := &basic.PageDataReader{DataType: chunkHeader.GetDataType(), ValueDecoder: valueDecoder, TimeDecoder: defaultTimeDecoder}
+				reader1 := &basic.PageDataReader{DataType: chunkHeader.GetDataType(), ValueDecoder: valueDecoder, TimeDecoder: timeDecoder}
 				reader1.Read(pageData)
 				for reader1.HasNext() {
 					pair, _ := reader1.Next()
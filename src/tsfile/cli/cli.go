@@ -0,0 +1,114 @@
+// Package cli is the tsfile command line tool, see Run
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"tsfile/timeseries/read"
+)
+
+const usage = `usage: tsfile <command> [flags] FILE
+
+commands:
+  inspect FILE [--json]                          the structure of the file
+  dump FILE [--device D] [--sensor S] [--json]   the points of the file
+  import-csv CSV FILE [--device D] [--schema SCHEMA] [--time-format F] [--time-unit U]
+             [--encoding E] [--compression C]    a new file of the rows of a CSV
+  export-csv FILE [--paths P] [--start T] [--end T] [--layout wide|long]
+             [--time-format F] [--time-unit U]   the points of the file as a CSV
+  fsck FILE [--json]                             the problems of the file, exit code 1 if any
+  repair IN OUT [--json]                         a new file of the points of a damaged file
+  merge FILE... --out OUT [--json]               one file of the points of the files, later files win
+
+flags:
+  --time-format  number (default), rfc3339 or a layout of the Go time package
+  --time-unit    the unit of the timestamps of the file, s, ms (default), us or ns
+`
+
+var commands = map[string]func(args []string, stdout io.Writer) error{
+	"inspect":    runInspect,
+	"dump":       runDump,
+	"import-csv": runImportCsv,
+	"export-csv": runExportCsv,
+	"fsck":       runFsck,
+	"repair":     runRepair,
+	"merge":      runMerge,
+}
+
+// errUsage makes Run print the usage, exit code 2
+var errUsage = errors.New("invalid arguments")
+
+// Run runs the command of args, without the program name, and returns the
+// exit code: 0 on success, 1 on an error, 2 on invalid arguments
+func Run(args []string, stdout io.Writer, stderr io.Writer) (code int) {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		fmt.Fprint(stderr, usage)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "tsfile: unknown command %s\n", args[0])
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	// the readers panic on corrupted files
+	defer func() {
+		if err := recover(); err != nil {
+			fmt.Fprintf(stderr, "tsfile %s: %v\n", args[0], err)
+			code = 1
+		}
+	}()
+	if err := cmd(args[1:], stdout); err != nil {
+		if err == flag.ErrHelp {
+			fmt.Fprint(stderr, usage)
+			return 0
+		}
+		if errors.Is(err, errUsage) {
+			fmt.Fprintf(stderr, "tsfile %s: %v\n", args[0], err)
+			fmt.Fprint(stderr, usage)
+			return 2
+		}
+		fmt.Fprintf(stderr, "tsfile %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+// parseArgs parses the flags of fs, before and after the positional arguments,
+// and returns the n positional arguments, e.g. the file, or at least -n of
+// them if n is negative
+func parseArgs(fs *flag.FlagSet, args []string, n int) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	var positional []string
+	for {
+		if err := fs.Parse(args); err == flag.ErrHelp {
+			return nil, err
+		} else if err != nil {
+			return nil, fmt.Errorf("%w, %v", errUsage, err)
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if n < 0 && len(positional) < -n {
+		return nil, fmt.Errorf("%w, expected at least %d file(s)", errUsage, -n)
+	} else if n >= 0 && len(positional) != n {
+		return nil, fmt.Errorf("%w, expected %d file(s)", errUsage, n)
+	}
+	return positional, nil
+}
+
+func openFile(file string) (*read.TsFileSequenceReader, error) {
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(file); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,298 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"tsfile/common/constant"
+	"tsfile/timeseries/write/sensorDescriptor"
+	"tsfile/timeseries/write/tsFileWriter"
+)
+
+var cliFilePath = "temp_cli_TsFile"
+
+// writeCliFile writes s0 INT32 and s1 DOUBLE of root.d0 and root.d1 at the
+// times 0 to 9, s0 is i*3 and s1 i/2
+func writeCliFile(t *testing.T) {
+	os.Remove(cliFilePath)
+	w, err := tsFileWriter.NewTsFileWriter(cliFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s0, _ := sensorDescriptor.NewWithCompress("s0", constant.INT32, constant.RLE, constant.SNAPPY)
+	s1, _ := sensorDescriptor.New("s1", constant.DOUBLE, constant.GORILLA)
+	w.AddSensor(s0)
+	w.AddSensor(s1)
+	for i := 0; i < 10; i++ {
+		for _, device := range []string{"root.d0", "root.d1"} {
+			record, _ := tsFileWriter.NewTsRecordUseTimestamp(int64(i), device)
+			p0, _ := tsFileWriter.NewInt("s0", constant.INT32, int32(i*3))
+			p1, _ := tsFileWriter.NewDouble("s1", constant.DOUBLE, float64(i)/2)
+			record.AddTuple(p0)
+			record.AddTuple(p1)
+			w.Write(record)
+		}
+	}
+	if !w.Close() {
+		t.Fatal("cannot close the file")
+	}
+}
+
+func run(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := Run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestInspect(t *testing.T) {
+	defer os.Remove(cliFilePath)
+	writeCliFile(t)
+
+	code, out, errOut := run("inspect", cliFilePath)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, errOut)
+	}
+	for _, line := range []string{"version: 4", "row groups: 2, chunks: 4, pages: 4, points: 40",
+		"device root.d1: row groups 1, time [0, 9]", "sensor s0 INT32: encoding RLE, time encoding TS_2DIFF, compression SNAPPY"} {
+		if !strings.Contains(out, line) {
+			t.Fatalf("expected %q in\n%s", line, out)
+		}
+	}
+
+	code, out, _ = run("inspect", "--json", cliFilePath)
+	var report fileReport
+	if err := json.Unmarshal([]byte(out), &report); code != 0 || err != nil {
+		t.Fatalf("expected a JSON report, got %d %v", code, err)
+	}
+	if len(report.Devices) != 2 || len(report.RowGroups) != 2 || report.Points != 40 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	s1 := report.Devices[0].Series[1]
+	if s1.Sensor != "s1" || s1.DataType != "DOUBLE" || s1.Encoding != "GORILLA" || s1.Points != 10 ||
+		s1.StartTime != 0 || s1.EndTime != 9 || s1.CompressedSize == 0 || s1.CompressionRatio != 1 {
+		t.Fatalf("unexpected series %+v", s1)
+	}
+}
+
+func TestDump(t *testing.T) {
+	defer os.Remove(cliFilePath)
+	writeCliFile(t)
+
+	code, out, _ := run("dump", cliFilePath)
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); code != 0 || len(lines) != 40 {
+		t.Fatalf("expected 40 points, got %d %d", code, len(lines))
+	}
+
+	// the flags may follow the file
+	code, out, _ = run("dump", cliFilePath, "--device", "root.d1", "--sensor", "s1")
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); code != 0 || len(lines) != 10 || lines[3] != "root.d1.s1 3 1.5" {
+		t.Fatalf("expected the points of root.d1.s1, got %d\n%s", code, out)
+	}
+
+	code, out, _ = run("dump", "--json", "--sensor", "s0", cliFilePath)
+	var points []point
+	decoder := json.NewDecoder(strings.NewReader(out))
+	for decoder.More() {
+		var p point
+		if err := decoder.Decode(&p); err != nil {
+			t.Fatal(err)
+		}
+		points = append(points, p)
+	}
+	if code != 0 || len(points) != 20 || points[12].Device != "root.d1" || points[12].Value != float64(6) {
+		t.Fatalf("expected the points of s0, got %d %v", code, points)
+	}
+
+	if code, _, _ = run("dump", cliFilePath, "--device", "root.d2"); code != 1 {
+		t.Fatalf("expected exit code 1 for an unknown device, got %d", code)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{{}, {"unknown"}, {"inspect"}, {"dump", "a", "b"}, {"inspect", "--unknown", "a"}} {
+		if code, _, errOut := run(args...); code != 2 || !strings.Contains(errOut, "usage") {
+			t.Fatalf("%v: expected the usage and exit code 2, got %d %s", args, code, errOut)
+		}
+	}
+	if code, _, errOut := run("inspect", "temp_missing_TsFile"); code != 1 || errOut == "" {
+		t.Fatalf("expected exit code 1 for a missing file, got %d", code)
+	}
+
+	os.WriteFile(cliFilePath, []byte("not a tsfile at all, too short anyway"), 0644)
+	defer os.Remove(cliFilePath)
+	if code, _, errOut := run("dump", cliFilePath); code != 1 || !strings.Contains(errOut, "tsfile dump:") {
+		t.Fatalf("expected exit code 1 for an invalid file, got %d %s", code, errOut)
+	}
+}
+
+func TestImportExportCsv(t *testing.T) {
+	csvPath, schemaPath := "temp_cli.csv", "temp_cli_schema.csv"
+	defer os.Remove(cliFilePath)
+	defer os.Remove(csvPath)
+	defer os.Remove(schemaPath)
+	os.Remove(cliFilePath)
+	os.WriteFile(csvPath, []byte("at;d0.s0;d0.s1;d1.s0;t\n"+
+		"2024-01-02 03:04:05;1.5;true;3;hello\n"+
+		"2024-01-02 03:04:06;2.5;;4;\"a;b\"\n"+
+		"2024-01-02 03:04:07;;false;5;\n"), 0644)
+	os.WriteFile(schemaPath, []byte("# the other columns are inferred\nd1.s0,INT32,TS_2DIFF,SNAPPY\n"), 0644)
+
+	code, _, errOut := run("import-csv", csvPath, cliFilePath, "--delimiter", ";", "--device", "d1", "--schema", schemaPath,
+		"--time-format", "2006-01-02 15:04:05", "--time-unit", "s")
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, errOut)
+	}
+	var report fileReport
+	_, out, _ := run("inspect", "--json", cliFilePath)
+	json.Unmarshal([]byte(out), &report)
+	types := make(map[string]string)
+	for _, device := range report.Devices {
+		for _, s := range device.Series {
+			types[device.Device+"."+s.Sensor] = s.DataType + " " + s.Encoding + " " + s.Compression
+		}
+	}
+	expected := map[string]string{"d0.s0": "DOUBLE PLAIN UNCOMPRESSED", "d0.s1": "BOOLEAN PLAIN UNCOMPRESSED",
+		"d1.s0": "INT32 TS_2DIFF SNAPPY", "d1.t": "TEXT PLAIN UNCOMPRESSED"}
+	for path, s := range expected {
+		if types[path] != s {
+			t.Fatalf("expected %s of %s, got %v", s, path, types)
+		}
+	}
+
+	code, out, _ = run("export-csv", cliFilePath)
+	if code != 0 || out != "time,d0.s0,d0.s1,d1.s0,d1.t\n"+
+		"1704164645,1.5,true,3,hello\n1704164646,2.5,,4,a;b\n1704164647,,false,5,\n" {
+		t.Fatalf("unexpected wide CSV, exit code %d\n%s", code, out)
+	}
+	code, out, _ = run("export-csv", cliFilePath, "--layout", "long", "--paths", "d0.s1,d1.t", "--time-unit", "s",
+		"--time-format", "rfc3339", "--start", "2024-01-02T03:04:06Z")
+	if code != 0 || out != "time,device,sensor,value\n"+
+		"2024-01-02T03:04:06Z,d1,t,a;b\n2024-01-02T03:04:07Z,d0,s1,false\n" {
+		t.Fatalf("unexpected long CSV, exit code %d\n%s", code, out)
+	}
+
+	if code, _, _ = run("export-csv", cliFilePath, "--paths", "d2.s0"); code != 1 {
+		t.Fatalf("expected exit code 1 for an unknown path, got %d", code)
+	}
+	// the file exists
+	if code, _, _ = run("import-csv", csvPath, cliFilePath, "--delimiter", ";", "--device", "d1"); code != 1 {
+		t.Fatalf("expected exit code 1 for an existing file, got %d", code)
+	}
+	os.Remove(cliFilePath)
+	os.WriteFile(csvPath, []byte("time,d0.s0\n1,2\n2,x\n"), 0644)
+	code, _, errOut = run("import-csv", csvPath, cliFilePath, "--infer-rows", "1")
+	if _, err := os.Stat(cliFilePath); code != 1 || !strings.Contains(errOut, "row 2") || err == nil {
+		t.Fatalf("expected exit code 1 and no file for an invalid value, got %d %s", code, errOut)
+	}
+
+	// unknown encodings and compressions of the flags or the schema are usage errors
+	os.WriteFile(schemaPath, []byte("d0.s0,INT64,ZIGZAG\n"), 0644)
+	for _, args := range [][]string{{"--encoding", "ZIGZAG"}, {"--compression", "BZIP2"}, {"--schema", schemaPath}} {
+		code, _, errOut = run(append([]string{"import-csv", csvPath, cliFilePath}, args...)...)
+		if _, err := os.Stat(cliFilePath); code != 2 || !strings.Contains(errOut, "usage") || err == nil {
+			t.Fatalf("%v: expected the usage, exit code 2 and no file, got %d %s", args, code, errOut)
+		}
+	}
+}
+
+func TestImportUnknownSensor(t *testing.T) {
+	defer os.Remove(cliFilePath)
+	os.Remove(cliFilePath)
+	w, err := tsFileWriter.NewTsFileWriter(cliFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	s0, _ := sensorDescriptor.New("s0", constant.INT64, constant.PLAIN)
+	w.AddSensor(s0)
+	columns := []*column{{index: 1, header: "d0.s0", device: "d0", sensor: "s0", dataType: constant.INT64},
+		{index: 2, header: "d0.s1", device: "d0", sensor: "s1", dataType: constant.INT64}}
+	format, _ := newTimeFormat("number", "ms")
+
+	if dropped, err := writeRow(w, 0, columns, []string{"1", "2", ""}, format); err != nil || dropped {
+		t.Fatalf("expected the row to be written, got %v %v", dropped, err)
+	}
+	if dropped, err := writeRow(w, 0, columns, []string{"2", "3", "4"}, format); err != nil || !dropped {
+		t.Fatalf("expected the value of s1 to be dropped, got %v %v", dropped, err)
+	}
+}
+
+func TestFsck(t *testing.T) {
+	defer os.Remove(cliFilePath)
+	writeCliFile(t)
+
+	code, out, _ := run("fsck", cliFilePath)
+	if code != 0 || out != cliFilePath+": ok\n" {
+		t.Fatalf("expected a valid file, got %d %s", code, out)
+	}
+	data, _ := os.ReadFile(cliFilePath)
+	os.WriteFile(cliFilePath, data[:len(data)-2], 0644)
+	code, out, errOut := run("fsck", "--json", cliFilePath)
+	var problems []map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &problems); err != nil || code != 1 || len(problems) != 1 ||
+		problems[0]["block"] != "file" || !strings.Contains(errOut, "1 problem(s)") {
+		t.Fatalf("expected the problem of the tail magic, got %d %v %s", code, err, out)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	repairedPath := "temp_cli_repaired_TsFile"
+	defer os.Remove(cliFilePath)
+	defer os.Remove(repairedPath)
+	os.Remove(repairedPath)
+	writeCliFile(t)
+
+	// the footer is lost
+	data, _ := os.ReadFile(cliFilePath)
+	os.WriteFile(cliFilePath, data[:len(data)-20], 0644)
+	code, out, errOut := run("repair", cliFilePath, repairedPath)
+	if code != 0 || !strings.Contains(out, "row groups 2, chunks 4, pages 4, points 40") || !strings.Contains(out, "bytes skipped") {
+		t.Fatalf("expected all the points, got %d %s%s", code, out, errOut)
+	}
+	if code, _, _ = run("fsck", repairedPath); code != 0 {
+		t.Fatalf("expected a valid file, got %d", code)
+	}
+	// the output exists
+	if code, _, _ = run("repair", cliFilePath, repairedPath); code != 1 {
+		t.Fatalf("expected exit code 1 for an existing file, got %d", code)
+	}
+
+	os.Remove(repairedPath)
+	code, out, _ = run("repair", "--json", cliFilePath, repairedPath)
+	var report tsFileWriter.RepairReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil || code != 0 || report.Points != 40 || report.SkippedBytes == 0 {
+		t.Fatalf("expected a JSON report, got %d %v %s", code, err, out)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	mergedPath := "temp_cli_merged_TsFile"
+	defer os.Remove(cliFilePath)
+	defer os.Remove(mergedPath)
+	os.Remove(mergedPath)
+	writeCliFile(t)
+
+	// a file merged with itself, all its points are duplicates
+	code, out, errOut := run("merge", cliFilePath, cliFilePath, "--out", mergedPath)
+	if code != 0 || !strings.Contains(out, "row groups 2, chunks copied 0, rewritten 4, points 40, duplicates dropped 40") {
+		t.Fatalf("expected the points once, got %d %s%s", code, out, errOut)
+	}
+	if code, _, _ = run("fsck", mergedPath); code != 0 {
+		t.Fatalf("expected a valid file, got %d", code)
+	}
+	if code, _, _ = run("merge", cliFilePath, "--out", mergedPath); code != 1 {
+		t.Fatalf("expected exit code 1 for an existing file, got %d", code)
+	}
+	if code, _, _ = run("merge", cliFilePath); code != 2 {
+		t.Fatalf("expected exit code 2 without --out, got %d", code)
+	}
+
+	os.Remove(mergedPath)
+	code, out, _ = run("merge", "--json", "--out", mergedPath, cliFilePath)
+	var report tsFileWriter.MergeReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil || code != 0 || report.CopiedChunks != 4 || report.Points != 40 {
+		t.Fatalf("expected a JSON report, got %d %v %s", code, err, out)
+	}
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"tsfile/common/constant"
+	"tsfile/timeseries/read/reader/impl/basic"
+)
+
+// point is a line of the JSON dump, a float value that JSON cannot express,
+// e.g. NaN, is written as a string
+type point struct {
+	Device string      `json:"device"`
+	Sensor string      `json:"sensor"`
+	Time   int64       `json:"time"`
+	Value  interface{} `json:"value"`
+}
+
+func runDump(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("dump", flag.ContinueOnError)
+	device := fs.String("device", "", "only the points of this device")
+	sensor := fs.String("sensor", "", "only the points of this sensor")
+	asJson := fs.Bool("json", false, "print one JSON object per point")
+	files, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(stdout)
+	defer w.Flush()
+	return dump(files[0], *device, *sensor, *asJson, w)
+}
+
+// dump prints the points chunk by chunk in the order of the file, one
+// "device.sensor time value" line each
+func dump(file string, device string, sensor string, asJson bool, w io.Writer) error {
+	f, err := openFile(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	encoder := json.NewEncoder(w)
+	matched := false
+	for f.HasNextRowGroup() {
+		rowGroupHeader := f.ReadRowGroupHeader()
+		deviceId := rowGroupHeader.GetDevice()
+		for i := 0; i < int(rowGroupHeader.GetNumberOfChunks()); i++ {
+			chunkHeader := f.ReadChunkHeader()
+			selected := (device == "" || device == deviceId) && (sensor == "" || sensor == chunkHeader.GetSensor())
+			matched = matched || selected
+			for j := 0; j < chunkHeader.GetNumberOfPages(); j++ {
+				pageHeader := f.ReadPageHeader(chunkHeader.GetDataType())
+				if !selected {
+					f.SkipPage(pageHeader)
+					continue
+				}
+				pageReader, err := basic.NewPageDataReader(chunkHeader.GetDataType(), chunkHeader.GetEncodingType(),
+					chunkHeader.GetTimeEncodingType())
+				if err != nil {
+					return fmt.Errorf("%s%s%s: %v", deviceId, constant.PATH_SEPARATOR, chunkHeader.GetSensor(), err)
+				}
+				pageReader.Read(f.ReadPage(pageHeader, chunkHeader.GetCompressionType()))
+				for pageReader.HasNext() {
+					pair, err := pageReader.Next()
+					if err != nil {
+						return fmt.Errorf("%s%s%s: %v", deviceId, constant.PATH_SEPARATOR, chunkHeader.GetSensor(), err)
+					}
+					if asJson {
+						p := point{Device: deviceId, Sensor: chunkHeader.GetSensor(), Time: pair.Timestamp, Value: jsonValue(pair.Value)}
+						if err := encoder.Encode(p); err != nil {
+							return err
+						}
+					} else {
+						fmt.Fprintf(w, "%s%s%s %d %v\n", deviceId, constant.PATH_SEPARATOR, chunkHeader.GetSensor(), pair.Timestamp, pair.Value)
+					}
+				}
+			}
+		}
+	}
+	if !matched && (device != "" || sensor != "") {
+		return fmt.Errorf("no chunks of the selected series in %s", file)
+	}
+	return nil
+}
+
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case float32:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return strconv.FormatFloat(float64(v), 'g', -1, 32)
+		}
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return strconv.FormatFloat(v, 'g', -1, 64)
+		}
+	}
+	return value
+}
@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"tsfile/common/constant"
+	"tsfile/common/utils"
+	"tsfile/file/metadata"
+	"tsfile/timeseries/filter"
+	"tsfile/timeseries/filter/operator"
+	"tsfile/timeseries/query"
+	"tsfile/timeseries/query/engine"
+)
+
+type exportOptions struct {
+	paths     string
+	start     string
+	end       string
+	layout    string
+	delimiter string
+	time      *timeFormat
+}
+
+func runExportCsv(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("export-csv", flag.ContinueOnError)
+	options := new(exportOptions)
+	fs.StringVar(&options.paths, "paths", "", "the comma separated paths device.sensor to export, all by default")
+	fs.StringVar(&options.start, "start", "", "the first time exported, in the --time-format")
+	fs.StringVar(&options.end, "end", "", "the last time exported, in the --time-format")
+	fs.StringVar(&options.layout, "layout", "wide", "wide for a column per path, long for a row per point")
+	fs.StringVar(&options.delimiter, "delimiter", ",", "the field delimiter")
+	timeFormatName := fs.String("time-format", "number", "number, rfc3339 or a layout of the Go time package")
+	timeUnit := fs.String("time-unit", "ms", "the unit of the timestamps of the file, s, ms, us or ns")
+	files, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if options.time, err = newTimeFormat(*timeFormatName, *timeUnit); err != nil {
+		return err
+	}
+	if options.layout != "wide" && options.layout != "long" {
+		return fmt.Errorf("%w, unknown layout %s, expected wide or long", errUsage, options.layout)
+	}
+	if len([]rune(options.delimiter)) != 1 {
+		return fmt.Errorf("%w, the delimiter should be one character", errUsage)
+	}
+	w := bufio.NewWriter(stdout)
+	defer w.Flush()
+	return exportCsv(files[0], options, w)
+}
+
+// exportCsv writes the rows of a query of the paths in the time range, a wide
+// row has an empty cell for a path without a value at its time
+func exportCsv(file string, options *exportOptions, w io.Writer) error {
+	f, err := openFile(file)
+	if err != nil {
+		return err
+	}
+	e := new(engine.Engine)
+	e.Open(f)
+	defer e.Close()
+
+	paths := allPaths(f.ReadFileMetadata())
+	if options.paths != "" {
+		known := make(map[string]bool)
+		for _, path := range paths {
+			known[path] = true
+		}
+		paths = strings.Split(options.paths, ",")
+		for _, path := range paths {
+			if !known[path] {
+				return fmt.Errorf("no series %s in %s", path, file)
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no series in %s", file)
+	}
+
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths(paths)
+	timeFilter, err := options.timeFilter()
+	if err != nil {
+		return err
+	}
+	if timeFilter != nil {
+		exp.SetFilter(filter.NewRowRecordTimeFilter(timeFilter))
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Comma = []rune(options.delimiter)[0]
+	if options.layout == "wide" {
+		writer.Write(append([]string{"time"}, paths...))
+	} else {
+		writer.Write([]string{"time", "device", "sensor", "value"})
+	}
+	row := make([]string, len(paths)+1)
+	dataSet := e.Query(exp)
+	for dataSet.HasNext() {
+		record, err := dataSet.Next()
+		if err != nil {
+			return err
+		}
+		timestamp := options.time.format(record.Timestamp())
+		for i, value := range record.Values() {
+			if options.layout == "wide" {
+				row[i+1] = formatValue(value)
+				continue
+			}
+			if value != nil {
+				pos := strings.LastIndex(paths[i], constant.PATH_SEPARATOR)
+				writer.Write([]string{timestamp, paths[i][:pos], paths[i][pos+1:], formatValue(value)})
+			}
+		}
+		if options.layout == "wide" {
+			row[0] = timestamp
+			writer.Write(row)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// timeFilter is nil without --start and --end
+func (o *exportOptions) timeFilter() (filter.Filter, error) {
+	if o.start == "" && o.end == "" {
+		return nil, nil
+	}
+	start, end := int64(math.MinInt64), int64(math.MaxInt64)
+	var err error
+	if o.start != "" {
+		if start, err = o.time.parse(o.start); err != nil {
+			return nil, fmt.Errorf("%w, invalid start %s: %v", errUsage, o.start, err)
+		}
+	}
+	if o.end != "" {
+		if end, err = o.time.parse(o.end); err != nil {
+			return nil, fmt.Errorf("%w, invalid end %s: %v", errUsage, o.end, err)
+		}
+	}
+	return &operator.AndFilter{Filters: []filter.Filter{&operator.LongGtEqFilter{Ref: start}, &operator.LongLtEqFilter{Ref: end}}}, nil
+}
+
+// allPaths are the paths of the series with chunks in the file, sorted
+func allPaths(fileMetadata *metadata.FileMetaData) []string {
+	var paths []string
+	deviceMap := fileMetadata.DeviceMap()
+	for _, deviceId := range utils.SortedKeys(deviceMap) {
+		sensors := make(map[string]bool)
+		for _, rowGroup := range deviceMap[deviceId].GetRowGroups() {
+			for _, chunk := range rowGroup.GetChunkMetaDataSli() {
+				sensors[chunk.Sensor()] = true
+			}
+		}
+		for _, sensorId := range utils.SortedKeys(sensors) {
+			paths = append(paths, deviceId+constant.PATH_SEPARATOR+sensorId)
+		}
+	}
+	return paths
+}
+
+// formatValue writes floating point values without an exponent
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"tsfile/timeseries/read"
+)
+
+func runFsck(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("fsck", flag.ContinueOnError)
+	asJson := fs.Bool("json", false, "print the problems as JSON")
+	files, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	problems := read.Check(files[0])
+	if *asJson {
+		if problems == nil {
+			problems = []*read.Problem{}
+		}
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(problems); err != nil {
+			return err
+		}
+	} else {
+		for _, problem := range problems {
+			fmt.Fprintln(stdout, problem)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s: %d problem(s) found", files[0], len(problems))
+	}
+	if !*asJson {
+		fmt.Fprintf(stdout, "%s: ok\n", files[0])
+	}
+	return nil
+}
@@ -0,0 +1,393 @@
+package cli
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"tsfile/common/conf"
+	"tsfile/common/constant"
+	"tsfile/timeseries/write/sensorDescriptor"
+	"tsfile/timeseries/write/tsFileWriter"
+)
+
+// column is a series of the CSV, its header is the path device.sensor or the
+// sensor of the --device
+type column struct {
+	index       int
+	header      string
+	device      string
+	sensor      string
+	dataType    constant.TSDataType
+	encoding    constant.TSEncoding
+	compression constant.CompressionType
+	// false if the data type is inferred from the values
+	typed bool
+}
+
+type importOptions struct {
+	device      string
+	timeColumn  string
+	schema      string
+	delimiter   string
+	inferRows   int
+	encoding    string
+	compression string
+	time        *timeFormat
+}
+
+func runImportCsv(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("import-csv", flag.ContinueOnError)
+	options := new(importOptions)
+	fs.StringVar(&options.device, "device", "", "the device of the columns whose header is a sensor")
+	fs.StringVar(&options.timeColumn, "time-column", "", "the header of the time column, the first column by default")
+	fs.StringVar(&options.schema, "schema", "", "a CSV of column,dataType[,encoding[,compression]] lines")
+	fs.StringVar(&options.delimiter, "delimiter", ",", "the field delimiter")
+	fs.IntVar(&options.inferRows, "infer-rows", 1000, "the rows the data types are inferred from")
+	fs.StringVar(&options.encoding, "encoding", conf.ValueEncoder, "the encoding of the values")
+	fs.StringVar(&options.compression, "compression", conf.Compressor, "the compression of the pages")
+	timeFormatName := fs.String("time-format", "number", "number, rfc3339 or a layout of the Go time package")
+	timeUnit := fs.String("time-unit", "ms", "the unit of the timestamps written, s, ms, us or ns")
+	files, err := parseArgs(fs, args, 2)
+	if err != nil {
+		return err
+	}
+	if options.time, err = newTimeFormat(*timeFormatName, *timeUnit); err != nil {
+		return err
+	}
+	if len([]rune(options.delimiter)) != 1 {
+		return fmt.Errorf("%w, the delimiter should be one character", errUsage)
+	}
+	if _, err := os.Stat(files[1]); err == nil {
+		return fmt.Errorf("%s exists", files[1])
+	}
+
+	in, err := os.Open(files[0])
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	n, dropped, err := importCsv(in, files[1], options)
+	if err != nil {
+		os.Remove(files[1])
+		return err
+	}
+	fmt.Fprintf(stdout, "imported %d rows into %s\n", n, files[1])
+	if dropped > 0 {
+		fmt.Fprintf(stdout, "dropped the values of unknown sensors in %d rows\n", dropped)
+	}
+	return nil
+}
+
+// importCsv writes the rows of the CSV into a new TsFile and returns their
+// number and that of the rows with values of sensors the writer does not
+// know, the first rows are kept in memory for inferring the data types
+func importCsv(in io.Reader, file string, options *importOptions) (int, int, error) {
+	reader := csv.NewReader(in)
+	reader.Comma = []rune(options.delimiter)[0]
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err != nil {
+		return 0, 0, fmt.Errorf("cannot read the header: %v", err)
+	}
+	timeIndex, columns, err := mapColumns(header, options)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := readSchema(options, columns); err != nil {
+		return 0, 0, err
+	}
+
+	var rows [][]string
+	for len(rows) < options.inferRows {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, 0, err
+		}
+		rows = append(rows, append([]string(nil), record...))
+	}
+	inferTypes(columns, rows)
+
+	writer, err := tsFileWriter.NewTsFileWriter(file)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := addSensors(writer, columns); err != nil {
+		writer.Close()
+		return 0, 0, err
+	}
+	rowNum, droppedNum := 0, 0
+	write := func(record []string) error {
+		rowNum++
+		dropped, err := writeRow(writer, timeIndex, columns, record, options.time)
+		if err != nil {
+			return fmt.Errorf("row %d: %v", rowNum, err)
+		}
+		if dropped {
+			droppedNum++
+		}
+		return nil
+	}
+	for _, row := range rows {
+		if err := write(row); err != nil {
+			writer.Close()
+			return 0, 0, err
+		}
+	}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err == nil {
+			err = write(record)
+		}
+		if err != nil {
+			writer.Close()
+			return 0, 0, err
+		}
+	}
+	if !writer.Close() {
+		return 0, 0, fmt.Errorf("cannot close %s", file)
+	}
+	return rowNum, droppedNum, nil
+}
+
+func mapColumns(header []string, options *importOptions) (int, []*column, error) {
+	timeIndex := 0
+	if options.timeColumn != "" {
+		timeIndex = -1
+		for i, h := range header {
+			if h == options.timeColumn {
+				timeIndex = i
+			}
+		}
+		if timeIndex < 0 {
+			return 0, nil, fmt.Errorf("no time column %s in the header", options.timeColumn)
+		}
+	}
+
+	encoding, err := constant.EncodingByName(options.encoding)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w, %v", errUsage, err)
+	}
+	compression, err := constant.CompressionTypeByName(options.compression)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w, %v", errUsage, err)
+	}
+	var columns []*column
+	for i, h := range header {
+		if i == timeIndex {
+			continue
+		}
+		c := &column{index: i, header: h, encoding: encoding, compression: compression}
+		if pos := strings.LastIndex(h, constant.PATH_SEPARATOR); pos > 0 {
+			c.device, c.sensor = h[:pos], h[pos+1:]
+		} else if options.device != "" {
+			c.device, c.sensor = options.device, h
+		} else {
+			return 0, nil, fmt.Errorf("column %s: no device, expected device%ssensor or the --device", h, constant.PATH_SEPARATOR)
+		}
+		if c.sensor == "" {
+			return 0, nil, fmt.Errorf("column %s: no sensor", h)
+		}
+		columns = append(columns, c)
+	}
+	return timeIndex, columns, nil
+}
+
+// readSchema sets the data type and optionally the encoding and compression
+// of the columns in the schema
+func readSchema(options *importOptions, columns []*column) error {
+	if options.schema == "" {
+		return nil
+	}
+	in, err := os.Open(options.schema)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	byHeader := make(map[string]*column)
+	for _, c := range columns {
+		byHeader[c.header] = c
+	}
+	reader := csv.NewReader(in)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("schema %s: %v", options.schema, err)
+		}
+		if len(record) < 2 || len(record) > 4 {
+			return fmt.Errorf("schema %s: expected column,dataType[,encoding[,compression]], got %v", options.schema, record)
+		}
+		c, ok := byHeader[record[0]]
+		if !ok {
+			return fmt.Errorf("schema %s: no column %s in the CSV", options.schema, record[0])
+		}
+		if c.dataType, ok = dataTypeByName(strings.TrimSpace(record[1])); !ok {
+			return fmt.Errorf("%w, schema %s: unknown data type %s", errUsage, options.schema, record[1])
+		}
+		c.typed = true
+		if len(record) > 2 {
+			if c.encoding, err = constant.EncodingByName(strings.TrimSpace(record[2])); err != nil {
+				return fmt.Errorf("%w, schema %s: %v", errUsage, options.schema, err)
+			}
+		}
+		if len(record) > 3 {
+			if c.compression, err = constant.CompressionTypeByName(strings.TrimSpace(record[3])); err != nil {
+				return fmt.Errorf("%w, schema %s: %v", errUsage, options.schema, err)
+			}
+		}
+	}
+}
+
+func dataTypeByName(name string) (constant.TSDataType, bool) {
+	for dataType := constant.BOOLEAN; dataType <= constant.TEXT; dataType++ {
+		if dataType.String() == name {
+			return dataType, true
+		}
+	}
+	return constant.INVALID, false
+}
+
+// inferTypes gives the columns without a schema the narrowest of BOOLEAN,
+// INT64, DOUBLE and TEXT their values in the rows parse as
+func inferTypes(columns []*column, rows [][]string) {
+	for _, c := range columns {
+		if c.typed {
+			continue
+		}
+		isBool, isInt, isFloat, empty := true, true, true, true
+		for _, row := range rows {
+			value := row[c.index]
+			if value == "" {
+				continue
+			}
+			empty = false
+			isBool = isBool && (strings.EqualFold(value, "true") || strings.EqualFold(value, "false"))
+			if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+				isInt = false
+			}
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				isFloat = false
+			}
+		}
+		switch {
+		case empty:
+			c.dataType = constant.TEXT
+		case isBool:
+			c.dataType = constant.BOOLEAN
+		case isInt:
+			c.dataType = constant.INT64
+		case isFloat:
+			c.dataType = constant.DOUBLE
+		default:
+			c.dataType = constant.TEXT
+		}
+	}
+}
+
+// addSensors registers a sensor shared by devices once, and one of a device
+// whose column differs from the first one of the sensor for the device
+func addSensors(writer *tsFileWriter.TsFileWriter, columns []*column) error {
+	added := make(map[string]*column)
+	for _, c := range columns {
+		sd, _ := sensorDescriptor.NewWithCompress(c.sensor, c.dataType, c.encoding, c.compression)
+		first, ok := added[c.sensor]
+		var err error
+		switch {
+		case !ok:
+			added[c.sensor] = c
+			err = writer.AddSensor(sd)
+		case first.dataType != c.dataType || first.encoding != c.encoding || first.compression != c.compression:
+			err = writer.AddDeviceSensor(c.device, sd)
+		}
+		if err != nil {
+			return fmt.Errorf("column %s: %v", c.header, err)
+		}
+	}
+	return nil
+}
+
+// writeRow writes the values of the row, it tells whether some were dropped
+// for sensors the writer does not know
+func writeRow(writer *tsFileWriter.TsFileWriter, timeIndex int, columns []*column, record []string, format *timeFormat) (bool, error) {
+	timestamp, err := format.parse(record[timeIndex])
+	if err != nil {
+		return false, fmt.Errorf("invalid time %s: %v", record[timeIndex], err)
+	}
+	// the columns of a device are one record
+	dropped := false
+	var records []*tsFileWriter.TsRecord
+	byDevice := make(map[string]*tsFileWriter.TsRecord)
+	for _, c := range columns {
+		value := record[c.index]
+		if value == "" {
+			continue
+		}
+		if !writer.HasSensor(c.device, c.sensor) {
+			dropped = true
+			continue
+		}
+		point, err := newDataPoint(c, value)
+		if err != nil {
+			return false, fmt.Errorf("column %s: invalid %s %s", c.header, c.dataType, value)
+		}
+		tsRecord, ok := byDevice[c.device]
+		if !ok {
+			tsRecord, _ = tsFileWriter.NewTsRecordUseTimestamp(timestamp, c.device)
+			byDevice[c.device] = tsRecord
+			records = append(records, tsRecord)
+		}
+		tsRecord.AddTuple(point)
+	}
+	for _, tsRecord := range records {
+		writer.Write(tsRecord)
+	}
+	return dropped, nil
+}
+
+func newDataPoint(c *column, value string) (*tsFileWriter.DataPoint, error) {
+	switch c.dataType {
+	case constant.BOOLEAN:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, err
+		}
+		return tsFileWriter.NewBool(c.sensor, c.dataType, v)
+	case constant.INT32:
+		v, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		return tsFileWriter.NewInt(c.sensor, c.dataType, int32(v))
+	case constant.INT64:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return tsFileWriter.NewLong(c.sensor, c.dataType, v)
+	case constant.FLOAT:
+		v, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return nil, err
+		}
+		return tsFileWriter.NewFloat(c.sensor, c.dataType, float32(v))
+	case constant.DOUBLE:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, err
+		}
+		return tsFileWriter.NewDouble(c.sensor, c.dataType, v)
+	default:
+		return tsFileWriter.NewString(c.sensor, c.dataType, value)
+	}
+}
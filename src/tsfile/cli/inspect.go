@@ -0,0 +1,177 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"tsfile/common/constant"
+	"tsfile/common/utils"
+	"tsfile/timeseries/read"
+)
+
+type fileReport struct {
+	File       string            `json:"file"`
+	Version    int               `json:"version"`
+	Size       int64             `json:"size"`
+	FooterSize int64             `json:"footerSize"`
+	Chunks     int               `json:"chunks"`
+	Pages      int               `json:"pages"`
+	Points     int64             `json:"points"`
+	RowGroups  []*rowGroupReport `json:"rowGroups"`
+	Devices    []*deviceReport   `json:"devices"`
+}
+
+type rowGroupReport struct {
+	Device string `json:"device"`
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+	Chunks int    `json:"chunks"`
+}
+
+type deviceReport struct {
+	Device    string          `json:"device"`
+	RowGroups int             `json:"rowGroups"`
+	StartTime int64           `json:"startTime"`
+	EndTime   int64           `json:"endTime"`
+	Series    []*seriesReport `json:"series"`
+}
+
+// seriesReport has the encodings and compression of the first chunk of the
+// series, the sizes are those of the page data
+type seriesReport struct {
+	Sensor           string  `json:"sensor"`
+	DataType         string  `json:"dataType"`
+	Encoding         string  `json:"encoding"`
+	TimeEncoding     string  `json:"timeEncoding"`
+	Compression      string  `json:"compression"`
+	Chunks           int     `json:"chunks"`
+	Pages            int     `json:"pages"`
+	Points           int64   `json:"points"`
+	UncompressedSize int64   `json:"uncompressedSize"`
+	CompressedSize   int64   `json:"compressedSize"`
+	CompressionRatio float64 `json:"compressionRatio"`
+	StartTime        int64   `json:"startTime"`
+	EndTime          int64   `json:"endTime"`
+}
+
+func runInspect(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	asJson := fs.Bool("json", false, "print the report as JSON")
+	files, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	report, err := inspect(files[0])
+	if err != nil {
+		return err
+	}
+	if *asJson {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	printReport(report, stdout)
+	return nil
+}
+
+// inspect walks the headers of the file, the page data is skipped
+func inspect(file string) (*fileReport, error) {
+	stat, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	f, err := openFile(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	report := &fileReport{File: file, Version: f.Version(), Size: stat.Size(), FooterSize: stat.Size() - f.MetadataPos()}
+	devices := make(map[string]*deviceReport)
+	series := make(map[string]map[string]*seriesReport)
+	for f.HasNextRowGroup() {
+		offset := f.Pos()
+		rowGroupHeader := f.ReadRowGroupHeader()
+		deviceId := rowGroupHeader.GetDevice()
+		report.RowGroups = append(report.RowGroups, &rowGroupReport{Device: deviceId, Offset: offset,
+			Size: rowGroupHeader.GetDataSize(), Chunks: int(rowGroupHeader.GetNumberOfChunks())})
+
+		device, ok := devices[deviceId]
+		if !ok {
+			device = &deviceReport{Device: deviceId, StartTime: math.MaxInt64, EndTime: math.MinInt64}
+			devices[deviceId] = device
+			series[deviceId] = make(map[string]*seriesReport)
+		}
+		device.RowGroups++
+		for i := 0; i < int(rowGroupHeader.GetNumberOfChunks()); i++ {
+			chunkHeader := f.ReadChunkHeader()
+			s, ok := series[deviceId][chunkHeader.GetSensor()]
+			if !ok {
+				s = &seriesReport{Sensor: chunkHeader.GetSensor(), DataType: chunkHeader.GetDataType().String(),
+					Encoding: chunkHeader.GetEncodingType().String(), TimeEncoding: chunkHeader.GetTimeEncodingType().String(),
+					Compression: chunkHeader.GetCompressionType().String(), StartTime: math.MaxInt64, EndTime: math.MinInt64}
+				series[deviceId][chunkHeader.GetSensor()] = s
+			}
+			s.Chunks++
+			report.Chunks++
+			inspectPages(f, chunkHeader.GetNumberOfPages(), chunkHeader.GetDataType(), s)
+		}
+	}
+
+	for _, deviceId := range utils.SortedKeys(devices) {
+		device := devices[deviceId]
+		for _, sensorId := range utils.SortedKeys(series[deviceId]) {
+			s := series[deviceId][sensorId]
+			if s.CompressedSize > 0 {
+				s.CompressionRatio = float64(s.UncompressedSize) / float64(s.CompressedSize)
+			}
+			device.StartTime = min(device.StartTime, s.StartTime)
+			device.EndTime = max(device.EndTime, s.EndTime)
+			report.Pages += s.Pages
+			report.Points += s.Points
+			device.Series = append(device.Series, s)
+		}
+		report.Devices = append(report.Devices, device)
+	}
+	return report, nil
+}
+
+func inspectPages(f *read.TsFileSequenceReader, pageNum int, dataType constant.TSDataType, s *seriesReport) {
+	for j := 0; j < pageNum; j++ {
+		pageHeader := f.ReadPageHeader(dataType)
+		f.SkipPage(pageHeader)
+		s.Pages++
+		s.Points += int64(pageHeader.GetNumberOfValues())
+		s.UncompressedSize += int64(pageHeader.GetUncompressedSize())
+		s.CompressedSize += int64(pageHeader.GetCompressedSize())
+		s.StartTime = min(s.StartTime, pageHeader.Min_timestamp())
+		s.EndTime = max(s.EndTime, pageHeader.Max_timestamp())
+	}
+}
+
+func printReport(report *fileReport, w io.Writer) {
+	fmt.Fprintf(w, "file: %s\n", report.File)
+	fmt.Fprintf(w, "version: %d\n", report.Version)
+	fmt.Fprintf(w, "size: %d bytes, footer: %d bytes\n", report.Size, report.FooterSize)
+	fmt.Fprintf(w, "row groups: %d, chunks: %d, pages: %d, points: %d\n",
+		len(report.RowGroups), report.Chunks, report.Pages, report.Points)
+	for i, rowGroup := range report.RowGroups {
+		fmt.Fprintf(w, "row group %d: device %s, offset %d, size %d, chunks %d\n",
+			i, rowGroup.Device, rowGroup.Offset, rowGroup.Size, rowGroup.Chunks)
+	}
+	for _, device := range report.Devices {
+		fmt.Fprintf(w, "device %s: row groups %d, time [%d, %d]\n",
+			device.Device, device.RowGroups, device.StartTime, device.EndTime)
+		for _, s := range device.Series {
+			fmt.Fprintf(w, "  sensor %s %s: encoding %s, time encoding %s, compression %s\n",
+				s.Sensor, s.DataType, s.Encoding, s.TimeEncoding, s.Compression)
+			fmt.Fprintf(w, "    chunks %d, pages %d, points %d, time [%d, %d]\n",
+				s.Chunks, s.Pages, s.Points, s.StartTime, s.EndTime)
+			fmt.Fprintf(w, "    size %d bytes, uncompressed %d bytes, ratio %.2f\n",
+				s.CompressedSize, s.UncompressedSize, s.CompressionRatio)
+		}
+	}
+}
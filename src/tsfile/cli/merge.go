@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"tsfile/timeseries/write/tsFileWriter"
+)
+
+func runMerge(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("merge", flag.ContinueOnError)
+	out := fs.String("out", "", "the merged file")
+	asJson := fs.Bool("json", false, "print the report as JSON")
+	files, err := parseArgs(fs, args, -1)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		return fmt.Errorf("%w, --out is required", errUsage)
+	}
+	if _, err := os.Stat(*out); err == nil {
+		return fmt.Errorf("%s exists", *out)
+	}
+	report, err := tsFileWriter.MergeTsFiles(files, *out)
+	if err != nil {
+		os.Remove(*out)
+		return err
+	}
+	if *asJson {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	fmt.Fprintf(stdout, "merged %s into %s: row groups %d, chunks copied %d, rewritten %d, points %d, duplicates dropped %d\n",
+		strings.Join(files, ", "), *out, report.RowGroups, report.CopiedChunks, report.RewrittenChunks,
+		report.Points, report.DuplicatePoints)
+	return nil
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"tsfile/timeseries/read"
+	"tsfile/timeseries/write/tsFileWriter"
+)
+
+func runRepair(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("repair", flag.ContinueOnError)
+	asJson := fs.Bool("json", false, "print the report as JSON")
+	files, err := parseArgs(fs, args, 2)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(files[1]); err == nil {
+		return fmt.Errorf("%s exists", files[1])
+	}
+	report, err := tsFileWriter.RepairTsFile(files[0], files[1])
+	if err != nil {
+		os.Remove(files[1])
+		return err
+	}
+	if *asJson {
+		if report.Lost == nil {
+			report.Lost = []*read.Problem{}
+		}
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	fmt.Fprintf(stdout, "repaired %s into %s: row groups %d, chunks %d, pages %d, points %d\n",
+		files[0], files[1], report.RowGroups, report.Chunks, report.Pages, report.Points)
+	for _, lost := range report.Lost {
+		fmt.Fprintln(stdout, lost)
+	}
+	if len(report.Lost) == 0 {
+		fmt.Fprintln(stdout, "nothing lost")
+	} else {
+		fmt.Fprintf(stdout, "lost %d points, skipped %d bytes\n", report.LostPoints, report.SkippedBytes)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// nanoseconds of the units of the timestamps in the file
+var timeUnits = map[string]int64{
+	"s":  int64(time.Second),
+	"ms": int64(time.Millisecond),
+	"us": int64(time.Microsecond),
+	"ns": 1,
+}
+
+// timeFormat converts between the timestamps of a file, in its unit, and the
+// times of a CSV. The format is "number" for the timestamps as they are,
+// "rfc3339" or a layout of the time package, a layout without a zone is UTC.
+type timeFormat struct {
+	layout string
+	unit   int64
+}
+
+func newTimeFormat(format string, unit string) (*timeFormat, error) {
+	nanos, ok := timeUnits[unit]
+	if !ok {
+		return nil, fmt.Errorf("%w, unknown time unit %s, expected s, ms, us or ns", errUsage, unit)
+	}
+	switch format {
+	case "number":
+		format = ""
+	case "rfc3339":
+		format = time.RFC3339Nano
+	}
+	return &timeFormat{layout: format, unit: nanos}, nil
+}
+
+func (f *timeFormat) parse(value string) (int64, error) {
+	if f.layout == "" {
+		return strconv.ParseInt(value, 10, 64)
+	}
+	t, err := time.Parse(f.layout, value)
+	if err != nil {
+		return 0, err
+	}
+	return t.UnixNano() / f.unit, nil
+}
+
+func (f *timeFormat) format(timestamp int64) string {
+	if f.layout == "" {
+		return strconv.FormatInt(timestamp, 10)
+	}
+	return time.Unix(0, timestamp*f.unit).UTC().Format(f.layout)
+}
@@ -30,6 +30,9 @@ const (
 // Memory size threshold for flushing to disk or HDFS, default value is 128MB
 var GroupSizeInByte int = 128 * 1024 * 1024
 
+// Interval of flushing the buffered data by time in milliseconds, 0 means only by size
+var FlushIntervalInMs int = 0
+
 // The memory size for each series writer to pack page, default value is 64KB
 var PageSizeInByte int = 64 * 1024
 
@@ -45,7 +48,7 @@ var MaxStringLength int = 128
 // Floating-point precision
 var FloatPrecision int = 2
 
-// Encoder of time series, TsFile supports TS_2DIFF, PLAIN and RLE(run-length encoding)
+// Encoder of time series, TsFile supports TS_2DIFF, PLAIN, RLE(run-length encoding) and GORILLA_TS
 var TimeSeriesEncoder string = "TS_2DIFF"
 
 // Encoder of value series. default value is PLAIN.
@@ -53,11 +56,23 @@ var ValueEncoder string = "PLAIN"
 
 var Compressor string = "UNCOMPRESSED"
 
+// Level of the GZIP and ZSTD compressors, 0 means the default level of the compressor
+var CompressionLevel int = 0
+
+// Max size of the dictionary of a PLAIN_DICTIONARY page, a page with a larger dictionary is written plain, default value is 64KB
+var DictionaryMaxSizeInByte int = 64 * 1024
+
+// Number of values of a page the AUTO encoding tries the candidate encodings on, 0 means the whole page
+var AutoEncodingSampleSize int = 1024
+
+// False positive rate of the bloom filter over the series paths in the footer, 0 means no bloom filter
+var BloomFilterErrorRate float64 = 0.05
+
 // Default block size of two-diff. delta encoding is 128
 var DeltaBlockSize = 128
 
-// Current version is 3
-var CurrentVersion = 3
+// Version of the format written by default is 4, 3 is the one of the TsFile v0.8
+var CurrentVersion = 4
 
 /**
 * String encoder with UTF-8 encodes a character to at most 4 bytes.
@@ -82,6 +97,8 @@ func loadProperties() {
 			switch {
 			case k == "group_size_in_byte":
 				GroupSizeInByte, _ = strconv.Atoi(v)
+			case k == "flush_interval_in_ms":
+				FlushIntervalInMs, _ = strconv.Atoi(v)
 			case k == "page_size_in_byte":
 				PageSizeInByte, _ = strconv.Atoi(v)
 			case k == "max_number_of_points_in_page":
@@ -98,6 +115,16 @@ func loadProperties() {
 				ValueEncoder = v
 			case k == "compressor":
 				Compressor = v
+			case k == "compression_level":
+				CompressionLevel, _ = strconv.Atoi(v)
+			case k == "dictionary_max_size_in_byte":
+				DictionaryMaxSizeInByte, _ = strconv.Atoi(v)
+			case k == "auto_encoding_sample_size":
+				AutoEncodingSampleSize, _ = strconv.Atoi(v)
+			case k == "format_version":
+				CurrentVersion, _ = strconv.Atoi(v)
+			case k == "bloom_filter_error_rate":
+				BloomFilterErrorRate, _ = strconv.ParseFloat(v, 64)
 			}
 		}
 	}
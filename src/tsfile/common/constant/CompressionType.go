@@ -1,5 +1,10 @@
 package constant
 
+import (
+	"errors"
+	"strconv"
+)
+
 type CompressionType int16
 
 const (
@@ -10,4 +15,66 @@ const (
 	SDT          CompressionType = 4
 	PAA          CompressionType = 5
 	PLA          CompressionType = 6
+	LZ4          CompressionType = 7
+	ZSTD         CompressionType = 8
 )
+
+func GetCompressionTypeByName(name string) CompressionType {
+	compression, err := CompressionTypeByName(name)
+	if err != nil {
+		panic(err.Error())
+	}
+	return compression
+}
+
+// CompressionTypeByName is GetCompressionTypeByName failing instead of
+// panicking on an unknown name, e.g. for the names given by users
+func CompressionTypeByName(name string) (CompressionType, error) {
+	switch name {
+	case "UNCOMPRESSED":
+		return UNCOMPRESSED, nil
+	case "SNAPPY":
+		return SNAPPY, nil
+	case "GZIP":
+		return GZIP, nil
+	case "LZO":
+		return LZO, nil
+	case "SDT":
+		return SDT, nil
+	case "PAA":
+		return PAA, nil
+	case "PLA":
+		return PLA, nil
+	case "LZ4":
+		return LZ4, nil
+	case "ZSTD":
+		return ZSTD, nil
+	default:
+		return 0, errors.New("No compression type found: " + name)
+	}
+}
+
+func (c CompressionType) String() string {
+	switch c {
+	case UNCOMPRESSED:
+		return "UNCOMPRESSED"
+	case SNAPPY:
+		return "SNAPPY"
+	case GZIP:
+		return "GZIP"
+	case LZO:
+		return "LZO"
+	case SDT:
+		return "SDT"
+	case PAA:
+		return "PAA"
+	case PLA:
+		return "PLA"
+	case LZ4:
+		return "LZ4"
+	case ZSTD:
+		return "ZSTD"
+	default:
+		return "COMPRESSION_" + strconv.Itoa(int(c))
+	}
+}
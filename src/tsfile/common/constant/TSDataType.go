@@ -20,3 +20,22 @@ const (
 	FLOAT_LEN   int = 4
 	DOUBLE_LEN  int = 8
 )
+
+func (t TSDataType) String() string {
+	switch t {
+	case BOOLEAN:
+		return "BOOLEAN"
+	case INT32:
+		return "INT32"
+	case INT64:
+		return "INT64"
+	case FLOAT:
+		return "FLOAT"
+	case DOUBLE:
+		return "DOUBLE"
+	case TEXT:
+		return "TEXT"
+	default:
+		return "INVALID"
+	}
+}
@@ -1,7 +1,17 @@
 package constant
 
+import (
+	"errors"
+	"strconv"
+	"sync"
+)
+
 type TSEncoding int8
 
+// MAX_BUILTIN_ENCODING is the largest id of the encodings of this package, the
+// ids above it are free for the ones registered by applications
+const MAX_BUILTIN_ENCODING TSEncoding = AUTO
+
 const (
 	PLAIN            TSEncoding = 0
 	PLAIN_DICTIONARY TSEncoding = 1
@@ -10,25 +20,114 @@ const (
 	TS_2DIFF         TSEncoding = 4
 	BITMAP           TSEncoding = 5
 	GORILLA          TSEncoding = 6
+	GORILLA_TS       TSEncoding = 7
+	CHIMP            TSEncoding = 8
+	ELF              TSEncoding = 9
+	AUTO             TSEncoding = 10
+)
+
+// modes of a PLAIN_DICTIONARY page, its first byte
+const (
+	DICTIONARY_MODE       byte = 0
+	DICTIONARY_PLAIN_MODE byte = 1
 )
 
 func GetEncodingByName(name string) TSEncoding {
+	encoding, err := EncodingByName(name)
+	if err != nil {
+		panic(err.Error())
+	}
+	return encoding
+}
+
+// EncodingByName is GetEncodingByName failing instead of panicking on an
+// unknown name, e.g. for the names given by users
+func EncodingByName(name string) (TSEncoding, error) {
 	switch name {
 	case "PLAIN":
-		return PLAIN
+		return PLAIN, nil
 	case "PLAIN_DICTIONARY":
-		return PLAIN_DICTIONARY
+		return PLAIN_DICTIONARY, nil
 	case "RLE":
-		return RLE
+		return RLE, nil
 	case "DIFF":
-		return DIFF
+		return DIFF, nil
 	case "TS_2DIFF":
-		return TS_2DIFF
+		return TS_2DIFF, nil
 	case "BITMAP":
-		return BITMAP
+		return BITMAP, nil
 	case "GORILLA":
-		return GORILLA
+		return GORILLA, nil
+	case "GORILLA_TS":
+		return GORILLA_TS, nil
+	case "CHIMP":
+		return CHIMP, nil
+	case "ELF":
+		return ELF, nil
+	case "AUTO":
+		return AUTO, nil
 	default:
-		panic("No encoding found: " + name)
+		if encoding, ok := lookupEncodingName(name); ok {
+			return encoding, nil
+		}
+		return 0, errors.New("No encoding found: " + name)
 	}
 }
+
+// String is the name GetEncodingByName takes, that of the registration for
+// the encodings of applications
+func (e TSEncoding) String() string {
+	switch e {
+	case PLAIN:
+		return "PLAIN"
+	case PLAIN_DICTIONARY:
+		return "PLAIN_DICTIONARY"
+	case RLE:
+		return "RLE"
+	case DIFF:
+		return "DIFF"
+	case TS_2DIFF:
+		return "TS_2DIFF"
+	case BITMAP:
+		return "BITMAP"
+	case GORILLA:
+		return "GORILLA"
+	case GORILLA_TS:
+		return "GORILLA_TS"
+	case CHIMP:
+		return "CHIMP"
+	case ELF:
+		return "ELF"
+	case AUTO:
+		return "AUTO"
+	default:
+		encodingNamesLock.RLock()
+		defer encodingNamesLock.RUnlock()
+		for name, encoding := range encodingNames {
+			if encoding == e {
+				return name
+			}
+		}
+		return "ENCODING_" + strconv.Itoa(int(e))
+	}
+}
+
+var (
+	encodingNames     = make(map[string]TSEncoding)
+	encodingNamesLock sync.RWMutex
+)
+
+// RegisterEncodingName makes GetEncodingByName know the name of an encoding
+// registered by an application, e.g. for the value_encoder property.
+func RegisterEncodingName(name string, encoding TSEncoding) {
+	encodingNamesLock.Lock()
+	defer encodingNamesLock.Unlock()
+	encodingNames[name] = encoding
+}
+
+func lookupEncodingName(name string) (TSEncoding, bool) {
+	encodingNamesLock.RLock()
+	defer encodingNamesLock.RUnlock()
+	encoding, ok := encodingNames[name]
+	return encoding, ok
+}
@@ -344,6 +344,7 @@ func WriteIntLittleEndianPaddedOnBitWidth(value int32, out *bytes.Buffer, bitWid
 }
 
 func WriteLongLittleEndianPaddedOnBitWidth(value int64, out *bytes.Buffer, bitWidth int) {
-	//paddedByteNum := (bitWidth + 7) / 8;
-	out.Write(Int64ToByte(value, 0))
+	// the lowest paddedByteNum bytes, as the decoder reads them
+	paddedByteNum := (bitWidth + 7) / 8
+	out.Write(Int64ToByte(value, 0)[8-paddedByteNum:])
 }
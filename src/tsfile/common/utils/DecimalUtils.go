@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DecimalPlaces returns the number of digits after the decimal point of the
+// shortest decimal string that reads back as v, bitSize is 32 for float32 values.
+func DecimalPlaces(v float64, bitSize int) int {
+	s := strconv.FormatFloat(v, 'f', -1, bitSize)
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return len(s) - i - 1
+	}
+	return 0
+}
+
+// CeilDecimal rounds the magnitude of v up to the given number of decimal places.
+func CeilDecimal(v float64, places int) float64 {
+	p := math.Pow10(places)
+	return math.Copysign(math.Ceil(math.Abs(v)*p)/p, v)
+}
+
+// CeilDecimalBits is CeilDecimal on the bits of a float32 (bitSize 32) or a float64 value.
+func CeilDecimalBits(bits int64, places int, bitSize int) int64 {
+	if bitSize == 32 {
+		v := CeilDecimal(float64(math.Float32frombits(uint32(bits))), places)
+		return int64(math.Float32bits(float32(v)))
+	}
+	return int64(math.Float64bits(CeilDecimal(math.Float64frombits(uint64(bits)), places)))
+}
@@ -172,6 +172,7 @@ func (f *FileReader) Pos() int64 {
 	return f.pos
 }
 
+// Skip from the position read to, the file is ahead of it by the buffer
 func (f *FileReader) Skip(length int32) (ret int64, err error) {
-	return f.Seek(int64(length), io.SeekCurrent)
+	return f.Seek(f.pos+int64(length), io.SeekStart)
 }
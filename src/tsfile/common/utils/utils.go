@@ -5,6 +5,7 @@ import (
 	"errors"
 	"math"
 	"reflect"
+	"sort"
 	"time"
 	"tsfile/common/log"
 )
@@ -44,6 +45,17 @@ func MapContains(target interface{}, obj interface{}) (bool, error) {
 	return false, errors.New("not in array")
 }
 
+// SortedKeys returns the keys of the map in increasing order, for output that
+// does not depend on the iteration order of the map
+func SortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func calculateTime() {
 	var d time.Duration
 	t0 := time.Now()
@@ -53,7 +65,9 @@ func calculateTime() {
 	log.Info("cost time = %v\n", d)
 }
 
+// Round rounds half up to n decimal digits like Math.round of Java, negative
+// values included
 func Round(f float64, n int) float64 {
 	pow10_n := math.Pow10(n)
-	return math.Trunc((f+0.5/pow10_n)*pow10_n) / pow10_n
+	return math.Floor((f+0.5/pow10_n)*pow10_n) / pow10_n
 }
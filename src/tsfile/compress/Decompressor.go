@@ -1,6 +1,8 @@
 package compress
 
 import (
+	"errors"
+	"strconv"
 	"tsfile/common/constant"
 )
 
@@ -9,16 +11,23 @@ type Decompressor interface {
 	Decompress(compressed []byte) ([]byte, error)
 }
 
-func GetDecompressor(name constant.CompressionType) Decompressor {
+// GetDecompressor fails for the compression types without a decompressor
+func GetDecompressor(name constant.CompressionType) (Decompressor, error) {
 	var decompressor Decompressor
 	switch {
 	case name == constant.UNCOMPRESSED:
 		decompressor = new(NoDecompressor)
 	case name == constant.SNAPPY:
 		decompressor = new(SnappyDecompressor)
+	case name == constant.GZIP:
+		decompressor = new(GzipDecompressor)
+	case name == constant.LZ4:
+		decompressor = new(LZ4Decompressor)
+	case name == constant.ZSTD:
+		decompressor = new(ZstdDecompressor)
 	default:
-		panic("Decompressor not found")
+		return nil, errors.New("compress: unsupported compression type " + strconv.Itoa(int(name)))
 	}
 
-	return decompressor
+	return decompressor, nil
 }
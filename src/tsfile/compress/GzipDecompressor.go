@@ -0,0 +1,28 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+)
+
+type GzipDecompressor struct{}
+
+func (g *GzipDecompressor) GetDecompressedLength(data []byte) (int, error) {
+	// the trailer ends with the length modulo 2^32
+	if len(data) < 4 {
+		return 0, errors.New("gzip: data too short")
+	}
+	return int(binary.LittleEndian.Uint32(data[len(data)-4:])), nil
+}
+
+func (g *GzipDecompressor) Decompress(compressed []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
@@ -0,0 +1,21 @@
+package compress
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/bkaradzic/go-lz4"
+)
+
+type LZ4Decompressor struct{}
+
+func (l *LZ4Decompressor) GetDecompressedLength(data []byte) (int, error) {
+	if len(data) < 4 {
+		return 0, errors.New("lz4: data too short")
+	}
+	return int(binary.LittleEndian.Uint32(data)), nil
+}
+
+func (l *LZ4Decompressor) Decompress(compressed []byte) ([]byte, error) {
+	return lz4.Decode(nil, compressed)
+}
@@ -0,0 +1,37 @@
+package compress
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	zstdDecoder     *zstd.Decoder
+	zstdDecoderErr  error
+	zstdDecoderOnce sync.Once
+)
+
+type ZstdDecompressor struct{}
+
+func (z *ZstdDecompressor) GetDecompressedLength(data []byte) (int, error) {
+	var header zstd.Header
+	if err := header.Decode(data); err != nil {
+		return 0, err
+	}
+	if !header.HasFCS {
+		return 0, errors.New("zstd: frame without content size")
+	}
+	return int(header.FrameContentSize), nil
+}
+
+func (z *ZstdDecompressor) Decompress(compressed []byte) ([]byte, error) {
+	zstdDecoderOnce.Do(func() {
+		zstdDecoder, zstdDecoderErr = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+	})
+	if zstdDecoderErr != nil {
+		return nil, zstdDecoderErr
+	}
+	return zstdDecoder.DecodeAll(compressed, nil)
+}
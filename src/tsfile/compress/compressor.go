@@ -1,6 +1,11 @@
 package compress
 
 import (
+	"compress/gzip"
+	"errors"
+	"strconv"
+	"tsfile/common/constant"
+
 	"github.com/golang/snappy"
 )
 
@@ -15,24 +20,41 @@ func (e *Encompress) DeCompress(compressed []byte) ([]byte, error) {
 	return snappy.Decode(nil, compressed)
 }
 
-func (e *Encompress) GetEncompressor(tsCompressionType int16) Encompressor {
-	var encompressor Encompressor
-	switch tsCompressionType {
-	case 0:
-		encompressor = new(NoEncompressor)
-	case 1:
-		encompressor = new(SnappyEncompressor)
-	//case 2:
-	//	encompressor = new(NoEncompressor)
-	//case 3:
-	//	encompressor = new(SnappyEncompressor)
-	//case 4:
-	//	encompressor = new(NoEncompressor)
-	//case 5:
-	//	encompressor = new(SnappyEncompressor)
+// GetEncompressor fails for the compression types without a compressor,
+// level 0 means the default level of the compressor.
+func (e *Encompress) GetEncompressor(tsCompressionType int16, level int) (Encompressor, error) {
+	switch constant.CompressionType(tsCompressionType) {
+	case constant.UNCOMPRESSED:
+		return new(NoEncompressor), nil
+	case constant.SNAPPY:
+		return new(SnappyEncompressor), nil
+	case constant.GZIP:
+		return NewGzipEncompressor(level), nil
+	case constant.LZ4:
+		return new(LZ4Encompressor), nil
+	case constant.ZSTD:
+		return NewZstdEncompressor(level), nil
+	default:
+		return nil, errors.New("compress: unsupported compression type " + strconv.Itoa(int(tsCompressionType)))
+	}
+}
+
+// CheckCompression tells whether pages can be written with the compression type and level.
+func CheckCompression(tsCompressionType int16, level int) error {
+	switch constant.CompressionType(tsCompressionType) {
+	case constant.UNCOMPRESSED, constant.SNAPPY, constant.LZ4:
+		return nil
+	case constant.GZIP:
+		if level != 0 && (level < gzip.HuffmanOnly || level > gzip.BestCompression) {
+			return errors.New("compress: invalid gzip level " + strconv.Itoa(level))
+		}
+		return nil
+	case constant.ZSTD:
+		if level < 0 || level > 22 {
+			return errors.New("compress: invalid zstd level " + strconv.Itoa(level))
+		}
+		return nil
 	default:
-		encompressor = new(NoEncompressor)
-		//log.Info("Encompressor not found, use default.")
+		return errors.New("compress: unsupported compression type " + strconv.Itoa(int(tsCompressionType)))
 	}
-	return encompressor
 }
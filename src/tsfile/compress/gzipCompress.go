@@ -0,0 +1,31 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+)
+
+type GzipEncompressor struct {
+	level int
+}
+
+func (g *GzipEncompressor) GetEncompressedLength(srcLen int) int {
+	// header and trailer, 5 bytes per stored block of 16KB at worst
+	return srcLen + 5*(srcLen/16383+1) + 18
+}
+
+func (g *GzipEncompressor) Encompress(dst []byte, src []byte) []byte {
+	buffer := bytes.NewBuffer(dst)
+	// the level is checked by CheckCompression
+	writer, _ := gzip.NewWriterLevel(buffer, g.level)
+	writer.Write(src)
+	writer.Close()
+	return buffer.Bytes()
+}
+
+func NewGzipEncompressor(level int) *GzipEncompressor {
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	return &GzipEncompressor{level: level}
+}
@@ -0,0 +1,68 @@
+package compress
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"tsfile/common/constant"
+)
+
+// LossyPoint is a point of a numeric series, the value converted to float64.
+type LossyPoint struct {
+	Time  int64
+	Value float64
+}
+
+// LossyFilter drops or approximates the points of a series before they are
+// encoded, trading bounded error for fewer points. The points are expected in
+// time order.
+type LossyFilter interface {
+	// Filter takes the next point and returns the points to write, possibly none
+	Filter(p LossyPoint) []LossyPoint
+	// Flush returns the points held back, the next point starts anew
+	Flush() []LossyPoint
+}
+
+// NewLossyFilter creates a filter of type SDT, PAA or PLA from its parameters:
+// SDT takes deviation, the width of the doors, and max_time, the longest time
+// between two written points where 0 means no limit, PAA takes window, the
+// number of points averaged into one, and PLA takes error_bound, the largest
+// difference of a point to the written line.
+func NewLossyFilter(filterType constant.CompressionType, params map[string]string) (LossyFilter, error) {
+	switch filterType {
+	case constant.SDT:
+		deviation, err := floatParam(params, "deviation")
+		if err != nil {
+			return nil, err
+		}
+		maxTime := int64(0)
+		if _, ok := params["max_time"]; ok {
+			if maxTime, err = strconv.ParseInt(params["max_time"], 10, 64); err != nil || maxTime < 0 {
+				return nil, errors.New("lossy filter: invalid max_time " + params["max_time"])
+			}
+		}
+		return NewSdtFilter(deviation, maxTime), nil
+	case constant.PAA:
+		window, err := strconv.Atoi(params["window"])
+		if err != nil || window <= 0 {
+			return nil, errors.New("lossy filter: invalid window " + params["window"])
+		}
+		return NewPaaFilter(window), nil
+	case constant.PLA:
+		errorBound, err := floatParam(params, "error_bound")
+		if err != nil {
+			return nil, err
+		}
+		return NewPlaFilter(errorBound), nil
+	default:
+		return nil, errors.New("lossy filter: unsupported type " + strconv.Itoa(int(filterType)))
+	}
+}
+
+func floatParam(params map[string]string, name string) (float64, error) {
+	v, err := strconv.ParseFloat(params[name], 64)
+	if err != nil || v < 0 || math.IsInf(v, 0) {
+		return 0, errors.New("lossy filter: invalid " + name + " " + params[name])
+	}
+	return v, nil
+}
@@ -0,0 +1,18 @@
+package compress
+
+import (
+	"github.com/bkaradzic/go-lz4"
+)
+
+// LZ4Encompressor writes lz4 blocks prefixed by the uncompressed length, lz4 has no levels.
+type LZ4Encompressor struct{}
+
+func (l *LZ4Encompressor) GetEncompressedLength(srcLen int) int {
+	return 4 + lz4.CompressBound(srcLen)
+}
+
+func (l *LZ4Encompressor) Encompress(dst []byte, src []byte) []byte {
+	// fails only for pages larger than 2GB, which a page never is
+	enc, _ := lz4.Encode(dst, src)
+	return enc
+}
@@ -0,0 +1,37 @@
+package compress
+
+// PaaFilter is the piecewise aggregate approximation, every window points are
+// written as one, at the time of the first of them, with their mean value.
+type PaaFilter struct {
+	window int
+
+	count     int
+	sum       float64
+	firstTime int64
+}
+
+func (f *PaaFilter) Filter(p LossyPoint) []LossyPoint {
+	if f.count == 0 {
+		f.firstTime = p.Time
+	}
+	f.count++
+	f.sum += p.Value
+	if f.count < f.window {
+		return nil
+	}
+	return f.Flush()
+}
+
+func (f *PaaFilter) Flush() []LossyPoint {
+	if f.count == 0 {
+		return nil
+	}
+	p := LossyPoint{f.firstTime, f.sum / float64(f.count)}
+	f.count = 0
+	f.sum = 0
+	return []LossyPoint{p}
+}
+
+func NewPaaFilter(window int) *PaaFilter {
+	return &PaaFilter{window: window}
+}
@@ -0,0 +1,79 @@
+package compress
+
+import (
+	"math"
+)
+
+// PlaFilter approximates the series by connected line segments. A segment
+// starts at the end of the previous one and is extended while a line through
+// its start passes within errorBound of every point of it, the end of the
+// segment is written on that line at the time of its last point.
+type PlaFilter struct {
+	errorBound float64
+
+	started bool
+	anchor  LossyPoint
+	last    LossyPoint
+	hasLast bool
+	// range of the slopes of the lines within errorBound of the points since anchor
+	lowerSlope float64
+	upperSlope float64
+}
+
+func (f *PlaFilter) Filter(p LossyPoint) []LossyPoint {
+	if !f.started || p.Time <= f.anchor.Time || (f.hasLast && p.Time <= f.last.Time) {
+		// first point, or out of order, starts anew
+		out := f.Flush()
+		f.start(p)
+		return append(out, p)
+	}
+	if f.extend(p) {
+		return nil
+	}
+	// the segment ends at the previous point, extending always succeeds
+	// for the first point of a segment
+	end := f.end()
+	f.start(end)
+	f.extend(p)
+	return []LossyPoint{end}
+}
+
+func (f *PlaFilter) extend(p LossyPoint) bool {
+	dt := float64(p.Time - f.anchor.Time)
+	lowerSlope := math.Max(f.lowerSlope, (p.Value-f.errorBound-f.anchor.Value)/dt)
+	upperSlope := math.Min(f.upperSlope, (p.Value+f.errorBound-f.anchor.Value)/dt)
+	if lowerSlope > upperSlope {
+		return false
+	}
+	f.lowerSlope, f.upperSlope = lowerSlope, upperSlope
+	f.last = p
+	f.hasLast = true
+	return true
+}
+
+// end returns the point of the segment line at the time of its last point
+func (f *PlaFilter) end() LossyPoint {
+	slope := (f.lowerSlope + f.upperSlope) / 2
+	return LossyPoint{f.last.Time, f.anchor.Value + slope*float64(f.last.Time-f.anchor.Time)}
+}
+
+func (f *PlaFilter) start(p LossyPoint) {
+	f.started = true
+	f.anchor = p
+	f.hasLast = false
+	f.lowerSlope = math.Inf(-1)
+	f.upperSlope = math.Inf(1)
+}
+
+func (f *PlaFilter) Flush() []LossyPoint {
+	f.started = false
+	if !f.hasLast {
+		return nil
+	}
+	f.hasLast = false
+	return []LossyPoint{f.end()}
+}
+
+func NewPlaFilter(errorBound float64) *PlaFilter {
+	return &PlaFilter{errorBound: errorBound}
+}
@@ -0,0 +1,87 @@
+package compress
+
+import (
+	"math"
+)
+
+// SdtFilter is the swinging door trending filter. The doors are opened from the
+// last written point to the points received since, deviation above and below
+// them. A point is held back while the doors still have room, once a point
+// closes them, or is more than maxTime after the last written point, the point
+// before it is written and the doors restart from there.
+type SdtFilter struct {
+	deviation float64
+	maxTime   int64
+
+	started  bool
+	archived LossyPoint
+	// the last point received and not written yet
+	last    LossyPoint
+	hasLast bool
+	// slopes of the upper and the lower door
+	upperSlope float64
+	lowerSlope float64
+}
+
+func (f *SdtFilter) Filter(p LossyPoint) []LossyPoint {
+	if !f.started || p.Time <= f.archived.Time || (f.hasLast && p.Time <= f.last.Time) {
+		// first point, or out of order, starts anew
+		out := f.Flush()
+		f.archive(p)
+		return append(out, p)
+	}
+	if f.open(p) {
+		return nil
+	}
+	if !f.hasLast {
+		f.archive(p)
+		return []LossyPoint{p}
+	}
+	// the doors closed, the point before is written
+	last := f.last
+	f.archive(last)
+	if f.open(p) {
+		return []LossyPoint{last}
+	}
+	f.archive(p)
+	return []LossyPoint{last, p}
+}
+
+// open narrows the doors to the point and holds it back, it returns false if
+// the doors closed or the point is too late.
+func (f *SdtFilter) open(p LossyPoint) bool {
+	if f.maxTime > 0 && p.Time-f.archived.Time > f.maxTime {
+		return false
+	}
+	dt := float64(p.Time - f.archived.Time)
+	upperSlope := math.Min(f.upperSlope, (p.Value+f.deviation-f.archived.Value)/dt)
+	lowerSlope := math.Max(f.lowerSlope, (p.Value-f.deviation-f.archived.Value)/dt)
+	if lowerSlope > upperSlope {
+		return false
+	}
+	f.upperSlope, f.lowerSlope = upperSlope, lowerSlope
+	f.last = p
+	f.hasLast = true
+	return true
+}
+
+func (f *SdtFilter) archive(p LossyPoint) {
+	f.started = true
+	f.archived = p
+	f.hasLast = false
+	f.upperSlope = math.Inf(1)
+	f.lowerSlope = math.Inf(-1)
+}
+
+func (f *SdtFilter) Flush() []LossyPoint {
+	f.started = false
+	if !f.hasLast {
+		return nil
+	}
+	f.hasLast = false
+	return []LossyPoint{f.last}
+}
+
+func NewSdtFilter(deviation float64, maxTime int64) *SdtFilter {
+	return &SdtFilter{deviation: deviation, maxTime: maxTime}
+}
@@ -0,0 +1,46 @@
+package compress
+
+import (
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// the zstd encoders are expensive to create and safe for concurrent EncodeAll,
+// one is shared per level
+var (
+	zstdEncoders     = make(map[int]*zstd.Encoder)
+	zstdEncodersLock sync.Mutex
+)
+
+func getZstdEncoder(level int) *zstd.Encoder {
+	zstdEncodersLock.Lock()
+	defer zstdEncodersLock.Unlock()
+	if encoder, ok := zstdEncoders[level]; ok {
+		return encoder
+	}
+	encoderLevel := zstd.SpeedDefault
+	if level != 0 {
+		encoderLevel = zstd.EncoderLevelFromZstd(level)
+	}
+	encoder, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(encoderLevel), zstd.WithEncoderConcurrency(1))
+	zstdEncoders[level] = encoder
+	return encoder
+}
+
+type ZstdEncompressor struct {
+	encoder *zstd.Encoder
+}
+
+func (z *ZstdEncompressor) GetEncompressedLength(srcLen int) int {
+	return z.encoder.MaxEncodedSize(srcLen)
+}
+
+func (z *ZstdEncompressor) Encompress(dst []byte, src []byte) []byte {
+	return z.encoder.EncodeAll(src, dst)
+}
+
+// NewZstdEncompressor takes the zstd level, 0 means the default level
+func NewZstdEncompressor(level int) *ZstdEncompressor {
+	return &ZstdEncompressor{encoder: getZstdEncoder(level)}
+}
@@ -0,0 +1,47 @@
+package decoder
+
+import (
+	"errors"
+	"tsfile/common/constant"
+)
+
+// AutoDecoder decodes the pages written by encoder.AutoEncoder with the decoder
+// of the encoding recorded in the first byte of the page.
+type AutoDecoder struct {
+	dataType constant.TSDataType
+	decoder  Decoder
+	// the page byte names an encoding without a decoder
+	err error
+}
+
+func (d *AutoDecoder) Init(data []byte) {
+	d.decoder, d.err = nil, nil
+	if len(data) == 0 {
+		return
+	}
+	// decoders keep state across pages, a page gets a fresh one
+	decoder, err := CreateDecoder(constant.TSEncoding(data[0]), d.dataType)
+	if err != nil {
+		d.err = errors.New("auto: " + err.Error())
+		return
+	}
+	d.decoder = decoder
+	d.decoder.Init(data[1:])
+}
+
+// Err returns why the page last given to Init can not be decoded, if it can not
+func (d *AutoDecoder) Err() error {
+	return d.err
+}
+
+func (d *AutoDecoder) HasNext() bool {
+	return d.decoder != nil && d.decoder.HasNext()
+}
+
+func (d *AutoDecoder) Next() interface{} {
+	return d.decoder.Next()
+}
+
+func NewAutoDecoder(dataType constant.TSDataType) *AutoDecoder {
+	return &AutoDecoder{dataType: dataType}
+}
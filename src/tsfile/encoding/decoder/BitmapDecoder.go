@@ -31,6 +31,10 @@ func (d *BitmapDecoder) Init(data []byte) {
 	d.currentCount = 0
 }
 
+func (d *BitmapDecoder) HasNext() bool {
+	return d.currentCount > 0 || d.reader.Len() > 0
+}
+
 func (d *BitmapDecoder) Next() interface{} {
 	if d.currentCount == 0 {
 		// reset
@@ -57,6 +61,9 @@ func (d *BitmapDecoder) Next() interface{} {
 
 	d.currentCount--
 
+	if d.dataType == constant.BOOLEAN {
+		return result != 0
+	}
 	return result
 }
 
@@ -73,3 +80,7 @@ func (d *BitmapDecoder) readPackage() {
 
 	d.currentCount = d.number
 }
+
+func NewBitmapDecoder(dataType constant.TSDataType) *BitmapDecoder {
+	return &BitmapDecoder{encoding: constant.BITMAP, dataType: dataType}
+}
@@ -0,0 +1,119 @@
+package decoder
+
+import (
+	"math"
+	"tsfile/common/conf"
+	"tsfile/common/constant"
+	"tsfile/common/utils"
+)
+
+// leading zero nums of the 3 bits leading codes, see encoder.ChimpEncoder
+var chimpLeadingRound = []int32{0, 8, 12, 16, 18, 20, 22, 24}
+
+// ChimpDecoder decodes the pages written by encoder.ChimpEncoder, it reads one
+// value ahead to know where the NaN end mark is.
+type ChimpDecoder struct {
+	dataType constant.TSDataType
+	reader   *utils.BytesReader
+
+	base        GorillaDecoder
+	valueLength int
+	lengthLen   int
+	flag        bool
+	value       int64
+	hasNext     bool
+	// leading zero num of the previous xor, valueLength+1 if it can not be reused
+	storedLeadingZeroNum int32
+}
+
+func (d *ChimpDecoder) Init(data []byte) {
+	d.init(data)
+	if d.hasNext {
+		_, d.hasNext = d.readValue()
+	}
+}
+
+func (d *ChimpDecoder) init(data []byte) {
+	d.reader = utils.NewBytesReader(data)
+	d.base = GorillaDecoder{}
+	d.flag = false
+	d.hasNext = d.reader.Len() > 0
+}
+
+func (d *ChimpDecoder) HasNext() bool {
+	return d.hasNext
+}
+
+func (d *ChimpDecoder) Next() interface{} {
+	value := d.value
+	_, d.hasNext = d.readValue()
+	return d.toFloat(value)
+}
+
+func (d *ChimpDecoder) toFloat(bits int64) interface{} {
+	if d.dataType == constant.FLOAT {
+		return math.Float32frombits(uint32(bits))
+	}
+	return math.Float64frombits(uint64(bits))
+}
+
+func (d *ChimpDecoder) nanBits() int64 {
+	if d.dataType == constant.FLOAT {
+		return 0x7fc00000
+	}
+	return 0x7ff8000000000000
+}
+
+// readValue decodes the bits of the next value, false at the end mark
+func (d *ChimpDecoder) readValue() (int64, bool) {
+	bits := d.readBits()
+	if bits == d.nanBits() {
+		// a NaN value is flagged by '1'
+		return bits, d.base.readBit(d.reader)
+	}
+	return bits, true
+}
+
+// readBits decodes the bits of the next value
+func (d *ChimpDecoder) readBits() int64 {
+	if !d.flag {
+		d.flag = true
+		d.storedLeadingZeroNum = int32(d.valueLength + 1)
+		d.value = d.base.readLongFromStream(d.reader, d.valueLength)
+		return d.value
+	}
+
+	value := d.value
+	switch d.base.readIntFromStream(d.reader, 2) {
+	case 0:
+		// case: '00'
+	case 1:
+		// case: '01'
+		leadingZeroNum := int(chimpLeadingRound[d.base.readIntFromStream(d.reader, 3)])
+		length := int(d.base.readIntFromStream(d.reader, d.lengthLen))
+		xor := d.base.readLongFromStream(d.reader, length)
+		value ^= xor << uint(d.valueLength-leadingZeroNum-length)
+		d.storedLeadingZeroNum = int32(d.valueLength + 1)
+	case 2:
+		// case: '10'
+		value ^= d.base.readLongFromStream(d.reader, d.valueLength-int(d.storedLeadingZeroNum))
+	default:
+		// case: '11'
+		d.storedLeadingZeroNum = chimpLeadingRound[d.base.readIntFromStream(d.reader, 3)]
+		value ^= d.base.readLongFromStream(d.reader, d.valueLength-int(d.storedLeadingZeroNum))
+	}
+	d.value = value
+	return value
+}
+
+func NewChimpDecoder(dataType constant.TSDataType) *ChimpDecoder {
+	d := &ChimpDecoder{dataType: dataType}
+	if dataType == constant.FLOAT {
+		d.valueLength = conf.FLOAT_LENGTH
+		d.lengthLen = 5
+	} else {
+		d.valueLength = conf.DOUBLE_LENGTH
+		d.lengthLen = 6
+	}
+	return d
+}
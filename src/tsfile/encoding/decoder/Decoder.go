@@ -2,6 +2,7 @@ package decoder
 
 import (
 	_ "bytes"
+	"errors"
 	_ "os"
 	"strconv"
 	"tsfile/common/constant"
@@ -12,19 +13,47 @@ const (
 	BIT_PACKED = 1
 )
 
+// the most values a block is decoded to, far above the blocks written. A larger
+// count is corrupted data, it must not make the decoder allocate gigabytes.
+const maxBlockValues = 1 << 20
+
+func checkBlockValues(decoder string, count int) {
+	if count < 0 || count > maxBlockValues {
+		panic("tsfile-encoding " + decoder + ": block of " + strconv.Itoa(count) + " values")
+	}
+}
+
 type Decoder interface {
 	Init(data []byte)
 	HasNext() bool
 	Next() interface{}
 }
 
-func CreateDecoder(encoding constant.TSEncoding, dataType constant.TSDataType) Decoder {
+// Err returns why d can not decode the page last given to its Init, for the
+// decoders that can tell, e.g. the AutoDecoder of a page of an unknown encoding.
+func Err(d Decoder) error {
+	if e, ok := d.(interface{ Err() error }); ok {
+		return e.Err()
+	}
+	return nil
+}
+
+// CreateDecoder fails for the encodings and data types without a decoder, e.g.
+// a custom encoding that is not registered.
+func CreateDecoder(encoding constant.TSEncoding, dataType constant.TSDataType) (Decoder, error) {
 	// PLA and DFT encoding are not supported in current version
-	var decoder Decoder
+	if decoder, ok := registeredDecoder(encoding, dataType); ok {
+		return decoder, nil
+	}
 
+	var decoder Decoder
 	switch {
 	case encoding == constant.PLAIN:
 		decoder = &PlainDecoder{dataType: dataType}
+	case encoding == constant.PLAIN_DICTIONARY:
+		if dataType == constant.TEXT {
+			decoder = NewDictionaryDecoder()
+		}
 	case encoding == constant.RLE:
 		if dataType == constant.BOOLEAN {
 			decoder = NewIntRleDecoder(dataType)
@@ -47,15 +76,37 @@ func CreateDecoder(encoding constant.TSEncoding, dataType constant.TSDataType) D
 		} else if dataType == constant.DOUBLE {
 			decoder = NewDoubleDecoder(encoding, dataType)
 		}
+	case encoding == constant.BITMAP:
+		if dataType == constant.BOOLEAN || dataType == constant.INT32 {
+			decoder = NewBitmapDecoder(dataType)
+		}
 	case encoding == constant.GORILLA:
 		if dataType == constant.FLOAT {
 			decoder = NewSinglePrecisionDecoder(dataType)
 		} else if dataType == constant.DOUBLE {
 			decoder = NewDoublePrecisionDecoder(dataType)
+		} else if dataType == constant.INT32 || dataType == constant.INT64 {
+			decoder = NewIntGorillaDecoder(dataType)
+		}
+	case encoding == constant.CHIMP:
+		if dataType == constant.FLOAT || dataType == constant.DOUBLE {
+			decoder = NewChimpDecoder(dataType)
+		}
+	case encoding == constant.ELF:
+		if dataType == constant.FLOAT || dataType == constant.DOUBLE {
+			decoder = NewElfDecoder(dataType)
+		}
+	case encoding == constant.AUTO:
+		decoder = NewAutoDecoder(dataType)
+	case encoding == constant.GORILLA_TS:
+		if dataType == constant.INT64 {
+			decoder = NewGorillaTimeDecoder()
 		}
-	default:
-		panic("Decoder not found, encoding:" + strconv.Itoa(int(encoding)) + ", dataType:" + strconv.Itoa(int(dataType)))
 	}
 
-	return decoder
+	if decoder == nil {
+		return nil, errors.New("decoder: unsupported encoding " + strconv.Itoa(int(encoding)) +
+			" of data type " + strconv.Itoa(int(dataType)))
+	}
+	return decoder, nil
 }
@@ -0,0 +1,99 @@
+package decoder
+
+import (
+	"tsfile/common/constant"
+	"tsfile/common/utils"
+	"tsfile/encoding/bitpacking"
+)
+
+type DictionaryDecoder struct {
+	reader *utils.BytesReader
+	// set if the page fell back to plain
+	plainDecoder *PlainDecoder
+
+	entries []string
+	packer  *bitpacking.IntPacker
+	// values of the page not read yet
+	valueCount int
+
+	// current run
+	mode          int
+	currentCount  int
+	currentIndex  int32
+	decodedValues []int32
+	decodedPos    int
+}
+
+func (d *DictionaryDecoder) Init(data []byte) {
+	d.reader = utils.NewBytesReader(data)
+	d.plainDecoder = nil
+	d.entries = d.entries[0:0]
+	d.valueCount = 0
+	d.currentCount = 0
+	if d.reader.Len() == 0 {
+		return
+	}
+
+	if byte(d.reader.Read()) == constant.DICTIONARY_PLAIN_MODE {
+		d.plainDecoder = &PlainDecoder{dataType: constant.TEXT}
+		d.plainDecoder.Init(d.reader.Remaining())
+		return
+	}
+	size := int(d.reader.ReadUnsignedVarInt())
+	for i := 0; i < size; i++ {
+		length := int(d.reader.ReadUnsignedVarInt())
+		d.entries = append(d.entries, string(d.reader.ReadSlice(length)))
+	}
+	d.valueCount = int(d.reader.ReadUnsignedVarInt())
+	d.packer = &bitpacking.IntPacker{BitWidth: int(d.reader.Read())}
+}
+
+func (d *DictionaryDecoder) HasNext() bool {
+	if d.plainDecoder != nil {
+		return d.plainDecoder.HasNext()
+	}
+	return d.valueCount > 0
+}
+
+func (d *DictionaryDecoder) Next() interface{} {
+	if d.plainDecoder != nil {
+		return d.plainDecoder.Next()
+	}
+	if d.currentCount == 0 {
+		d.readRun()
+	}
+	d.currentCount--
+	d.valueCount--
+
+	index := d.currentIndex
+	if d.mode == BIT_PACKED {
+		index = d.decodedValues[d.decodedPos]
+		d.decodedPos++
+	}
+	if int(index) >= len(d.entries) {
+		panic("tsfile-encoding DictionaryDecoder: index out of dictionary")
+	}
+	return d.entries[index]
+}
+
+func (d *DictionaryDecoder) readRun() {
+	header := int(d.reader.ReadUnsignedVarInt())
+	d.currentCount = header >> 1
+	if header&1 == 0 {
+		d.mode = RLE
+		d.currentIndex = d.reader.ReadUnsignedVarInt()
+		return
+	}
+
+	d.mode = BIT_PACKED
+	groupCount := (d.currentCount + bitpacking.NUM_OF_INTS - 1) / bitpacking.NUM_OF_INTS
+	bytesToRead := groupCount * d.packer.BitWidth
+	checkBlockValues("DictionaryDecoder", groupCount*bitpacking.NUM_OF_INTS)
+	d.decodedValues = make([]int32, groupCount*bitpacking.NUM_OF_INTS)
+	d.packer.UnpackAllValues(d.reader.ReadSlice(bytesToRead), bytesToRead, d.decodedValues)
+	d.decodedPos = 0
+}
+
+func NewDictionaryDecoder() *DictionaryDecoder {
+	return &DictionaryDecoder{entries: make([]string, 0)}
+}
@@ -0,0 +1,56 @@
+package decoder
+
+import (
+	"tsfile/common/constant"
+	"tsfile/common/utils"
+)
+
+// ElfDecoder decodes the pages written by encoder.ElfEncoder, it restores the
+// erased values and reads one value ahead to know where the end mark is.
+type ElfDecoder struct {
+	chimp *ChimpDecoder
+	// decimal places of the previous erased value
+	preDecimalPlaces int
+	value            int64
+	hasNext          bool
+}
+
+func (d *ElfDecoder) Init(data []byte) {
+	d.chimp.init(data)
+	d.preDecimalPlaces = 0
+	d.hasNext = d.chimp.hasNext
+	if d.hasNext {
+		d.readNext()
+	}
+}
+
+func (d *ElfDecoder) HasNext() bool {
+	return d.hasNext
+}
+
+func (d *ElfDecoder) Next() interface{} {
+	value := d.value
+	d.readNext()
+	return d.chimp.toFloat(value)
+}
+
+func (d *ElfDecoder) readNext() {
+	base := &d.chimp.base
+	erased := base.readBit(d.chimp.reader)
+	if erased && base.readBit(d.chimp.reader) {
+		d.preDecimalPlaces = int(base.readIntFromStream(d.chimp.reader, 4))
+	}
+	bits, ok := d.chimp.readValue()
+	if !ok {
+		d.hasNext = false
+		return
+	}
+	if erased {
+		bits = utils.CeilDecimalBits(bits, d.preDecimalPlaces, d.chimp.valueLength)
+	}
+	d.value = bits
+}
+
+func NewElfDecoder(dataType constant.TSDataType) *ElfDecoder {
+	return &ElfDecoder{chimp: NewChimpDecoder(dataType)}
+}
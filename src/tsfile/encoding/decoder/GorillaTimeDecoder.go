@@ -0,0 +1,68 @@
+package decoder
+
+import (
+	"tsfile/common/utils"
+)
+
+// GorillaTimeDecoder decodes the pages written by encoder.GorillaTimeEncoder, it
+// reads one timestamp ahead to know where the end mark is.
+type GorillaTimeDecoder struct {
+	reader *utils.BytesReader
+
+	base     GorillaDecoder
+	value    int64
+	preDelta int64
+	hasNext  bool
+}
+
+func (d *GorillaTimeDecoder) Init(data []byte) {
+	d.reader = utils.NewBytesReader(data)
+	d.base = GorillaDecoder{}
+	d.preDelta = 0
+	d.hasNext = d.reader.Len() > 0
+	if d.hasNext {
+		d.value = d.base.readLongFromStream(d.reader, 64)
+	}
+}
+
+func (d *GorillaTimeDecoder) HasNext() bool {
+	return d.hasNext
+}
+
+func (d *GorillaTimeDecoder) Next() interface{} {
+	value := d.value
+	d.readNext()
+	return value
+}
+
+func (d *GorillaTimeDecoder) readNext() {
+	// count the leading '1' of the control bits, at most 5
+	ones := 0
+	for ones < 5 && d.base.readBit(d.reader) {
+		ones++
+	}
+
+	var dod int64
+	switch ones {
+	case 0:
+		dod = 0
+	case 1:
+		dod = d.base.readLongFromStream(d.reader, 7) - 63
+	case 2:
+		dod = d.base.readLongFromStream(d.reader, 9) - 255
+	case 3:
+		dod = d.base.readLongFromStream(d.reader, 12) - 2047
+	case 4:
+		dod = d.base.readLongFromStream(d.reader, 64)
+	default:
+		// end mark '11111'
+		d.hasNext = false
+		return
+	}
+	d.preDelta += dod
+	d.value += d.preDelta
+}
+
+func NewGorillaTimeDecoder() *GorillaTimeDecoder {
+	return &GorillaTimeDecoder{}
+}
@@ -53,6 +53,7 @@ func (d *IntDeltaDecoder) loadPack() int32 {
 	d.firstValue = d.reader.ReadInt()
 
 	d.index = 0
+	checkBlockValues("IntDeltaDecoder", d.count)
 
 	//how many bytes data takes after encoding
 	encodingLength := int(math.Ceil(float64(d.count*d.width) / 8.0))
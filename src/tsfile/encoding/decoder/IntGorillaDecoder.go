@@ -0,0 +1,86 @@
+package decoder
+
+import (
+	"tsfile/common/conf"
+	"tsfile/common/constant"
+	"tsfile/common/utils"
+)
+
+// IntGorillaDecoder decodes the pages written by encoder.IntGorillaEncoder, it
+// reads one value ahead to know where the end mark is.
+type IntGorillaDecoder struct {
+	dataType constant.TSDataType
+	reader   *utils.BytesReader
+
+	base        GorillaDecoder
+	valueLength int
+	leadingLen  int
+	lengthLen   int
+	value       int64
+	hasNext     bool
+}
+
+func (d *IntGorillaDecoder) Init(data []byte) {
+	d.reader = utils.NewBytesReader(data)
+	d.base = GorillaDecoder{}
+	d.hasNext = d.reader.Len() > 0
+	if d.hasNext {
+		d.value = d.base.readLongFromStream(d.reader, d.valueLength)
+		// no window yet, the first xor starts one
+		d.base.leadingZeroNum = int32(d.valueLength)
+		d.base.tailingZeroNum = 0
+	}
+}
+
+func (d *IntGorillaDecoder) HasNext() bool {
+	return d.hasNext
+}
+
+func (d *IntGorillaDecoder) Next() interface{} {
+	value := d.value
+	d.readNext()
+	if d.dataType == constant.INT32 {
+		return int32(value)
+	}
+	return value
+}
+
+func (d *IntGorillaDecoder) readNext() {
+	// case: '0'
+	if !d.base.readBit(d.reader) {
+		return
+	}
+	if !d.base.readBit(d.reader) {
+		// case: '10'
+		length := d.valueLength - int(d.base.leadingZeroNum+d.base.tailingZeroNum)
+		tmp := d.base.readLongFromStream(d.reader, length)
+		d.value ^= tmp << uint(d.base.tailingZeroNum)
+		return
+	}
+	// case: '11'
+	leadingZeroNum := int(d.base.readIntFromStream(d.reader, d.leadingLen))
+	length := int(d.base.readIntFromStream(d.reader, d.lengthLen))
+	if length == 0 {
+		d.hasNext = false
+		return
+	}
+	tailingZeroNum := d.valueLength - leadingZeroNum - length
+	tmp := d.base.readLongFromStream(d.reader, length)
+	d.value ^= tmp << uint(tailingZeroNum)
+	d.base.leadingZeroNum = int32(leadingZeroNum)
+	d.base.tailingZeroNum = int32(tailingZeroNum)
+}
+
+func NewIntGorillaDecoder(dataType constant.TSDataType) *IntGorillaDecoder {
+	d := &IntGorillaDecoder{dataType: dataType}
+	if dataType == constant.INT32 {
+		d.valueLength = conf.FLOAT_LENGTH
+		d.leadingLen = conf.FLAOT_LEADING_ZERO_LENGTH
+		d.lengthLen = conf.FLOAT_VALUE_LENGTH
+	} else {
+		d.valueLength = conf.DOUBLE_LENGTH
+		d.leadingLen = conf.DOUBLE_LEADING_ZERO_LENGTH
+		d.lengthLen = conf.DOUBLE_VALUE_LENGTH
+	}
+	return d
+}
@@ -96,6 +96,9 @@ func (d *IntRleDecoder) Next() interface{} {
 	//		d.isReadingBegan = false
 	//	}
 
+	if d.dataType == constant.BOOLEAN {
+		return result != 0
+	}
 	return result
 }
 
@@ -137,6 +140,7 @@ func (d *IntRleDecoder) readBitPackingBuffer(bitPackedGroupCount int, lastBitPac
 	}
 	bytes := d.packageReader.ReadSlice(int(bytesToRead))
 
+	checkBlockValues("IntRleDecoder", bitPackedGroupCount*conf.RLE_MIN_REPEATED_NUM)
 	d.decodedValues = make([]int32, bitPackedGroupCount*conf.RLE_MIN_REPEATED_NUM)
 	d.packer.UnpackAllValues(bytes, bytesToRead, d.decodedValues)
 }
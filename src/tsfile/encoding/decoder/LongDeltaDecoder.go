@@ -53,6 +53,7 @@ func (d *LongDeltaDecoder) loadPack() int64 {
 	d.firstValue = d.reader.ReadLong()
 
 	d.index = 0
+	checkBlockValues("LongDeltaDecoder", d.count)
 
 	//how many bytes data takes after encoding
 	encodingLength := int(math.Ceil(float64(d.count*d.width) / 8.0))
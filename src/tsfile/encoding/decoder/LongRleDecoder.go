@@ -137,6 +137,7 @@ func (d *LongRleDecoder) readBitPackingBuffer(bitPackedGroupCount int, lastBitPa
 	}
 	bytes := d.packageReader.ReadSlice(int(bytesToRead))
 
+	checkBlockValues("LongRleDecoder", bitPackedGroupCount*conf.RLE_MIN_REPEATED_NUM)
 	d.decodedValues = make([]int64, bitPackedGroupCount*(conf.RLE_MIN_REPEATED_NUM))
 	d.packer.UnpackAllValues(bytes, bytesToRead, d.decodedValues)
 }
@@ -0,0 +1,44 @@
+package decoder
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"tsfile/common/constant"
+)
+
+// DecoderFactory creates the decoder of a page of the given data type.
+type DecoderFactory func(dataType constant.TSDataType) Decoder
+
+var (
+	factories     = make(map[constant.TSEncoding]map[constant.TSDataType]DecoderFactory)
+	factoriesLock sync.RWMutex
+)
+
+// RegisterDecoder makes CreateDecoder use the factory for the given encoding and data
+// types. The encoding must be above constant.MAX_BUILTIN_ENCODING, registering it
+// again for a data type replaces the factory.
+func RegisterDecoder(encoding constant.TSEncoding, factory DecoderFactory, dataTypes ...constant.TSDataType) error {
+	if encoding <= constant.MAX_BUILTIN_ENCODING {
+		return errors.New("decoder: can not register the built-in encoding " + strconv.Itoa(int(encoding)))
+	}
+	factoriesLock.Lock()
+	defer factoriesLock.Unlock()
+	if _, ok := factories[encoding]; !ok {
+		factories[encoding] = make(map[constant.TSDataType]DecoderFactory)
+	}
+	for _, dataType := range dataTypes {
+		factories[encoding][dataType] = factory
+	}
+	return nil
+}
+
+func registeredDecoder(encoding constant.TSEncoding, dataType constant.TSDataType) (Decoder, bool) {
+	factoriesLock.RLock()
+	factory, ok := factories[encoding][dataType]
+	factoriesLock.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return factory(dataType), true
+}
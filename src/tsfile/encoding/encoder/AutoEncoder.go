@@ -0,0 +1,124 @@
+package encoder
+
+import (
+	"bytes"
+	"tsfile/common/conf"
+	"tsfile/common/constant"
+)
+
+// auto-page: <encoding> <page>
+// 		encoding := one byte, the TSEncoding the page is written with
+// 		page := the values as written by the encoder of that encoding
+// The candidates are tried on the first conf.AutoEncodingSampleSize values of the
+// page, the page is written PLAIN if the smallest of them does not beat it on the
+// whole page. An empty page is written as nothing.
+
+// AUTO_CANDIDATES are the lossless encodings of each data type, RLE and TS_2DIFF
+// of FLOAT and DOUBLE keep conf.FloatPrecision digits only.
+var AUTO_CANDIDATES = map[constant.TSDataType][]constant.TSEncoding{
+	constant.BOOLEAN: {constant.RLE, constant.BITMAP},
+	constant.INT32:   {constant.RLE, constant.TS_2DIFF, constant.GORILLA, constant.BITMAP},
+	constant.INT64:   {constant.RLE, constant.TS_2DIFF, constant.GORILLA},
+	constant.FLOAT:   {constant.GORILLA, constant.CHIMP, constant.ELF},
+	constant.DOUBLE:  {constant.GORILLA, constant.CHIMP, constant.ELF},
+	constant.TEXT:    {constant.PLAIN_DICTIONARY},
+}
+
+type AutoEncoder struct {
+	dataType constant.TSDataType
+
+	values []interface{}
+	// gorilla, chimp and elf pages end with NaN, they can not hold it
+	hasNaN       bool
+	plainEncoder *PlainEncoder
+	plainBuf     *bytes.Buffer
+}
+
+func (d *AutoEncoder) Encode(value interface{}, buffer *bytes.Buffer) {
+	d.values = append(d.values, value)
+	switch data := value.(type) {
+	case float32:
+		d.hasNaN = d.hasNaN || data != data
+	case float64:
+		d.hasNaN = d.hasNaN || data != data
+	}
+	d.plainEncoder.Encode(value, d.plainBuf)
+}
+
+func (d *AutoEncoder) Flush(buffer *bytes.Buffer) {
+	if len(d.values) > 0 {
+		encoding, data := d.choose()
+		buffer.WriteByte(byte(encoding))
+		buffer.Write(data)
+	}
+	d.values = d.values[0:0]
+	d.hasNaN = false
+	d.plainBuf.Reset()
+}
+
+// choose returns the encoding the page is written with and the encoded page
+func (d *AutoEncoder) choose() (constant.TSEncoding, []byte) {
+	sample := d.values
+	sampled := conf.AutoEncodingSampleSize > 0 && len(d.values) > conf.AutoEncodingSampleSize
+	if sampled {
+		sample = d.values[:conf.AutoEncodingSampleSize]
+	}
+
+	best := constant.PLAIN
+	bestData := d.plainBuf.Bytes()
+	if sampled {
+		bestData = d.encodeWith(constant.PLAIN, sample)
+	}
+	candidates := AUTO_CANDIDATES[d.dataType]
+	if d.hasNaN {
+		candidates = nil
+	}
+	for _, encoding := range candidates {
+		if data := d.encodeWith(encoding, sample); data != nil && len(data) < len(bestData) {
+			best, bestData = encoding, data
+		}
+	}
+
+	if !sampled {
+		return best, bestData
+	}
+	if best != constant.PLAIN {
+		if data := d.encodeWith(best, d.values); len(data) < d.plainBuf.Len() {
+			return best, data
+		}
+	}
+	return constant.PLAIN, d.plainBuf.Bytes()
+}
+
+// encodeWith returns nil if the encoding has no encoder of the data type
+func (d *AutoEncoder) encodeWith(encoding constant.TSEncoding, values []interface{}) []byte {
+	buffer := bytes.NewBuffer([]byte{})
+	encoder, err := GetEncoder(int16(encoding), int16(d.dataType))
+	if err != nil {
+		return nil
+	}
+	for _, value := range values {
+		encoder.Encode(value, buffer)
+	}
+	encoder.Flush(buffer)
+	return buffer.Bytes()
+}
+
+func (d *AutoEncoder) GetMaxByteSize() int64 {
+	// the chosen encoding is never larger than plain
+	return int64(1 + d.plainBuf.Len())
+}
+
+func (d *AutoEncoder) GetOneItemMaxSize() int {
+	return d.plainEncoder.GetOneItemMaxSize()
+}
+
+func NewAutoEncoder(dataType constant.TSDataType) *AutoEncoder {
+	plainEncoder, _ := NewPlainEncoder(dataType)
+	return &AutoEncoder{
+		dataType:     dataType,
+		values:       make([]interface{}, 0),
+		plainEncoder: plainEncoder,
+		plainBuf:     bytes.NewBuffer([]byte{}),
+	}
+}
@@ -0,0 +1,162 @@
+package encoder
+
+import (
+	"bytes"
+	"math"
+	"tsfile/common/conf"
+	"tsfile/common/constant"
+	"tsfile/common/log"
+	"tsfile/common/utils"
+)
+
+// chimp-page: <first value> <xor> ... <end>
+// 		first value := the raw 32 or 64 bits of the first value
+// 		xor := the value xor-ed with the previous one, its leading zero num is
+// 			rounded down to one of CHIMP_LEADING_ROUND and stored as a 3 bits code
+// 			'00' if equal to the previous value
+// 			'01' <leading code> <meaningful bit len> <meaningful bits> if the xor has more
+// 				trailing zeros than the threshold, the length takes 5 bits for FLOAT and 6 for DOUBLE
+// 			'10' <bits after the leading zeros> if the leading zero num is the one of the previous xor
+// 			'11' <leading code> <bits after the leading zeros> otherwise
+// 		nan := a value of the bits of the end mark is followed by '1', so that it
+// 			is told apart from the end
+// 		end := NaN encoded as a value followed by '0', the last byte is padded with 0
+
+var CHIMP_LEADING_ROUND = []int32{0, 8, 12, 16, 18, 20, 22, 24}
+
+const (
+	CHIMP_FLOAT_TRAILING_THRESHOLD  int32 = 5
+	CHIMP_DOUBLE_TRAILING_THRESHOLD int32 = 6
+	CHIMP_FLOAT_VALUE_LENGTH        int   = 5
+	CHIMP_DOUBLE_VALUE_LENGTH       int   = 6
+
+	FLOAT_NAN_BITS  int64 = 0x7fc00000
+	DOUBLE_NAN_BITS int64 = 0x7ff8000000000000
+)
+
+type ChimpEncoder struct {
+	dataType constant.TSDataType
+
+	base        *GorillaEncoder
+	valueLength int
+	lengthLen   int
+	threshold   int32
+	preValue    int64
+	// leading zero num of the previous xor, valueLength+1 if it can not be reused
+	storedLeadingZeroNum int32
+}
+
+func (d *ChimpEncoder) Encode(v interface{}, buffer *bytes.Buffer) {
+	switch data := v.(type) {
+	case float32:
+		d.encodeValue(int64(math.Float32bits(data)), buffer)
+	case float64:
+		d.encodeValue(int64(math.Float64bits(data)), buffer)
+	default:
+		log.Error("invalid input of chimp encoder: %v", v)
+	}
+}
+
+// encodeValue writes the bits of a value, a value with the bits of the end
+// mark is flagged as a value
+func (d *ChimpEncoder) encodeValue(value int64, buffer *bytes.Buffer) {
+	d.encodeBits(value, buffer)
+	if value == d.nanBits() {
+		d.base.writeBit(true, buffer)
+	}
+}
+
+func (d *ChimpEncoder) encodeBits(value int64, buffer *bytes.Buffer) {
+	base := d.base
+	if !base.flag {
+		// case: write the first value without any encoding
+		base.flag = true
+		base.writeBits(value, d.valueLength, buffer)
+		d.preValue = value
+		d.storedLeadingZeroNum = int32(d.valueLength + 1)
+		return
+	}
+
+	xor := value ^ d.preValue
+	d.preValue = value
+	if xor == 0 {
+		// case: write '00'
+		base.writeBits(0x0, 2, buffer)
+		return
+	}
+	leadingZeroNum, tailingZeroNum := d.zeroNums(xor)
+	leadingCode := chimpLeadingCode(leadingZeroNum)
+	leadingZeroNum = CHIMP_LEADING_ROUND[leadingCode]
+	if tailingZeroNum > d.threshold {
+		// case: write '01', leading code, meaningful bit len and meaningful bits
+		length := d.valueLength - int(leadingZeroNum+tailingZeroNum)
+		base.writeBits(0x1, 2, buffer)
+		base.writeBits(int64(leadingCode), 3, buffer)
+		base.writeBits(int64(length), d.lengthLen, buffer)
+		base.writeBits(xor>>uint(tailingZeroNum), length, buffer)
+		d.storedLeadingZeroNum = int32(d.valueLength + 1)
+	} else if leadingZeroNum == d.storedLeadingZeroNum {
+		// case: write '10' and the bits after the leading zeros
+		base.writeBits(0x2, 2, buffer)
+		base.writeBits(xor, d.valueLength-int(leadingZeroNum), buffer)
+	} else {
+		// case: write '11', leading code and the bits after the leading zeros
+		base.writeBits(0x3, 2, buffer)
+		base.writeBits(int64(leadingCode), 3, buffer)
+		base.writeBits(xor, d.valueLength-int(leadingZeroNum), buffer)
+		d.storedLeadingZeroNum = leadingZeroNum
+	}
+}
+
+func (d *ChimpEncoder) zeroNums(xor int64) (int32, int32) {
+	if d.dataType == constant.FLOAT {
+		return utils.NumberOfLeadingZeros(int32(xor)), utils.NumberOfTrailingZeros(int32(xor))
+	}
+	return utils.NumberOfLeadingZerosLong(xor), utils.NumberOfTrailingZerosLong(xor)
+}
+
+func chimpLeadingCode(leadingZeroNum int32) int {
+	code := len(CHIMP_LEADING_ROUND) - 1
+	for code > 0 && CHIMP_LEADING_ROUND[code] > leadingZeroNum {
+		code--
+	}
+	return code
+}
+
+func (d *ChimpEncoder) nanBits() int64 {
+	if d.dataType == constant.FLOAT {
+		return FLOAT_NAN_BITS
+	}
+	return DOUBLE_NAN_BITS
+}
+
+func (d *ChimpEncoder) Flush(buffer *bytes.Buffer) {
+	d.encodeBits(d.nanBits(), buffer)
+	d.base.writeBit(false, buffer)
+	d.base.CleanBuffer(buffer)
+	d.base.Reset()
+}
+
+func (d *ChimpEncoder) GetMaxByteSize() int64 {
+	// first value and the end mark
+	return int64(d.valueLength/8 + d.GetOneItemMaxSize())
+}
+
+func (d *ChimpEncoder) GetOneItemMaxSize() int {
+	// case '11', 2bit + 3bit + value + the flag of a NaN
+	return (2+3+d.valueLength)/8 + 1
+}
+
+func NewChimpEncoder(dataType constant.TSDataType) *ChimpEncoder {
+	d := &ChimpEncoder{dataType: dataType, base: &GorillaEncoder{}}
+	if dataType == constant.FLOAT {
+		d.valueLength = conf.FLOAT_LENGTH
+		d.lengthLen = CHIMP_FLOAT_VALUE_LENGTH
+		d.threshold = CHIMP_FLOAT_TRAILING_THRESHOLD
+	} else {
+		d.valueLength = conf.DOUBLE_LENGTH
+		d.lengthLen = CHIMP_DOUBLE_VALUE_LENGTH
+		d.threshold = CHIMP_DOUBLE_TRAILING_THRESHOLD
+	}
+	return d
+}
@@ -0,0 +1,162 @@
+package encoder
+
+import (
+	"bytes"
+	"tsfile/common/conf"
+	"tsfile/common/constant"
+	"tsfile/common/log"
+	"tsfile/common/utils"
+	"tsfile/encoding/bitpacking"
+)
+
+// dictionary-page: <mode> <dictionary-data> | <plain-data>
+// 		mode := constant.DICTIONARY_MODE or constant.DICTIONARY_PLAIN_MODE, one byte
+// 		plain-data := the values as written by PlainEncoder, used once the dictionary
+// 			of the page grows past conf.DictionaryMaxSizeInByte
+// 		dictionary-data := <dictionary> <value-count> <bit-width> <run> ...
+// 			dictionary := varint(entry count) followed by varint(length) <bytes> for each entry
+// 			value-count := varint, number of values in the page
+// 			bit-width := one byte, bit width of the bit packed indices
+// 			run := <rle-run> | <bit-packed-run>
+// 				rle-run := varint(repeat count << 1) varint(index)
+// 				bit-packed-run := varint(index count << 1 | 1) followed by the indices packed
+// 					8 at a time by IntPacker, the last group is padded with 0
+
+type DictionaryEncoder struct {
+	plainEncoder *PlainEncoder
+	dictionary   map[string]int32
+	entries      []string
+	dictSize     int
+	indices      []int32
+	// values of the page once it fell back to plain
+	isPlain  bool
+	plainBuf *bytes.Buffer
+}
+
+func (d *DictionaryEncoder) Encode(value interface{}, buffer *bytes.Buffer) {
+	data, ok := value.(string)
+	if !ok {
+		log.Error("invalid input of dictionary encoder: %v", value)
+		return
+	}
+	if d.isPlain {
+		d.plainEncoder.Encode(data, d.plainBuf)
+		return
+	}
+	index, ok := d.dictionary[data]
+	if !ok {
+		index = int32(len(d.entries))
+		d.dictionary[data] = index
+		d.entries = append(d.entries, data)
+		d.dictSize += constant.INT_LEN + len(data)
+	}
+	d.indices = append(d.indices, index)
+	if d.dictSize > conf.DictionaryMaxSizeInByte {
+		d.fallbackToPlain()
+	}
+}
+
+// fallbackToPlain rewrites the values of the page plain, the dictionary does
+// not pay off any more.
+func (d *DictionaryEncoder) fallbackToPlain() {
+	for _, index := range d.indices {
+		d.plainEncoder.Encode(d.entries[index], d.plainBuf)
+	}
+	d.isPlain = true
+	d.dictionary = make(map[string]int32)
+	d.entries = d.entries[0:0]
+	d.indices = d.indices[0:0]
+	d.dictSize = 0
+}
+
+func (d *DictionaryEncoder) Flush(buffer *bytes.Buffer) {
+	if d.isPlain {
+		buffer.WriteByte(constant.DICTIONARY_PLAIN_MODE)
+		buffer.Write(d.plainBuf.Bytes())
+	} else if len(d.indices) > 0 {
+		buffer.WriteByte(constant.DICTIONARY_MODE)
+		utils.WriteUnsignedVarInt(int32(len(d.entries)), buffer)
+		for _, entry := range d.entries {
+			utils.WriteUnsignedVarInt(int32(len(entry)), buffer)
+			buffer.Write([]byte(entry))
+		}
+		utils.WriteUnsignedVarInt(int32(len(d.indices)), buffer)
+		bitWidth := int(32 - utils.NumberOfLeadingZeros(int32(len(d.entries)-1)))
+		if bitWidth == 0 {
+			bitWidth = 1
+		}
+		buffer.WriteByte(byte(bitWidth))
+		d.writeIndices(bitWidth, buffer)
+	}
+	d.reset()
+}
+
+func (d *DictionaryEncoder) writeIndices(bitWidth int, buffer *bytes.Buffer) {
+	packed := make([]int32, 0)
+	for i := 0; i < len(d.indices); {
+		j := i + 1
+		for j < len(d.indices) && d.indices[j] == d.indices[i] {
+			j++
+		}
+		if j-i >= conf.RLE_MIN_REPEATED_NUM {
+			writeBitPackedRun(packed, bitWidth, buffer)
+			packed = packed[0:0]
+			utils.WriteUnsignedVarInt(int32((j-i)<<1), buffer)
+			utils.WriteUnsignedVarInt(d.indices[i], buffer)
+		} else {
+			packed = append(packed, d.indices[i:j]...)
+		}
+		i = j
+	}
+	writeBitPackedRun(packed, bitWidth, buffer)
+}
+
+func writeBitPackedRun(values []int32, bitWidth int, buffer *bytes.Buffer) {
+	if len(values) == 0 {
+		return
+	}
+	utils.WriteUnsignedVarInt(int32(len(values)<<1|1), buffer)
+	packer := &bitpacking.IntPacker{BitWidth: bitWidth}
+	group := make([]int32, bitpacking.NUM_OF_INTS)
+	packedBytes := make([]byte, bitWidth)
+	for i := 0; i < len(values); i += bitpacking.NUM_OF_INTS {
+		for j := range group {
+			group[j] = 0
+		}
+		copy(group, values[i:])
+		packer.Pack8Values(group, 0, packedBytes)
+		buffer.Write(packedBytes)
+	}
+}
+
+func (d *DictionaryEncoder) reset() {
+	d.isPlain = false
+	d.plainBuf.Reset()
+	d.dictionary = make(map[string]int32)
+	d.entries = d.entries[0:0]
+	d.indices = d.indices[0:0]
+	d.dictSize = 0
+}
+
+func (d *DictionaryEncoder) GetMaxByteSize() int64 {
+	if d.isPlain {
+		return int64(1 + d.plainBuf.Len())
+	}
+	// mode, counts and bit width, at most 4 bytes per index
+	return int64(1 + 3*5 + 1 + d.dictSize + constant.INT_LEN*len(d.indices))
+}
+
+func (d *DictionaryEncoder) GetOneItemMaxSize() int {
+	return d.plainEncoder.GetOneItemMaxSize()
+}
+
+func NewDictionaryEncoder() *DictionaryEncoder {
+	plainEncoder, _ := NewPlainEncoder(constant.TEXT)
+	return &DictionaryEncoder{
+		plainEncoder: plainEncoder,
+		dictionary:   make(map[string]int32),
+		entries:      make([]string, 0),
+		indices:      make([]int32, 0),
+		plainBuf:     bytes.NewBuffer([]byte{}),
+	}
+}
@@ -0,0 +1,115 @@
+package encoder
+
+import (
+	"bytes"
+	"math"
+	"tsfile/common/constant"
+	"tsfile/common/log"
+	"tsfile/common/utils"
+)
+
+// elf-page: (<erasure> <chimp xor>) ... <end>
+// 		Values that come from decimals with few digits after the point have their
+// 		trailing mantissa bits erased before they are xor-ed by the chimp encoder,
+// 		the decoder restores them by rounding the magnitude up to those digits.
+// 		erasure := '0' if the value is written as is
+// 			'10' if erased with the decimal places of the previous erased value
+// 			'11' <decimal places> otherwise, 4 bits
+// 		chimp xor := the possibly erased value, as written by ChimpEncoder, NaN
+// 			is never erased and flagged as a value like there
+// 		end := '0' followed by the end of ChimpEncoder
+
+const (
+	ELF_MAX_DECIMAL_PLACES int = 15
+	// erasing fewer bits does not pay off the erasure flag
+	ELF_MIN_ERASED_BITS int = 4
+)
+
+type ElfEncoder struct {
+	dataType constant.TSDataType
+
+	chimp        *ChimpEncoder
+	mantissaLen  int
+	exponentBias int
+	// decimal places of the previous erased value, -1 if none
+	preDecimalPlaces int
+}
+
+func (d *ElfEncoder) Encode(v interface{}, buffer *bytes.Buffer) {
+	var value int64
+	var decimalPlaces int
+	switch data := v.(type) {
+	case float32:
+		value = int64(math.Float32bits(data))
+		decimalPlaces = utils.DecimalPlaces(float64(data), 32)
+	case float64:
+		value = int64(math.Float64bits(data))
+		decimalPlaces = utils.DecimalPlaces(data, 64)
+	default:
+		log.Error("invalid input of elf encoder: %v", v)
+		return
+	}
+
+	base := d.chimp.base
+	erased := d.erase(value, decimalPlaces)
+	if erased == value {
+		base.writeBit(false, buffer)
+	} else if decimalPlaces == d.preDecimalPlaces {
+		base.writeBits(0x2, 2, buffer)
+	} else {
+		base.writeBits(0x3, 2, buffer)
+		base.writeBits(int64(decimalPlaces), 4, buffer)
+		d.preDecimalPlaces = decimalPlaces
+	}
+	d.chimp.encodeValue(erased, buffer)
+}
+
+// erase clears the mantissa bits below 2^-ceil(decimalPlaces*log2(10)), it returns
+// the value itself if the restored value would differ or not enough bits are erased.
+func (d *ElfEncoder) erase(value int64, decimalPlaces int) int64 {
+	if decimalPlaces > ELF_MAX_DECIMAL_PLACES {
+		return value
+	}
+	exponent := int(value>>uint(d.mantissaLen)) & (2*d.exponentBias + 1)
+	if exponent == 0 || exponent == 2*d.exponentBias+1 {
+		// zero, subnormal, infinity or NaN
+		return value
+	}
+	kept := exponent - d.exponentBias + int(math.Ceil(float64(decimalPlaces)*math.Log2(10)))
+	erasedBits := d.mantissaLen - kept
+	if kept < 0 || erasedBits < ELF_MIN_ERASED_BITS {
+		return value
+	}
+	erased := value &^ (1<<uint(erasedBits) - 1)
+	if utils.CeilDecimalBits(erased, decimalPlaces, d.chimp.valueLength) != value {
+		return value
+	}
+	return erased
+}
+
+func (d *ElfEncoder) Flush(buffer *bytes.Buffer) {
+	d.chimp.base.writeBit(false, buffer)
+	d.chimp.Flush(buffer)
+	d.preDecimalPlaces = -1
+}
+
+func (d *ElfEncoder) GetMaxByteSize() int64 {
+	return d.chimp.GetMaxByteSize() + 1
+}
+
+func (d *ElfEncoder) GetOneItemMaxSize() int {
+	// erasure flag of 6bit at most
+	return d.chimp.GetOneItemMaxSize() + 1
+}
+
+func NewElfEncoder(dataType constant.TSDataType) *ElfEncoder {
+	d := &ElfEncoder{dataType: dataType, chimp: NewChimpEncoder(dataType), preDecimalPlaces: -1}
+	if dataType == constant.FLOAT {
+		d.mantissaLen = 23
+		d.exponentBias = 127
+	} else {
+		d.mantissaLen = 52
+		d.exponentBias = 1023
+	}
+	return d
+}
@@ -69,3 +69,10 @@ func (d *GorillaEncoder) CleanBuffer(buffer *bytes.Buffer) {
 	d.numberLeftInBuffer = 0
 	d.buffer = 0
 }
+
+// writeBits writes the lowest n bits of value, the most significant first
+func (d *GorillaEncoder) writeBits(value int64, n int, buffer *bytes.Buffer) {
+	for i := n - 1; i >= 0; i-- {
+		d.writeBit(value&(1<<uint(i)) != 0, buffer)
+	}
+}
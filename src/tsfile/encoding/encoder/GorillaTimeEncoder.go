@@ -0,0 +1,88 @@
+package encoder
+
+import (
+	"bytes"
+	"tsfile/common/constant"
+	"tsfile/common/log"
+)
+
+// gorilla-ts-page: <first timestamp> <delta of delta> ... <end>
+// 		first timestamp := 64 raw bits, the delta before it is 0
+// 		delta of delta := the delta to the previous timestamp minus the previous delta
+// 			'0' for 0
+// 			'10' followed by 7 bits of dod+63 for [-63, 64]
+// 			'110' followed by 9 bits of dod+255 for [-255, 256]
+// 			'1110' followed by 12 bits of dod+2047 for [-2047, 2048]
+// 			'11110' followed by 64 bits of dod otherwise
+// 		end := '11111', the last byte is padded with 0
+
+const (
+	GORILLA_TS_END_MARK     int64 = 0x1F
+	GORILLA_TS_END_MARK_LEN int   = 5
+)
+
+type GorillaTimeEncoder struct {
+	base     *GorillaEncoder
+	preValue int64
+	preDelta int64
+}
+
+func (d *GorillaTimeEncoder) Encode(v interface{}, buffer *bytes.Buffer) {
+	value, ok := v.(int64)
+	if !ok {
+		log.Error("invalid input of gorilla time encoder: %v", v)
+		return
+	}
+
+	base := d.base
+	if !base.flag {
+		base.flag = true
+		base.writeBits(value, 64, buffer)
+		d.preValue = value
+		d.preDelta = 0
+		return
+	}
+
+	delta := value - d.preValue
+	dod := delta - d.preDelta
+	d.preValue = value
+	d.preDelta = delta
+	switch {
+	case dod == 0:
+		base.writeBit(false, buffer)
+	case dod >= -63 && dod <= 64:
+		base.writeBits(0x2, 2, buffer)
+		base.writeBits(dod+63, 7, buffer)
+	case dod >= -255 && dod <= 256:
+		base.writeBits(0x6, 3, buffer)
+		base.writeBits(dod+255, 9, buffer)
+	case dod >= -2047 && dod <= 2048:
+		base.writeBits(0xE, 4, buffer)
+		base.writeBits(dod+2047, 12, buffer)
+	default:
+		base.writeBits(0x1E, 5, buffer)
+		base.writeBits(dod, 64, buffer)
+	}
+}
+
+func (d *GorillaTimeEncoder) Flush(buffer *bytes.Buffer) {
+	if d.base.flag {
+		d.base.writeBits(GORILLA_TS_END_MARK, GORILLA_TS_END_MARK_LEN, buffer)
+	}
+	d.base.CleanBuffer(buffer)
+	d.base.Reset()
+}
+
+func (d *GorillaTimeEncoder) GetMaxByteSize() int64 {
+	// first timestamp and the end mark
+	return int64(constant.LONG_LEN + d.GetOneItemMaxSize())
+}
+
+func (d *GorillaTimeEncoder) GetOneItemMaxSize() int {
+	// case '11110', 5bit + 64bit
+	return 9
+}
+
+func NewGorillaTimeEncoder() *GorillaTimeEncoder {
+	return &GorillaTimeEncoder{base: &GorillaEncoder{}}
+}
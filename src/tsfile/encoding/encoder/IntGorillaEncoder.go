@@ -0,0 +1,118 @@
+package encoder
+
+import (
+	"bytes"
+	"tsfile/common/conf"
+	"tsfile/common/constant"
+	"tsfile/common/utils"
+)
+
+// gorilla-int-page: <first value> <xor> ... <end>
+// 		first value := the raw 32 or 64 bits of the first value
+// 		xor := the first value xor-ed with the previous one
+// 			'0' if equal to the previous value
+// 			'10' <meaningful bits> if the meaningful bits fit in the window of the previous xor
+// 			'11' <leading zero num> <meaningful bit len> <meaningful bits> otherwise, the
+// 				leading zero num takes 5 bits for INT32 and 6 bits for INT64, the length 6 and 7 bits
+// 		end := '11' followed by a leading zero num and a meaningful bit len of 0,
+// 			the last byte is padded with 0
+
+type IntGorillaEncoder struct {
+	dataType constant.TSDataType
+
+	base        *GorillaEncoder
+	valueLength int
+	leadingLen  int
+	lengthLen   int
+	preValue    int64
+}
+
+func (d *IntGorillaEncoder) Encode(v interface{}, buffer *bytes.Buffer) {
+	var value int64
+	switch data := v.(type) {
+	case int32:
+		value = int64(uint32(data))
+	case int64:
+		value = data
+	default:
+		panic("invalid input of gorilla int encoder")
+	}
+
+	base := d.base
+	if !base.flag {
+		// case: write the first value without any encoding
+		base.flag = true
+		base.writeBits(value, d.valueLength, buffer)
+		d.preValue = value
+		// no window yet, the next xor starts one
+		base.leadingZeroNum = int32(d.valueLength)
+		base.tailingZeroNum = 0
+		return
+	}
+
+	tmp := value ^ d.preValue
+	d.preValue = value
+	if tmp == 0 {
+		// case: write '0'
+		base.writeBit(false, buffer)
+		return
+	}
+	leadingZeroNum, tailingZeroNum := d.zeroNums(tmp)
+	if leadingZeroNum >= base.leadingZeroNum && tailingZeroNum >= base.tailingZeroNum {
+		// case: write '10' and the bits inside the window of the previous xor
+		base.writeBit(true, buffer)
+		base.writeBit(false, buffer)
+		base.writeBits(tmp>>uint(base.tailingZeroNum), d.valueLength-int(base.leadingZeroNum+base.tailingZeroNum), buffer)
+		return
+	}
+	// case: write '11', leading zero num, meaningful bit len and meaningful bits
+	base.writeBit(true, buffer)
+	base.writeBit(true, buffer)
+	length := d.valueLength - int(leadingZeroNum+tailingZeroNum)
+	base.writeBits(int64(leadingZeroNum), d.leadingLen, buffer)
+	base.writeBits(int64(length), d.lengthLen, buffer)
+	base.writeBits(tmp>>uint(tailingZeroNum), length, buffer)
+	base.leadingZeroNum = leadingZeroNum
+	base.tailingZeroNum = tailingZeroNum
+}
+
+func (d *IntGorillaEncoder) zeroNums(xor int64) (int32, int32) {
+	if d.dataType == constant.INT32 {
+		return utils.NumberOfLeadingZeros(int32(xor)), utils.NumberOfTrailingZeros(int32(xor))
+	}
+	return utils.NumberOfLeadingZerosLong(xor), utils.NumberOfTrailingZerosLong(xor)
+}
+
+func (d *IntGorillaEncoder) Flush(buffer *bytes.Buffer) {
+	if d.base.flag {
+		d.base.writeBit(true, buffer)
+		d.base.writeBit(true, buffer)
+		d.base.writeBits(0, d.leadingLen+d.lengthLen, buffer)
+	}
+	d.base.CleanBuffer(buffer)
+	d.base.Reset()
+}
+
+func (d *IntGorillaEncoder) GetMaxByteSize() int64 {
+	// first value and the end mark
+	return int64(d.valueLength/8 + d.GetOneItemMaxSize())
+}
+
+func (d *IntGorillaEncoder) GetOneItemMaxSize() int {
+	// case '11', 2bit + leading zero num + length + value
+	return (2+d.leadingLen+d.lengthLen+d.valueLength)/8 + 1
+}
+
+func NewIntGorillaEncoder(dataType constant.TSDataType) *IntGorillaEncoder {
+	d := &IntGorillaEncoder{dataType: dataType, base: &GorillaEncoder{}}
+	if dataType == constant.INT32 {
+		d.valueLength = conf.FLOAT_LENGTH
+		d.leadingLen = conf.FLAOT_LEADING_ZERO_LENGTH
+		d.lengthLen = conf.FLOAT_VALUE_LENGTH
+	} else {
+		d.valueLength = conf.DOUBLE_LENGTH
+		d.leadingLen = conf.DOUBLE_LEADING_ZERO_LENGTH
+		d.lengthLen = conf.DOUBLE_VALUE_LENGTH
+	}
+	return d
+}
@@ -6,56 +6,79 @@ import (
 	"tsfile/common/utils"
 )
 
+// bitmap-package: <length> <number> <bitmap> ...
+//
+//	length := varint, length of all bitmaps in bytes
+//	number := varint, number of encoded values
+//	bitmap := varint(value) followed by (number+7)/8 bytes, bit i is set if value i equals value
+//
+// BOOLEAN values are encoded as 0 and 1.
 type BitmapEncoder struct {
 	tsDataType   constant.TSDataType
 	endianType   int8
 	encodeEndian int8
 	values       []int32
+	// distinct values in the order they first occur
+	distinct    []int32
+	distinctSet map[int32]bool
 }
 
 func (this *BitmapEncoder) Encode(value interface{}, buffer *bytes.Buffer) {
 	switch this.tsDataType {
+	case (constant.BOOLEAN):
+		if data, ok := value.(bool); ok {
+			if data {
+				this.add(1)
+			} else {
+				this.add(0)
+			}
+		}
 	case (constant.INT32):
 		if data, ok := value.(int32); ok {
-			this.values = append(this.values, data)
+			this.add(data)
 		}
-		break
 	default:
 		break
 	}
+}
 
+func (this *BitmapEncoder) add(value int32) {
+	if !this.distinctSet[value] {
+		this.distinctSet[value] = true
+		this.distinct = append(this.distinct, value)
+	}
+	this.values = append(this.values, value)
 }
 
 func (this *BitmapEncoder) Flush(buffer *bytes.Buffer) {
-	var byteCache *bytes.Buffer
-	valueType := this.values //make([]int32,this.values...)//new HashSet<Integer>(values)
+	byteCache := bytes.NewBuffer([]byte{})
 	len := len(this.values)
 	byteNum := (len + 7) / 8
 	if byteNum == 0 {
 		this.reset()
 		return
 	}
-	for _, value := range valueType {
-		buffer := make([]byte, byteNum)
+	for _, value := range this.distinct {
+		bitmap := make([]byte, byteNum)
 		for i := 0; i < len; i++ {
 			if this.values[i] == value {
 				index := i / 8
 				offset := 7 - (i % 8)
-				buffer[index] = (buffer[index] | (byte(1) << uint(offset)))
+				bitmap[index] = (bitmap[index] | (byte(1) << uint(offset)))
 			}
 		}
 		utils.WriteUnsignedVarInt(value, byteCache)
-		byteCache.Write(buffer)
+		byteCache.Write(bitmap)
 	}
 	utils.WriteUnsignedVarInt(int32(byteCache.Len()), buffer)
 	utils.WriteUnsignedVarInt(int32(len), buffer)
 	buffer.Write(byteCache.Bytes())
 	this.reset()
-
 }
 
 func (this *BitmapEncoder) GetMaxByteSize() int64 {
-	return int64((4 + 4 + (len(this.values)+7)/8 + 4) * len(this.values))
+	// two varint headers, a varint and a bitmap per distinct value
+	return int64(5 + 5 + (5+(len(this.values)+7)/8)*len(this.distinct))
 }
 
 func (this *BitmapEncoder) GetOneItemMaxSize() int {
@@ -64,6 +87,8 @@ func (this *BitmapEncoder) GetOneItemMaxSize() int {
 
 func (this *BitmapEncoder) reset() {
 	this.values = this.values[0:0]
+	this.distinct = this.distinct[0:0]
+	this.distinctSet = make(map[int32]bool)
 }
 
 func NewBitmapEncoder(tdt constant.TSDataType, endianType int8) (*BitmapEncoder, error) {
@@ -71,5 +96,8 @@ func NewBitmapEncoder(tdt constant.TSDataType, endianType int8) (*BitmapEncoder,
 		tsDataType:   tdt,
 		endianType:   endianType,
 		encodeEndian: 1,
+		values:       make([]int32, 0),
+		distinct:     make([]int32, 0),
+		distinctSet:  make(map[int32]bool),
 	}, nil
 }
@@ -2,6 +2,7 @@ package encoder
 
 import (
 	"bytes"
+	"errors"
 	"strconv"
 	"tsfile/common/conf"
 	"tsfile/common/constant"
@@ -22,16 +23,28 @@ type Encoder interface {
 	GetMaxByteSize() int64
 }
 
-func GetEncoder(et int16, tdt int16) Encoder {
+// GetEncoder fails for the encodings and data types without an encoder, e.g.
+// a custom encoding that is not registered.
+func GetEncoder(et int16, tdt int16) (Encoder, error) {
 	encoding := constant.TSEncoding(et)
 	dataType := constant.TSDataType(tdt)
 
+	if encoder, ok := registeredEncoder(encoding, dataType); ok {
+		return encoder, nil
+	}
+
 	var encoder Encoder
 	switch {
 	case encoding == constant.PLAIN:
 		encoder, _ = NewPlainEncoder(dataType)
+	case encoding == constant.PLAIN_DICTIONARY:
+		if dataType == constant.TEXT {
+			encoder = NewDictionaryEncoder()
+		}
 	case encoding == constant.RLE:
-		if dataType == constant.INT32 {
+		if dataType == constant.BOOLEAN {
+			encoder = NewRleEncoder(constant.BOOLEAN)
+		} else if dataType == constant.INT32 {
 			encoder = NewRleEncoder(constant.INT32)
 		} else if dataType == constant.INT64 {
 			encoder = NewRleEncoder(constant.INT64)
@@ -50,16 +63,37 @@ func GetEncoder(et int16, tdt int16) Encoder {
 			encoder = NewFloatDeltaEncoder(encoding, conf.FloatPrecision, dataType)
 			//encoder = NewFloatEncoder(encoding, conf.FloatPrecision, dataType)
 		}
+	case encoding == constant.BITMAP:
+		if dataType == constant.BOOLEAN || dataType == constant.INT32 {
+			encoder, _ = NewBitmapEncoder(dataType, 0)
+		}
 	case encoding == constant.GORILLA:
 		if dataType == constant.FLOAT {
 			encoder = NewSinglePrecisionEncoder(dataType)
 		} else if dataType == constant.DOUBLE {
 			encoder = NewDoublePrecisionEncoder(dataType)
+		} else if dataType == constant.INT32 || dataType == constant.INT64 {
+			encoder = NewIntGorillaEncoder(dataType)
+		}
+	case encoding == constant.CHIMP:
+		if dataType == constant.FLOAT || dataType == constant.DOUBLE {
+			encoder = NewChimpEncoder(dataType)
+		}
+	case encoding == constant.ELF:
+		if dataType == constant.FLOAT || dataType == constant.DOUBLE {
+			encoder = NewElfEncoder(dataType)
+		}
+	case encoding == constant.AUTO:
+		encoder = NewAutoEncoder(dataType)
+	case encoding == constant.GORILLA_TS:
+		if dataType == constant.INT64 {
+			encoder = NewGorillaTimeEncoder()
 		}
-
-	default:
-		panic("Encoder not found, encoding:" + strconv.Itoa(int(encoding)) + ", dataType:" + strconv.Itoa(int(dataType)))
 	}
 
-	return encoder
+	if encoder == nil {
+		return nil, errors.New("encoder: unsupported encoding " + strconv.Itoa(int(encoding)) +
+			" of data type " + strconv.Itoa(int(dataType)))
+	}
+	return encoder, nil
 }
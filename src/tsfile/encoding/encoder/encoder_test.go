@@ -0,0 +1,222 @@
+package encoder
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+	"tsfile/common/constant"
+	"tsfile/encoding/decoder"
+)
+
+// sensor like data, a random walk with two digits after the decimal point
+func generateDoubles(n int) []float64 {
+	r := rand.New(rand.NewSource(1))
+	values := make([]float64, n)
+	v := 20.0
+	for i := range values {
+		v += float64(r.Intn(21)-10) / 100
+		values[i] = math.Round(v*100) / 100
+	}
+	return values
+}
+
+func encodePage(encoding constant.TSEncoding, dataType constant.TSDataType, values []interface{}) []byte {
+	buffer := bytes.NewBuffer([]byte{})
+	e, _ := GetEncoder(int16(encoding), int16(dataType))
+	for _, v := range values {
+		e.Encode(v, buffer)
+	}
+	e.Flush(buffer)
+	return buffer.Bytes()
+}
+
+func TestFloatEncodings(t *testing.T) {
+	doubles := []interface{}{}
+	for _, v := range generateDoubles(500) {
+		doubles = append(doubles, v, -v)
+	}
+	doubles = append(doubles, 0.0, math.Copysign(0, -1), 1e300, -1e-300, 5e-324, 0.1, 0.1, 123456789.123,
+		math.Inf(1), math.Inf(-1), 1.0/3, math.MaxFloat64, 12.5)
+	floats := []interface{}{}
+	for _, v := range doubles {
+		floats = append(floats, float32(v.(float64)))
+	}
+
+	for _, encoding := range []constant.TSEncoding{constant.CHIMP, constant.ELF} {
+		for _, values := range [][]interface{}{doubles, floats, doubles[:1], {}} {
+			dataType := constant.DOUBLE
+			if len(values) > 0 {
+				if _, ok := values[0].(float32); ok {
+					dataType = constant.FLOAT
+				}
+			}
+			d, _ := decoder.CreateDecoder(encoding, dataType)
+			// the encoder and the decoder are reused across pages
+			e, _ := GetEncoder(int16(encoding), int16(dataType))
+			for page := 0; page < 2; page++ {
+				buffer := bytes.NewBuffer([]byte{})
+				for _, v := range values {
+					e.Encode(v, buffer)
+				}
+				e.Flush(buffer)
+
+				d.Init(buffer.Bytes())
+				for i, v := range values {
+					if !d.HasNext() {
+						t.Fatalf("encoding %d: expected %d values got %d", encoding, len(values), i)
+					}
+					if got := d.Next(); got != v && !(v == 0.0 && got == 0.0) {
+						t.Fatalf("encoding %d: value %d expected %v got %v", encoding, i, v, got)
+					}
+				}
+				if d.HasNext() {
+					t.Fatalf("encoding %d: more than %d values", encoding, len(values))
+				}
+			}
+		}
+	}
+}
+
+func TestFloatEncodingsSpecialValues(t *testing.T) {
+	// the bits of the end mark, other NaNs, the infinities and the zeros, alone,
+	// repeated and between numbers
+	doubleBits := []uint64{0x7ff8000000000000, 0x7ff8000000000000, 0x3ff0000000000000, 0x7ff8000000000000,
+		0xfff8000000000000, 0x7ff0000000000001, math.Float64bits(math.Inf(1)), math.Float64bits(math.Inf(-1)),
+		math.Float64bits(math.Copysign(0, -1)), 0, 0x4029000000000000, 0x7ff8000000000000}
+	floatBits := []uint32{0x7fc00000, 0x7fc00000, 0x3f800000, 0x7fc00000, 0xffc00000, 0x7f800001,
+		math.Float32bits(float32(math.Inf(1))), math.Float32bits(float32(math.Inf(-1))), 0x80000000, 0,
+		0x41480000, 0x7fc00000}
+	var doubles, floats []interface{}
+	for i := range doubleBits {
+		doubles = append(doubles, math.Float64frombits(doubleBits[i]))
+		floats = append(floats, math.Float32frombits(floatBits[i]))
+	}
+	bits := func(v interface{}) uint64 {
+		if f, ok := v.(float32); ok {
+			return uint64(math.Float32bits(f))
+		}
+		return math.Float64bits(v.(float64))
+	}
+
+	for _, encoding := range []constant.TSEncoding{constant.CHIMP, constant.ELF} {
+		for _, values := range [][]interface{}{doubles, floats, doubles[:1], floats[:1], doubles[len(doubles)-2:]} {
+			dataType := constant.DOUBLE
+			if _, ok := values[0].(float32); ok {
+				dataType = constant.FLOAT
+			}
+			d, _ := decoder.CreateDecoder(encoding, dataType)
+			d.Init(encodePage(encoding, dataType, values))
+			for i, v := range values {
+				if !d.HasNext() {
+					t.Fatalf("encoding %d: expected %d values got %d", encoding, len(values), i)
+				}
+				if got := d.Next(); bits(got) != bits(v) {
+					t.Fatalf("encoding %d: value %d expected bits %x got %x", encoding, i, bits(v), bits(got))
+				}
+			}
+			if d.HasNext() {
+				t.Fatalf("encoding %d: more than %d values", encoding, len(values))
+			}
+		}
+	}
+}
+
+// BenchmarkDoubleEncodings reports the encoded size of the generated data,
+// note TS_2DIFF keeps conf.FloatPrecision digits only.
+func BenchmarkDoubleEncodings(b *testing.B) {
+	values := []interface{}{}
+	for _, v := range generateDoubles(10000) {
+		values = append(values, v)
+	}
+	encodings := map[string]constant.TSEncoding{"GORILLA": constant.GORILLA, "TS_2DIFF": constant.TS_2DIFF,
+		"CHIMP": constant.CHIMP, "ELF": constant.ELF}
+	for _, name := range []string{"GORILLA", "TS_2DIFF", "CHIMP", "ELF"} {
+		encoding := encodings[name]
+		b.Run(name, func(b *testing.B) {
+			var size int
+			for i := 0; i < b.N; i++ {
+				size = len(encodePage(encoding, constant.DOUBLE, values))
+			}
+			b.ReportMetric(float64(size)/float64(len(values)), "bytes/value")
+		})
+	}
+}
+
+func TestAutoEncoding(t *testing.T) {
+	constants := []interface{}{}
+	steps := []interface{}{}
+	texts := []interface{}{}
+	withNaN := []interface{}{}
+	for i := 0; i < 3000; i++ {
+		constants = append(constants, int32(7))
+		steps = append(steps, int64(1000+i*10))
+		texts = append(texts, []string{"on", "off", "standby"}[i%3])
+		withNaN = append(withNaN, 1.5)
+	}
+	withNaN[1500] = math.NaN()
+	doubles := []interface{}{}
+	for _, v := range generateDoubles(3000) {
+		doubles = append(doubles, v)
+	}
+
+	cases := []struct {
+		dataType constant.TSDataType
+		values   []interface{}
+		// whether an encoding smaller than plain should be chosen
+		compressible bool
+	}{
+		{constant.INT32, constants, true},
+		{constant.INT64, steps, true},
+		{constant.TEXT, texts, true},
+		{constant.DOUBLE, doubles, true},
+		{constant.DOUBLE, withNaN, false},
+		{constant.BOOLEAN, []interface{}{true}, false},
+	}
+	for _, c := range cases {
+		page := encodePage(constant.AUTO, c.dataType, c.values)
+		plain := encodePage(constant.PLAIN, c.dataType, c.values)
+		if len(page) > 1+len(plain) {
+			t.Fatalf("data type %d: auto page of %d bytes is larger than plain of %d bytes", c.dataType, len(page), len(plain))
+		}
+		if c.compressible && constant.TSEncoding(page[0]) == constant.PLAIN {
+			t.Fatalf("data type %d: expected an encoding better than plain", c.dataType)
+		}
+
+		d, _ := decoder.CreateDecoder(constant.AUTO, c.dataType)
+		d.Init(page)
+		for i, v := range c.values {
+			if !d.HasNext() {
+				t.Fatalf("data type %d: expected %d values got %d", c.dataType, len(c.values), i)
+			}
+			if got := d.Next(); got != v && !(v != v && got != got) {
+				t.Fatalf("data type %d: value %d expected %v got %v", c.dataType, i, v, got)
+			}
+		}
+		if d.HasNext() {
+			t.Fatalf("data type %d: more than %d values", c.dataType, len(c.values))
+		}
+	}
+}
+
+func TestUnknownEncoding(t *testing.T) {
+	if _, err := GetEncoder(120, int16(constant.INT64)); err == nil {
+		t.Fatal("expected no encoder of encoding 120")
+	}
+	if _, err := GetEncoder(int16(constant.BITMAP), int16(constant.DOUBLE)); err == nil {
+		t.Fatal("expected no bitmap encoder of DOUBLE")
+	}
+	if _, err := decoder.CreateDecoder(120, constant.INT64); err == nil {
+		t.Fatal("expected no decoder of encoding 120")
+	}
+	// an auto page whose encoding byte has no decoder
+	d, _ := decoder.CreateDecoder(constant.AUTO, constant.INT64)
+	d.Init([]byte{120, 1, 2, 3})
+	if decoder.Err(d) == nil || d.HasNext() {
+		t.Fatal("expected the page to be refused")
+	}
+	d.Init(encodePage(constant.AUTO, constant.INT64, []interface{}{int64(1)}))
+	if decoder.Err(d) != nil || !d.HasNext() || d.Next() != int64(1) {
+		t.Fatal("expected the next page to be decoded")
+	}
+}
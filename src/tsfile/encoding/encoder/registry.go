@@ -0,0 +1,44 @@
+package encoder
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"tsfile/common/constant"
+)
+
+// EncoderFactory creates the encoder of a page of the given data type.
+type EncoderFactory func(dataType constant.TSDataType) Encoder
+
+var (
+	factories     = make(map[constant.TSEncoding]map[constant.TSDataType]EncoderFactory)
+	factoriesLock sync.RWMutex
+)
+
+// RegisterEncoder makes GetEncoder use the factory for the given encoding and data
+// types. The encoding must be above constant.MAX_BUILTIN_ENCODING, registering it
+// again for a data type replaces the factory.
+func RegisterEncoder(encoding constant.TSEncoding, factory EncoderFactory, dataTypes ...constant.TSDataType) error {
+	if encoding <= constant.MAX_BUILTIN_ENCODING {
+		return errors.New("encoder: can not register the built-in encoding " + strconv.Itoa(int(encoding)))
+	}
+	factoriesLock.Lock()
+	defer factoriesLock.Unlock()
+	if _, ok := factories[encoding]; !ok {
+		factories[encoding] = make(map[constant.TSDataType]EncoderFactory)
+	}
+	for _, dataType := range dataTypes {
+		factories[encoding][dataType] = factory
+	}
+	return nil
+}
+
+func registeredEncoder(encoding constant.TSEncoding, dataType constant.TSDataType) (Encoder, bool) {
+	factoriesLock.RLock()
+	factory, ok := factories[encoding][dataType]
+	factoriesLock.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return factory(dataType), true
+}
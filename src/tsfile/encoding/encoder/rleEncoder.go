@@ -112,8 +112,7 @@ func (this *RleEncoder) writeRleRun() {
 	this.endPreviousBitPackedRun(int32(conf.RLE_MIN_REPEATED_NUM))
 	utils.WriteUnsignedVarInt(int32(this.repeatCount<<1), this.byteCache)
 	switch this.tsDataType {
-	case (constant.BOOLEAN):
-	case (constant.INT32):
+	case (constant.BOOLEAN), (constant.INT32):
 		utils.WriteIntLittleEndianPaddedOnBitWidth((this.preValue_32), this.byteCache, this.bitWidth)
 		break
 	case (constant.INT64):
@@ -123,14 +122,13 @@ func (this *RleEncoder) writeRleRun() {
 		break
 	}
 	this.repeatCount = 0
-	this.numBufferedValues = 0
+	this.clearBufferedValues()
 }
 
 func (this *RleEncoder) convertBuffer() {
 	bytes := make([]byte, this.bitWidth)
 	switch this.tsDataType {
-	case (constant.BOOLEAN):
-	case (constant.INT32):
+	case (constant.BOOLEAN), (constant.INT32):
 		tmpBuffer := make([]int32, conf.RLE_MIN_REPEATED_NUM)
 		for i := 0; i < conf.RLE_MIN_REPEATED_NUM; i++ {
 			if i < len(this.bufferedValues_32) {
@@ -165,7 +163,7 @@ func (this *RleEncoder) writeOrAppendBitPackedRun() {
 		this.isBitPackRun = true
 	}
 	this.convertBuffer()
-	this.numBufferedValues = 0
+	this.clearBufferedValues()
 	this.repeatCount = 0
 	this.bitPackedGroupCount = this.bitPackedGroupCount + 1
 }
@@ -227,8 +225,7 @@ func (this *RleEncoder) encodeValue(v int64) {
 func (this *RleEncoder) clearBuffer() {
 	for i := this.numBufferedValues; i < conf.RLE_MIN_REPEATED_NUM; i++ {
 		switch this.tsDataType {
-		case (constant.BOOLEAN):
-		case (constant.INT32):
+		case (constant.BOOLEAN), (constant.INT32):
 			if i < len(this.bufferedValues_32) {
 				this.bufferedValues_32 = append(this.bufferedValues_32, 0)
 			}
@@ -244,8 +241,15 @@ func (this *RleEncoder) clearBuffer() {
 	}
 }
 
-func (this *RleEncoder) reset() {
+// clearBufferedValues drops the values packed or written as a run already
+func (this *RleEncoder) clearBufferedValues() {
 	this.numBufferedValues = 0
+	this.bufferedValues_32 = this.bufferedValues_32[0:0]
+	this.bufferedValues_64 = this.bufferedValues_64[0:0]
+}
+
+func (this *RleEncoder) reset() {
+	this.clearBufferedValues()
 	this.repeatCount = 0
 	this.bitPackedGroupCount = 0
 	this.bytesBuffer = this.bytesBuffer[0:0]
@@ -253,8 +257,7 @@ func (this *RleEncoder) reset() {
 	this.isBitWidthSaved = false
 	this.byteCache.Reset() // = this.byteCache[0:0]
 	switch this.tsDataType {
-	case (constant.BOOLEAN):
-	case (constant.INT32):
+	case (constant.BOOLEAN), (constant.INT32):
 		this.values_32 = this.values_32[0:0]
 		this.preValue_32 = 0 //this.preValue_32[0:0]
 		break
@@ -287,8 +290,7 @@ func (this *RleEncoder) flush(buffer *bytes.Buffer) {
 func (this *RleEncoder) Flush(buffer *bytes.Buffer) {
 
 	switch this.tsDataType {
-	case (constant.BOOLEAN):
-	case (constant.INT32):
+	case (constant.BOOLEAN), (constant.INT32):
 		this.bitWidth = int(getIntMaxBitWidth(this.values_32))
 		this.packer_32 = &bitpacking.IntPacker{BitWidth: int(this.bitWidth)}
 		for _, v := range this.values_32 {
@@ -310,8 +312,7 @@ func (this *RleEncoder) Flush(buffer *bytes.Buffer) {
 
 func (this *RleEncoder) GetMaxByteSize() int64 {
 	switch this.tsDataType {
-	case (constant.BOOLEAN):
-	case (constant.INT32):
+	case (constant.BOOLEAN), (constant.INT32):
 		len := len(this.values_32)
 		if len == 0 {
 			return 0
@@ -319,7 +320,7 @@ func (this *RleEncoder) GetMaxByteSize() int64 {
 		groupNum := (len/8+1)/63 + 1
 		return int64(8 + groupNum*5 + len*4)
 	case (constant.INT64):
-		len := len(this.values_32)
+		len := len(this.values_64)
 		if len == 0 {
 			return 0
 		}
@@ -333,8 +334,7 @@ func (this *RleEncoder) GetMaxByteSize() int64 {
 
 func (this *RleEncoder) GetOneItemMaxSize() int {
 	switch this.tsDataType {
-	case (constant.BOOLEAN):
-	case (constant.INT32):
+	case (constant.BOOLEAN), (constant.INT32):
 		return 45
 	case (constant.INT64):
 		return 77
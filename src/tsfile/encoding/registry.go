@@ -0,0 +1,23 @@
+package encoding
+
+import (
+	"tsfile/common/constant"
+	"tsfile/encoding/decoder"
+	"tsfile/encoding/encoder"
+)
+
+// Register adds an application encoding under the given name and id, the writer,
+// the readers and the query engine then encode and decode the pages of the given
+// data types with the factories. The id is recorded in the chunk headers, it must
+// be above constant.MAX_BUILTIN_ENCODING and stay the same for the files written.
+func Register(name string, id constant.TSEncoding, encoderFactory encoder.EncoderFactory,
+	decoderFactory decoder.DecoderFactory, dataTypes ...constant.TSDataType) error {
+	if err := encoder.RegisterEncoder(id, encoderFactory, dataTypes...); err != nil {
+		return err
+	}
+	if err := decoder.RegisterDecoder(id, decoderFactory, dataTypes...); err != nil {
+		return err
+	}
+	constant.RegisterEncodingName(name, id)
+	return nil
+}
@@ -15,6 +15,7 @@ type ChunkHeader struct {
 	dataType         constant.TSDataType
 	compressionType  constant.CompressionType
 	encodingType     constant.TSEncoding
+	timeEncodingType constant.TSEncoding
 	numberOfPages    int
 	maxTombstoneTime int64
 	serializedSize   int
@@ -26,7 +27,7 @@ func (h *ChunkHeader) Deserialize(reader *utils.FileReader) {
 	h.dataType = constant.TSDataType(reader.ReadShort())
 	h.numberOfPages = int(reader.ReadInt())
 	h.compressionType = constant.CompressionType(reader.ReadShort())
-	h.encodingType = constant.TSEncoding(reader.ReadShort())
+	h.encodingType, h.timeEncodingType = splitEncodings(reader.ReadShort())
 	h.maxTombstoneTime = reader.ReadLong()
 
 	h.serializedSize = (constant.INT_LEN + len(h.sensor) + constant.INT_LEN + constant.SHORT_LEN + constant.INT_LEN + constant.SHORT_LEN + constant.SHORT_LEN + constant.LONG_LEN)
@@ -52,6 +53,10 @@ func (h *ChunkHeader) GetEncodingType() constant.TSEncoding {
 	return h.encodingType
 }
 
+func (h *ChunkHeader) GetTimeEncodingType() constant.TSEncoding {
+	return h.timeEncodingType
+}
+
 func (h *ChunkHeader) GetNumberOfPages() int {
 	return h.numberOfPages
 }
@@ -72,7 +77,7 @@ func (c *ChunkHeader) ChunkHeaderToMemory(buffer *bytes.Buffer) int32 {
 	buffer.Write(utils.Int16ToByte(int16(c.dataType), 0))
 	buffer.Write(utils.Int32ToByte(int32(c.numberOfPages), 0))
 	buffer.Write(utils.Int16ToByte(int16(c.compressionType), 0))
-	buffer.Write(utils.Int16ToByte(int16(c.encodingType), 0))
+	buffer.Write(utils.Int16ToByte(joinEncodings(c.encodingType, c.timeEncodingType), 0))
 	buffer.Write(utils.Int64ToByte(c.maxTombstoneTime, 0))
 	return int32(c.serializedSize)
 }
@@ -81,6 +86,27 @@ func (c *ChunkHeader) SetMaxTombstoneTime(mtt int64) {
 	c.maxTombstoneTime = mtt
 }
 
+func (c *ChunkHeader) SetTimeEncodingType(et constant.TSEncoding) {
+	c.timeEncodingType = et
+}
+
+// The value encoding takes the low byte of the encoding short, the time encoding
+// plus one the high byte. A high byte of 0, as written by older versions, means TS_2DIFF.
+func joinEncodings(valueEncoding constant.TSEncoding, timeEncoding constant.TSEncoding) int16 {
+	if timeEncoding == constant.TS_2DIFF {
+		return int16(valueEncoding)
+	}
+	return int16(timeEncoding+1)<<8 | int16(valueEncoding)
+}
+
+func splitEncodings(encodings int16) (constant.TSEncoding, constant.TSEncoding) {
+	valueEncoding := constant.TSEncoding(encodings & 0xFF)
+	if encodings>>8 == 0 {
+		return valueEncoding, constant.TS_2DIFF
+	}
+	return valueEncoding, constant.TSEncoding(encodings>>8 - 1)
+}
+
 func GetChunkSerializedSize(sensorId string) int {
 	return 3*4 + 3*2 + len(sensorId) + 8
 }
@@ -93,6 +119,7 @@ func NewChunkHeader(sId string, pbs int, tdt int16, ct int16, et int16, nop int,
 		dataType:         constant.TSDataType(tdt),
 		compressionType:  constant.CompressionType(ct),
 		encodingType:     constant.TSEncoding(et),
+		timeEncodingType: constant.TS_2DIFF,
 		numberOfPages:    nop,
 		serializedSize:   ss,
 		maxTombstoneTime: mtt,
@@ -39,6 +39,7 @@ func (h *PageHeader) Deserialize(reader *utils.FileReader, dataType constant.TSD
 	h.max_timestamp = reader.ReadLong()
 	h.min_timestamp = reader.ReadLong()
 	h.statistics = statistics.Deserialize(reader, dataType)
+	h.statistics.SetRange(int64(h.numberOfValues), h.min_timestamp, h.max_timestamp)
 
 	h.serializedSize = int32(3*constant.INT_LEN + 2*constant.LONG_LEN + h.statistics.GetSerializedSize())
 }
@@ -0,0 +1,93 @@
+package metadata
+
+import (
+	"bytes"
+	"hash/fnv"
+	"math"
+	"tsfile/common/constant"
+	"tsfile/common/utils"
+)
+
+// bloom-filter-section: <hash num> <bit num> <bits> <length> <tag>
+// 		hash num := the number of bits set for a path, int32
+// 		bit num := the number of bits, int32, the bits take (bit num + 7) / 8 bytes
+// 		length := the length of the section before it, int32
+// 		tag := FOOTER_BLOOM_FILTER, the section is the last before FOOTER_CRC
+// 			so that it is found from the end of the footer
+// The bits of a path are h1 + i*h2 for i < hash num, h1 and h2 the two halves
+// of the 64 bits FNV-1a hash of the path.
+
+type BloomFilter struct {
+	hashNum int32
+	bitNum  int32
+	bits    []byte
+}
+
+// NewBloomFilter sizes a filter for num paths with the given false positive rate
+func NewBloomFilter(errorRate float64, num int) *BloomFilter {
+	num = int(math.Max(float64(num), 1))
+	bitNum := math.Ceil(-float64(num) * math.Log(errorRate) / (math.Ln2 * math.Ln2))
+	hashNum := math.Round(bitNum / float64(num) * math.Ln2)
+	b := &BloomFilter{hashNum: int32(math.Max(hashNum, 1)), bitNum: int32(math.Max(bitNum, 8))}
+	b.bits = make([]byte, (b.bitNum+7)/8)
+	return b
+}
+
+func (b *BloomFilter) Add(path string) {
+	h1, h2 := bloomHash(path)
+	for i := int32(0); i < b.hashNum; i++ {
+		bit := (h1 + uint32(i)*h2) % uint32(b.bitNum)
+		b.bits[bit/8] |= 1 << (bit % 8)
+	}
+}
+
+// MayContain is false only if the path was not added
+func (b *BloomFilter) MayContain(path string) bool {
+	h1, h2 := bloomHash(path)
+	for i := int32(0); i < b.hashNum; i++ {
+		bit := (h1 + uint32(i)*h2) % uint32(b.bitNum)
+		if b.bits[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func bloomHash(path string) (uint32, uint32) {
+	h := fnv.New64a()
+	h.Write([]byte(path))
+	sum := h.Sum64()
+	return uint32(sum), uint32(sum>>32) | 1
+}
+
+func (b *BloomFilter) serializeTo(buf *bytes.Buffer) {
+	start := buf.Len()
+	buf.Write(utils.Int32ToByte(b.hashNum, 0))
+	buf.Write(utils.Int32ToByte(b.bitNum, 0))
+	buf.Write(b.bits)
+	buf.Write(utils.Int32ToByte(int32(buf.Len()-start), 0))
+	buf.Write(utils.Int16ToByte(FOOTER_BLOOM_FILTER, 0))
+}
+
+// DeserializeBloomFilter reads the section before its length and tag, nil if it is invalid
+func DeserializeBloomFilter(data []byte) *BloomFilter {
+	if len(data) < 2*constant.INT_LEN {
+		return nil
+	}
+	reader := utils.NewBytesReader(data)
+	b := &BloomFilter{hashNum: reader.ReadInt(), bitNum: reader.ReadInt()}
+	if b.hashNum <= 0 || b.bitNum <= 0 || int(b.bitNum+7)/8 > reader.Len() {
+		return nil
+	}
+	b.bits = reader.ReadSlice(int(b.bitNum+7) / 8)
+	return b
+}
+
+func (f *FileMetaData) GetBloomFilter() *BloomFilter {
+	return f.bloomFilter
+}
+
+// SetBloomFilter adds the filter to the footer, nil writes none
+func (f *FileMetaData) SetBloomFilter(bloomFilter *BloomFilter) {
+	f.bloomFilter = bloomFilter
+}
@@ -0,0 +1,71 @@
+package metadata
+
+import (
+	"bytes"
+	"hash/crc32"
+	"sort"
+	"tsfile/common/constant"
+	"tsfile/common/utils"
+)
+
+// CRC32C is the table of the checksums of a tsfile
+var CRC32C = crc32.MakeTable(crc32.Castagnoli)
+
+// size of the FOOTER_CRC section ending the footer, the CRC32C and the version
+const FOOTER_CRC_SIZE = constant.SHORT_LEN + constant.INT_LEN + 2*constant.INT_LEN
+
+// Checksum is the CRC32C of a block of the file, a row group header, a chunk
+// header or a page with its header, kept under the offset of the block.
+type Checksum struct {
+	Length int32
+	Crc    uint32
+}
+
+func NewChecksum(data []byte) Checksum {
+	return Checksum{Length: int32(len(data)), Crc: crc32.Checksum(data, CRC32C)}
+}
+
+// VerifyFooter checks the CRC32C the serialized footer ends with, ok is false if
+// the footer was written without one.
+func VerifyFooter(footer []byte) (valid bool, ok bool) {
+	if len(footer) < FOOTER_CRC_SIZE {
+		return false, false
+	}
+	reader := utils.NewBytesReader(footer[len(footer)-FOOTER_CRC_SIZE:])
+	if reader.ReadShort() != FOOTER_CRC || int(reader.ReadInt()) != 2*constant.INT_LEN {
+		return false, false
+	}
+	crc := uint32(reader.ReadInt())
+	return crc32.Checksum(footer[:len(footer)-FOOTER_CRC_SIZE], CRC32C) == crc, true
+}
+
+func (f *FileMetaData) GetChecksums() map[int64]Checksum {
+	return f.checksums
+}
+
+func (f *FileMetaData) SetChecksums(checksums map[int64]Checksum) {
+	f.checksums = checksums
+}
+
+func (f *FileMetaData) deserializeChecksums(reader *utils.BytesReader) {
+	size := int(reader.ReadInt())
+	for i := 0; i < size; i++ {
+		offset := reader.ReadLong()
+		f.checksums[offset] = Checksum{Length: reader.ReadInt(), Crc: uint32(reader.ReadInt())}
+	}
+}
+
+func (f *FileMetaData) serializeChecksums(buf *bytes.Buffer) {
+	offsets := make([]int64, 0, len(f.checksums))
+	for offset := range f.checksums {
+		offsets = append(offsets, offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	buf.Write(utils.Int32ToByte(int32(len(offsets)), 0))
+	for _, offset := range offsets {
+		checksum := f.checksums[offset]
+		buf.Write(utils.Int64ToByte(offset, 0))
+		buf.Write(utils.Int32ToByte(checksum.Length, 0))
+		buf.Write(utils.Int32ToByte(int32(checksum.Crc), 0))
+	}
+}
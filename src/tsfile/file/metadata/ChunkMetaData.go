@@ -5,6 +5,7 @@ import (
 	_ "log"
 	"tsfile/common/constant"
 	"tsfile/common/utils"
+	"tsfile/file/metadata/statistics"
 )
 
 type ChunkMetaData struct {
@@ -56,6 +57,33 @@ func (t *ChunkMetaData) SetDigest(tsDigest *TsDigest) {
 	t.valuesStatistics = tsDigest
 }
 
+func (t *ChunkMetaData) GetDigest() *TsDigest {
+	return t.valuesStatistics
+}
+
+// GetStatistics returns the statistics of the values of the chunk, nil if its
+// digest has none.
+func (t *ChunkMetaData) GetStatistics(dataType constant.TSDataType) statistics.Statistics {
+	if t.valuesStatistics == nil {
+		return nil
+	}
+	keys := []string{MIN_VALUE, MAX_VALUE, FIRST, LAST, SUM}
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		value, ok := t.valuesStatistics.statistics[key]
+		if !ok {
+			return nil
+		}
+		values[i] = value.Bytes()
+	}
+	return statistics.FromDigest(dataType, values[0], values[1], values[2], values[3], values[4],
+		t.numOfPoints, t.startTime, t.endTime)
+}
+
+func (t *ChunkMetaData) GetNumOfPoints() int64 {
+	return t.numOfPoints
+}
+
 func (t *ChunkMetaData) GetStartTime() int64 {
 	return t.startTime
 }
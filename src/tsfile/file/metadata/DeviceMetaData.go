@@ -5,6 +5,7 @@ import (
 	_ "log"
 	"tsfile/common/constant"
 	"tsfile/common/utils"
+	"tsfile/file/metadata/statistics"
 )
 
 type DeviceMetaData struct {
@@ -56,6 +57,27 @@ func (t *DeviceMetaData) GetRowGroups() []*RowGroupMetaData {
 	return t.rowGroupMetadataSli
 }
 
+// GetStatistics merges the statistics of the chunks of the sensor in all row
+// groups of the device, nil if the sensor has no chunk with statistics.
+func (t *DeviceMetaData) GetStatistics(sensorId string, dataType constant.TSDataType) statistics.Statistics {
+	var merged statistics.Statistics
+	for _, rowGroup := range t.rowGroupMetadataSli {
+		for _, chunkMetaData := range rowGroup.GetChunkMetaDataSli() {
+			if chunkMetaData.Sensor() != sensorId {
+				continue
+			}
+			if s := chunkMetaData.GetStatistics(dataType); s == nil {
+				continue
+			} else if merged == nil {
+				merged = s
+			} else {
+				merged.Merge(s)
+			}
+		}
+	}
+	return merged
+}
+
 func (t *DeviceMetaData) GetStartTime() int64 {
 	return t.startTime
 }
@@ -3,10 +3,26 @@ package metadata
 import (
 	"bytes"
 	_ "log"
+	"strings"
 	"tsfile/common/constant"
 	"tsfile/common/utils"
 )
 
+// keys of the statistics of a chunk, see ChunkMetaData.GetStatistics
+const (
+	MAX_VALUE = "max_value"
+	MIN_VALUE = "min_value"
+	FIRST     = "first"
+	SUM       = "sum"
+	LAST      = "last"
+)
+
+// keys of the lossy filter a chunk was written with, see SetLossyFilter
+const (
+	LOSSY_FILTER = "lossy_filter"
+	LOSSY_PARAMS = "lossy_params"
+)
+
 type TsDigest struct {
 	//statistics     map[string][]byte
 	statistics     map[string]*bytes.Buffer
@@ -27,6 +43,7 @@ func (f *TsDigest) Deserialize(reader *utils.BytesReader) {
 			f.serializedSize += constant.INT_LEN + len(key) + constant.INT_LEN + len(value)
 		}
 	}
+	f.sizeOfList = len(f.statistics)
 }
 
 func (t *TsDigest) SetStatistics(statistics map[string]*bytes.Buffer) {
@@ -35,6 +52,41 @@ func (t *TsDigest) SetStatistics(statistics map[string]*bytes.Buffer) {
 	t.ReCalculateSerializedSize()
 }
 
+func (t *TsDigest) GetStatistics() map[string]*bytes.Buffer {
+	return t.statistics
+}
+
+// SetLossyFilter records that the values of the chunk were approximated by the
+// filter with the given parameters.
+func (t *TsDigest) SetLossyFilter(filterType constant.CompressionType, params map[string]string) {
+	pairs := make([]string, 0, len(params))
+	for _, k := range utils.SortedKeys(params) {
+		pairs = append(pairs, k+"="+params[k])
+	}
+	t.statistics[LOSSY_FILTER] = bytes.NewBuffer(utils.Int16ToByte(int16(filterType), 0))
+	t.statistics[LOSSY_PARAMS] = bytes.NewBufferString(strings.Join(pairs, ","))
+	t.ReCalculateSerializedSize()
+}
+
+// GetLossyFilter returns the filter the values of the chunk were approximated by,
+// ok is false if the values are exact.
+func (t *TsDigest) GetLossyFilter() (filterType constant.CompressionType, params map[string]string, ok bool) {
+	filter, ok := t.statistics[LOSSY_FILTER]
+	if !ok || filter.Len() != constant.SHORT_LEN {
+		return constant.UNCOMPRESSED, nil, false
+	}
+	filterType = constant.CompressionType(utils.NewBytesReader(filter.Bytes()).ReadShort())
+	params = make(map[string]string)
+	if p, ok := t.statistics[LOSSY_PARAMS]; ok && p.Len() > 0 {
+		for _, pair := range strings.Split(p.String(), ",") {
+			if kv := strings.SplitN(pair, "=", 2); len(kv) == 2 {
+				params[kv[0]] = kv[1]
+			}
+		}
+	}
+	return filterType, params, true
+}
+
 func (t *TsDigest) ReCalculateSerializedSize() {
 	//calculate size again
 	t.serializedSize = 4
@@ -65,7 +117,8 @@ func (t *TsDigest) serializeTo(buf *bytes.Buffer) int {
 	} else {
 		n2, _ := buf.Write(utils.Int32ToByte(int32(len(t.statistics)), 0))
 		byteLen += n2
-		for k, v := range t.statistics {
+		for _, k := range utils.SortedKeys(t.statistics) {
+			v := t.statistics[k]
 			n3, _ := buf.Write(utils.Int32ToByte(int32(len(k)), 0))
 			byteLen += n3
 			n4, _ := buf.Write([]byte(k))
@@ -74,9 +127,7 @@ func (t *TsDigest) serializeTo(buf *bytes.Buffer) int {
 			n5, _ := buf.Write(utils.Int32ToByte(int32(v.Len()), 0))
 			byteLen += n5
 
-			timeSlice := make([]byte, v.Len())
-			v.Read(timeSlice)
-			n6, _ := buf.Write(timeSlice)
+			n6, _ := buf.Write(v.Bytes())
 			byteLen += n6
 			// delete(t.statistics, k)
 		}
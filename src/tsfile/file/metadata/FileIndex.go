@@ -0,0 +1,178 @@
+package metadata
+
+import (
+	"bytes"
+	"tsfile/common/constant"
+	"tsfile/common/utils"
+)
+
+// index-section: <series> ... <device table> <table length> <tag>
+// 		series := the series of a device, sensor count, then for each sensor
+// 			sensor id, data type and the chunks as offset, start time and end time
+// 		device table := device count, then for each device its id, start time,
+// 			end time, offset and length of its DeviceMetaData and of its series
+// 		table length := the length of the device table, int32
+// 		tag := FOOTER_INDEX, the section is the last before FOOTER_CRC or
+// 			FOOTER_BLOOM_FILTER so that the table is found from the end of the footer
+// Offsets of DeviceMetaData and series are relative to the start of the footer.
+
+// size of the length and tag ending the sections found from the end of the footer
+const TAIL_TRAILER_SIZE = constant.INT_LEN + constant.SHORT_LEN
+
+// FileIndex is the device table of a file, read without the rest of the footer.
+type FileIndex struct {
+	devices map[string]*DeviceIndex
+}
+
+func (f *FileIndex) Devices() []string {
+	return utils.SortedKeys(f.devices)
+}
+
+func (f *FileIndex) GetDevice(deviceId string) (*DeviceIndex, bool) {
+	d, ok := f.devices[deviceId]
+	return d, ok
+}
+
+// DeviceIndex locates the metadata and the series of a device in the footer.
+type DeviceIndex struct {
+	DeviceId       string
+	StartTime      int64
+	EndTime        int64
+	metadataOffset int32
+	metadataLength int32
+	seriesOffset   int32
+	seriesLength   int32
+}
+
+// MetadataRange returns the offset in the footer and the length of the DeviceMetaData
+func (d *DeviceIndex) MetadataRange() (int64, int) {
+	return int64(d.metadataOffset), int(d.metadataLength)
+}
+
+// SeriesRange returns the offset in the footer and the length of the series
+func (d *DeviceIndex) SeriesRange() (int64, int) {
+	return int64(d.seriesOffset), int(d.seriesLength)
+}
+
+// SeriesIndex lists the chunks of a sensor of a device.
+type SeriesIndex struct {
+	SensorId string
+	DataType constant.TSDataType
+	Chunks   []ChunkIndex
+}
+
+// ChunkIndex is a chunk of a series, Offset is the one of its chunk header.
+type ChunkIndex struct {
+	Offset    int64
+	StartTime int64
+	EndTime   int64
+}
+
+// TailSection returns the tag and length ending a section found from the end
+// of the footer, i.e. FOOTER_INDEX and FOOTER_BLOOM_FILTER, ok is false if
+// the bytes end no such section.
+func TailSection(trailer []byte) (tag int16, length int, ok bool) {
+	if len(trailer) < TAIL_TRAILER_SIZE {
+		return 0, 0, false
+	}
+	reader := utils.NewBytesReader(trailer[len(trailer)-TAIL_TRAILER_SIZE:])
+	length = int(reader.ReadInt())
+	tag = reader.ReadShort()
+	if (tag != FOOTER_INDEX && tag != FOOTER_BLOOM_FILTER) || length < constant.INT_LEN {
+		return 0, 0, false
+	}
+	return tag, length, true
+}
+
+func DeserializeFileIndex(table []byte) *FileIndex {
+	reader := utils.NewBytesReader(table)
+	index := &FileIndex{devices: make(map[string]*DeviceIndex)}
+	size := int(reader.ReadInt())
+	for i := 0; i < size; i++ {
+		d := &DeviceIndex{DeviceId: reader.ReadString()}
+		d.StartTime = reader.ReadLong()
+		d.EndTime = reader.ReadLong()
+		d.metadataOffset = reader.ReadInt()
+		d.metadataLength = reader.ReadInt()
+		d.seriesOffset = reader.ReadInt()
+		d.seriesLength = reader.ReadInt()
+		index.devices[d.DeviceId] = d
+	}
+	return index
+}
+
+func DeserializeSeriesIndex(data []byte) map[string]*SeriesIndex {
+	reader := utils.NewBytesReader(data)
+	series := make(map[string]*SeriesIndex)
+	size := int(reader.ReadInt())
+	for i := 0; i < size; i++ {
+		s := &SeriesIndex{SensorId: reader.ReadString(), DataType: constant.TSDataType(reader.ReadShort())}
+		chunkNum := int(reader.ReadInt())
+		for j := 0; j < chunkNum; j++ {
+			s.Chunks = append(s.Chunks, ChunkIndex{Offset: reader.ReadLong(), StartTime: reader.ReadLong(), EndTime: reader.ReadLong()})
+		}
+		series[s.SensorId] = s
+	}
+	return series
+}
+
+// DeserializeDeviceMetaData reads the DeviceMetaData at the range of a DeviceIndex
+func DeserializeDeviceMetaData(data []byte) *DeviceMetaData {
+	deviceMetaData := new(DeviceMetaData)
+	deviceMetaData.Deserialize(utils.NewBytesReader(data))
+	return deviceMetaData
+}
+
+// serializeIndex writes the index section, payloadOffset is where its payload
+// starts in the footer and deviceRanges the offset and length of the
+// DeviceMetaData of each device there.
+func (t *FileMetaData) serializeIndex(buf *bytes.Buffer, payloadOffset int, deviceRanges map[string][2]int) {
+	deviceIds := utils.SortedKeys(t.deviceMap)
+	seriesRanges := make([][2]int, len(deviceIds))
+	for i, deviceId := range deviceIds {
+		start := buf.Len()
+		t.serializeSeries(buf, deviceId)
+		seriesRanges[i] = [2]int{payloadOffset + start, buf.Len() - start}
+	}
+
+	tableStart := buf.Len()
+	buf.Write(utils.Int32ToByte(int32(len(deviceIds)), 0))
+	for i, deviceId := range deviceIds {
+		deviceMetaData := t.deviceMap[deviceId]
+		buf.Write(utils.Int32ToByte(int32(len(deviceId)), 0))
+		buf.Write([]byte(deviceId))
+		buf.Write(utils.Int64ToByte(deviceMetaData.GetStartTime(), 0))
+		buf.Write(utils.Int64ToByte(deviceMetaData.GetEndTime(), 0))
+		buf.Write(utils.Int32ToByte(int32(deviceRanges[deviceId][0]), 0))
+		buf.Write(utils.Int32ToByte(int32(deviceRanges[deviceId][1]), 0))
+		buf.Write(utils.Int32ToByte(int32(seriesRanges[i][0]), 0))
+		buf.Write(utils.Int32ToByte(int32(seriesRanges[i][1]), 0))
+	}
+	buf.Write(utils.Int32ToByte(int32(buf.Len()-tableStart), 0))
+	buf.Write(utils.Int16ToByte(FOOTER_INDEX, 0))
+}
+
+func (t *FileMetaData) serializeSeries(buf *bytes.Buffer, deviceId string) {
+	chunks := make(map[string][]ChunkIndex)
+	for _, rowGroup := range t.deviceMap[deviceId].GetRowGroups() {
+		for _, chunkMetaData := range rowGroup.GetChunkMetaDataSli() {
+			chunks[chunkMetaData.Sensor()] = append(chunks[chunkMetaData.Sensor()], ChunkIndex{
+				Offset:    chunkMetaData.FileOffsetOfCorrespondingData(),
+				StartTime: chunkMetaData.GetStartTime(),
+				EndTime:   chunkMetaData.GetEndTime(),
+			})
+		}
+	}
+	buf.Write(utils.Int32ToByte(int32(len(chunks)), 0))
+	for _, sensorId := range utils.SortedKeys(chunks) {
+		buf.Write(utils.Int32ToByte(int32(len(sensorId)), 0))
+		buf.Write([]byte(sensorId))
+		buf.Write(utils.Int16ToByte(int16(t.GetDataType(deviceId, sensorId)), 0))
+		buf.Write(utils.Int32ToByte(int32(len(chunks[sensorId])), 0))
+		for _, chunk := range chunks[sensorId] {
+			buf.Write(utils.Int64ToByte(chunk.Offset, 0))
+			buf.Write(utils.Int64ToByte(chunk.StartTime, 0))
+			buf.Write(utils.Int64ToByte(chunk.EndTime, 0))
+		}
+	}
+}
@@ -3,10 +3,28 @@ package metadata
 import (
 	"bytes"
 	_ "encoding/binary"
+	"hash/crc32"
 	_ "log"
+	"tsfile/common/constant"
 	"tsfile/common/utils"
 )
 
+// Optional sections follow the fixed fields of the footer, each one is
+// written as tag(int16) | length(int32) | payload. Readers skip the tags they
+// do not know, and older readers stop before the sections.
+const (
+	FOOTER_DEVICE_SCHEMA int16 = 1
+	// the checksums of the blocks before the footer
+	FOOTER_CHECKSUMS int16 = 2
+	// the CRC32C of the footer before it and the version, always the last section
+	FOOTER_CRC int16 = 3
+	// the device table locating the metadata of each device, see FileIndex
+	FOOTER_INDEX int16 = 4
+	// the bloom filter over the series paths, optional, always the one before
+	// FOOTER_CRC and after FOOTER_INDEX, see BloomFilter
+	FOOTER_BLOOM_FILTER int16 = 5
+)
+
 type FileMetaData struct {
 	currentVersion                   int
 	createdBy                        string
@@ -17,6 +35,34 @@ type FileMetaData struct {
 
 	deviceMap             map[string]*DeviceMetaData
 	timeSeriesMetadataMap map[string]*TimeSeriesMetaData
+	// sensors registered for one device, they shadow timeSeriesMetadataMap
+	deviceSchemaMap map[string]map[string]*TimeSeriesMetaData
+	// checksums of the blocks by their offset
+	checksums map[int64]Checksum
+	// over the paths of the series with chunks in the file, nil if none is written
+	bloomFilter *BloomFilter
+}
+
+func (f *FileMetaData) DeviceSchemaMap() map[string]map[string]*TimeSeriesMetaData {
+	return f.deviceSchemaMap
+}
+
+func (f *FileMetaData) SetDeviceSchemaMap(deviceSchemaMap map[string]map[string]*TimeSeriesMetaData) {
+	f.deviceSchemaMap = deviceSchemaMap
+}
+
+// GetDataType returns the type of the sensor of the device, INVALID if the
+// file does not know it.
+func (f *FileMetaData) GetDataType(deviceId string, sensorId string) constant.TSDataType {
+	if schema, ok := f.deviceSchemaMap[deviceId]; ok {
+		if tsMeta, ok := schema[sensorId]; ok {
+			return tsMeta.DataType()
+		}
+	}
+	if tsMeta, ok := f.timeSeriesMetadataMap[sensorId]; ok {
+		return tsMeta.DataType()
+	}
+	return constant.INVALID
 }
 
 func (f *FileMetaData) TimeSeriesMetadataMap() map[string]*TimeSeriesMetaData {
@@ -60,6 +106,71 @@ func (f *FileMetaData) Deserialize(metadata []byte) {
 	f.lastTimeSeriesMetadataOffset = reader.ReadLong()
 	f.firstTsDeltaObjectMetadataOffset = reader.ReadLong()
 	f.lastTsDeltaObjectMetadataOffset = reader.ReadLong()
+
+	f.deviceSchemaMap = make(map[string]map[string]*TimeSeriesMetaData)
+	f.checksums = make(map[int64]Checksum)
+	switch f.currentVersion {
+	case VERSION_3:
+		// nothing follows the offsets
+	default:
+		f.deserializeSections(reader)
+	}
+}
+
+// deserializeSections reads the sections after the offsets of VERSION_4,
+// unknown ones are skipped
+func (f *FileMetaData) deserializeSections(reader *utils.BytesReader) {
+	for reader.Len() >= constant.SHORT_LEN+constant.INT_LEN {
+		tag := reader.ReadShort()
+		length := int(reader.ReadInt())
+		if length < 0 || length > reader.Len() {
+			break
+		}
+		section := utils.NewBytesReader(reader.ReadSlice(length))
+		switch tag {
+		case FOOTER_DEVICE_SCHEMA:
+			f.deserializeDeviceSchema(section)
+		case FOOTER_CHECKSUMS:
+			f.deserializeChecksums(section)
+		case FOOTER_BLOOM_FILTER:
+			f.bloomFilter = DeserializeBloomFilter(section.Remaining())
+		}
+	}
+}
+
+func (f *FileMetaData) deserializeDeviceSchema(reader *utils.BytesReader) {
+	size := int(reader.ReadInt())
+	for i := 0; i < size; i++ {
+		deviceId := reader.ReadString()
+		schema := make(map[string]*TimeSeriesMetaData)
+		sensorNum := int(reader.ReadInt())
+		for j := 0; j < sensorNum; j++ {
+			value := new(TimeSeriesMetaData)
+			value.Deserialize(reader)
+			schema[value.GetSensor()] = value
+		}
+		f.deviceSchemaMap[deviceId] = schema
+	}
+}
+
+func (t *FileMetaData) serializeDeviceSchema(buf *bytes.Buffer) {
+	buf.Write(utils.Int32ToByte(int32(len(t.deviceSchemaMap)), 0))
+	for _, deviceId := range utils.SortedKeys(t.deviceSchemaMap) {
+		schema := t.deviceSchemaMap[deviceId]
+		buf.Write(utils.Int32ToByte(int32(len(deviceId)), 0))
+		buf.Write([]byte(deviceId))
+		buf.Write(utils.Int32ToByte(int32(len(schema)), 0))
+		for _, sensorId := range utils.SortedKeys(schema) {
+			schema[sensorId].Serialize(buf)
+		}
+	}
+}
+
+func writeFooterSection(buf *bytes.Buffer, tag int16, payload []byte) int {
+	n1, _ := buf.Write(utils.Int16ToByte(tag, 0))
+	n2, _ := buf.Write(utils.Int32ToByte(int32(len(payload)), 0))
+	n3, _ := buf.Write(payload)
+	return n1 + n2 + n3
 }
 
 func (f *FileMetaData) GetCurrentVersion() int {
@@ -67,7 +178,10 @@ func (f *FileMetaData) GetCurrentVersion() int {
 }
 
 func (t *FileMetaData) SerializeTo(buf *bytes.Buffer) int {
+	start := buf.Len()
 	var byteLen int
+	// where the DeviceMetaData of each device is in the footer, for the index
+	deviceRanges := make(map[string][2]int)
 	if t.deviceMap == nil {
 		n, _ := buf.Write(utils.Int32ToByte(0, 0))
 		byteLen += n
@@ -76,14 +190,17 @@ func (t *FileMetaData) SerializeTo(buf *bytes.Buffer) int {
 		d1, _ := buf.Write(utils.Int32ToByte(int32(n), 0))
 		byteLen += d1
 
-		for k, v := range t.deviceMap {
+		for _, k := range utils.SortedKeys(t.deviceMap) {
+			v := t.deviceMap[k]
 			// write string tsDeviceMetaData key
 			d2, _ := buf.Write(utils.Int32ToByte(int32(len(k)), 0))
 			byteLen += d2
 			d3, _ := buf.Write([]byte(k))
 			byteLen += d3
 			// tsDeviceMetaData SerializeTo
+			deviceStart := buf.Len()
 			byteLen += v.SerializeTo(buf)
+			deviceRanges[k] = [2]int{deviceStart - start, buf.Len() - deviceStart}
 			// log.Info("v: %s", v)
 		}
 	}
@@ -93,7 +210,8 @@ func (t *FileMetaData) SerializeTo(buf *bytes.Buffer) int {
 	} else {
 		e2, _ := buf.Write(utils.Int32ToByte(int32(len(t.timeSeriesMetadataMap)), 0))
 		byteLen += e2
-		for _, vv := range t.timeSeriesMetadataMap {
+		for _, sensorId := range utils.SortedKeys(t.timeSeriesMetadataMap) {
+			vv := t.timeSeriesMetadataMap[sensorId]
 			// timeSeriesMetaData SerializeTo
 			byteLen += vv.Serialize(buf)
 			// log.Info("vv: %s", vv)
@@ -125,6 +243,32 @@ func (t *FileMetaData) SerializeTo(buf *bytes.Buffer) int {
 	off4, _ := buf.Write(utils.Int64ToByte(t.lastTsDeltaObjectMetadataOffset, 0))
 	byteLen += off4
 
+	if t.currentVersion < VERSION_4 {
+		return byteLen
+	}
+	if len(t.deviceSchemaMap) > 0 {
+		section := bytes.NewBuffer([]byte{})
+		t.serializeDeviceSchema(section)
+		byteLen += writeFooterSection(buf, FOOTER_DEVICE_SCHEMA, section.Bytes())
+	}
+	if len(t.checksums) > 0 {
+		section := bytes.NewBuffer([]byte{})
+		t.serializeChecksums(section)
+		byteLen += writeFooterSection(buf, FOOTER_CHECKSUMS, section.Bytes())
+	}
+	if len(t.deviceMap) > 0 {
+		section := bytes.NewBuffer([]byte{})
+		t.serializeIndex(section, buf.Len()-start+constant.SHORT_LEN+constant.INT_LEN, deviceRanges)
+		byteLen += writeFooterSection(buf, FOOTER_INDEX, section.Bytes())
+	}
+	if t.bloomFilter != nil {
+		section := bytes.NewBuffer([]byte{})
+		t.bloomFilter.serializeTo(section)
+		byteLen += writeFooterSection(buf, FOOTER_BLOOM_FILTER, section.Bytes())
+	}
+	crc := crc32.Checksum(buf.Bytes()[start:], CRC32C)
+	byteLen += writeFooterSection(buf, FOOTER_CRC, append(utils.Int32ToByte(int32(crc), 0), utils.Int32ToByte(int32(t.currentVersion), 0)...))
+
 	return byteLen
 }
 
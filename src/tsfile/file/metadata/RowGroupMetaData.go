@@ -51,10 +51,19 @@ func (r *RowGroupMetaData) SetTotalByteSize(ms int64) {
 	r.totalByteSize = ms
 }
 
+// GetTotalByteSize is the size of the row group with its header
+func (r *RowGroupMetaData) GetTotalByteSize() int64 {
+	return r.totalByteSize
+}
+
 func (r *RowGroupMetaData) GetDeviceId() string {
 	return r.device
 }
 
+func (r *RowGroupMetaData) GetFileOffsetOfCorrespondingData() int64 {
+	return r.fileOffsetOfCorrespondingData
+}
+
 func (r *RowGroupMetaData) SerializeTo(buf *bytes.Buffer) int {
 	if r.sizeOfChunkSli != len(r.ChunkMetaDataSli) {
 		r.RecalculateSerializedSize()
@@ -99,7 +108,7 @@ func (r *RowGroupMetaData) RecalculateSerializedSize() {
 	for _, v := range r.ChunkMetaDataSli {
 		if &v != nil {
 			r.serializedSize += v.GetSerializedSize()
-			log.Info("ChunkMetaDataSliaaaaaa: %s", v)
+			log.Info("ChunkMetaDataSliaaaaaa: %v", v)
 		}
 	}
 	r.sizeOfChunkSli = len(r.ChunkMetaDataSli)
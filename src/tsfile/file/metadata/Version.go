@@ -0,0 +1,43 @@
+package metadata
+
+import (
+	"errors"
+	"strconv"
+	"tsfile/common/constant"
+	"tsfile/common/utils"
+)
+
+// versions of the format read and written
+const (
+	// the format of the TsFile v0.8, the footer ends after the metadata offsets
+	VERSION_3 = 3
+	// the footer ends with the sections, the last one, FOOTER_CRC, repeats the
+	// version so that it is read without the rest of the footer
+	VERSION_4 = 4
+
+	MIN_VERSION    = VERSION_3
+	LATEST_VERSION = VERSION_4
+)
+
+// CheckVersion fails for the versions that can not be read or written
+func CheckVersion(version int) error {
+	if version < MIN_VERSION || version > LATEST_VERSION {
+		return errors.New("unsupported format version " + strconv.Itoa(version) + ", supported are " +
+			strconv.Itoa(MIN_VERSION) + " to " + strconv.Itoa(LATEST_VERSION))
+	}
+	return nil
+}
+
+// TailVersion returns the version in the FOOTER_CRC section the serialized
+// footer ends with, ok is false if it has none, i.e. it is of VERSION_3.
+func TailVersion(tail []byte) (version int, ok bool) {
+	if len(tail) < FOOTER_CRC_SIZE {
+		return 0, false
+	}
+	reader := utils.NewBytesReader(tail[len(tail)-FOOTER_CRC_SIZE:])
+	if reader.ReadShort() != FOOTER_CRC || int(reader.ReadInt()) != 2*constant.INT_LEN {
+		return 0, false
+	}
+	reader.ReadInt()
+	return int(reader.ReadInt()), true
+}
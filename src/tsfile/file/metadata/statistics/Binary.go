@@ -2,28 +2,18 @@ package statistics
 
 import (
 	"tsfile/common/utils"
-	"bytes"
 )
 
 type Binary struct {
-	//max   string
-	//min   string
-	//first string
-	//last  string
-	//sum   float64 //meaningless
-	max     []byte
-	min     []byte
-	first   []byte
-	last    []byte
-	sum     float64 //meaningless
-	isEmpty bool
+	// sum is meaningless
+	values[string]
 }
 
-func (s *Binary) Deserialize(reader *utils.FileReader) {
-	s.min = reader.ReadStringBinary()
-	s.max = reader.ReadStringBinary()
-	s.first = reader.ReadStringBinary()
-	s.last = reader.ReadStringBinary()
+func (s *Binary) Deserialize(reader Reader) {
+	s.min = string(reader.ReadStringBinary())
+	s.max = string(reader.ReadStringBinary())
+	s.first = string(reader.ReadStringBinary())
+	s.last = string(reader.ReadStringBinary())
 	s.sum = reader.ReadDouble()
 }
 
@@ -51,38 +41,15 @@ func (b *Binary) GetSumByte(tdt int16) []byte {
 	return utils.Float64ToByte(b.sum, 0)
 }
 
-func (b *Binary) UpdateStats(fValue interface{}) {
-	value := []byte(fValue.(string))
-	if !b.isEmpty {
-		b.InitializeStats(value, value, value, value, 0)
-		b.isEmpty = true
-	} else {
-		b.UpdateValue(value, value, value, value, 0)
-	}
+func (b *Binary) UpdateStats(t int64, fValue interface{}) {
+	value := fValue.(string)
+	b.update(t, value, 0, less[string])
 }
 
-func (b *Binary) UpdateValue(max []byte, min []byte, first []byte, last []byte, sum float64) {
-	// todo compare two []byte
-	retMax := bytes.Compare(max, b.max)
-	if retMax > 0 {
-		b.max = max
-	}
-	retMin := bytes.Compare(min, b.min)
-	if retMin < 0 {
-		b.min = min
-	}
-
-	b.last = last
-}
-
-func (b *Binary) InitializeStats(max []byte, min []byte, first []byte, last []byte, sum float64) {
-	b.max = max
-	b.min = min
-	b.first = first
-	b.last = last
-	b.sum = sum
+func (b *Binary) Merge(other Statistics) {
+	b.merge(&other.(*Binary).values, less[string])
 }
 
 func (s *Binary) GetSerializedSize() int {
-	return 4*4 + len(s.max) + len(s.min) + len(s.first) + len(s.last)
+	return 4*4 + len(s.max) + len(s.min) + len(s.first) + len(s.last) + 8
 }
@@ -6,15 +6,10 @@ import (
 )
 
 type Boolean struct {
-	max     bool
-	min     bool
-	first   bool
-	last    bool
-	sum     float64
-	isEmpty bool
+	values[bool]
 }
 
-func (s *Boolean) Deserialize(reader *utils.FileReader) {
+func (s *Boolean) Deserialize(reader Reader) {
 	s.min = reader.ReadBool()
 	s.max = reader.ReadBool()
 	s.first = reader.ReadBool()
@@ -46,41 +41,15 @@ func (b *Boolean) GetSumByte(tdt int16) []byte {
 	return utils.Float64ToByte(b.sum, 0)
 }
 
-func (b *Boolean) UpdateStats(iValue interface{}) {
+func (b *Boolean) UpdateStats(t int64, iValue interface{}) {
 	value := iValue.(bool)
-	if !b.isEmpty {
-		b.InitializeStats(value, value, value, value, 0)
-		b.isEmpty = true
-	} else {
-		b.UpdateValue(value, value, value, value, 0)
-		b.isEmpty = false
-	}
+	b.update(t, value, 0, lessBool)
 }
 
-func (b *Boolean) UpdateValue(max bool, min bool, first bool, last bool, sum float64) {
-	if max && !b.max {
-		b.max = max
-	}
-	if !min && b.min {
-		b.min = min
-	}
-	b.last = last
-}
-
-func (b *Boolean) InitializeStats(max bool, min bool, first bool, last bool, sum float64) {
-	b.max = max
-	b.min = min
-	b.first = first
-	b.last = last
+func (b *Boolean) Merge(other Statistics) {
+	b.merge(&other.(*Boolean).values, lessBool)
 }
 
 func (s *Boolean) GetSerializedSize() int {
 	return 4*constant.BOOLEAN_LEN + constant.DOUBLE_LEN
 }
-
-//func NewBool() (*Statistics, error) {
-//
-//	return &Statistics{
-//		isEmpty:true,
-//	},nil
-//}
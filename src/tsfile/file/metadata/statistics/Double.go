@@ -6,15 +6,10 @@ import (
 )
 
 type Double struct {
-	max     float64
-	min     float64
-	first   float64
-	last    float64
-	sum     float64
-	isEmpty bool
+	values[float64]
 }
 
-func (s *Double) Deserialize(reader *utils.FileReader) {
+func (s *Double) Deserialize(reader Reader) {
 	s.min = reader.ReadDouble()
 	s.max = reader.ReadDouble()
 	s.first = reader.ReadDouble()
@@ -23,7 +18,7 @@ func (s *Double) Deserialize(reader *utils.FileReader) {
 }
 
 func (d *Double) SizeOfDaum() int {
-	return 4
+	return 8
 }
 
 func (d *Double) GetMaxByte(tdt int16) []byte {
@@ -46,35 +41,15 @@ func (d *Double) GetSumByte(tdt int16) []byte {
 	return utils.Float64ToByte(d.sum, 0)
 }
 
-func (d *Double) UpdateStats(dValue interface{}) {
+func (d *Double) UpdateStats(t int64, dValue interface{}) {
 	value := dValue.(float64)
-	if !d.isEmpty {
-		d.InitializeStats(value, value, value, value, value)
-		d.isEmpty = true
-	} else {
-		d.UpdateValue(value, value, value, value, value)
-	}
+	d.update(t, value, value, less[float64])
 }
 
-func (d *Double) UpdateValue(max float64, min float64, first float64, last float64, sum float64) {
-	if max > d.max {
-		d.max = max
-	}
-	if min < d.min {
-		d.min = min
-	}
-	d.sum += sum
-	d.last = last
-}
-
-func (d *Double) InitializeStats(max float64, min float64, first float64, last float64, sum float64) {
-	d.max = max
-	d.min = min
-	d.first = first
-	d.last = last
-	d.sum = sum
+func (d *Double) Merge(other Statistics) {
+	d.merge(&other.(*Double).values, less[float64])
 }
 
 func (s *Double) GetSerializedSize() int {
-	return constant.DOUBLE_LEN * 5
+	return 4*constant.DOUBLE_LEN + constant.DOUBLE_LEN
 }
@@ -6,15 +6,10 @@ import (
 )
 
 type Float struct {
-	max     float32
-	min     float32
-	first   float32
-	last    float32
-	sum     float64
-	isEmpty bool
+	values[float32]
 }
 
-func (s *Float) Deserialize(reader *utils.FileReader) {
+func (s *Float) Deserialize(reader Reader) {
 	s.min = reader.ReadFloat()
 	s.max = reader.ReadFloat()
 	s.first = reader.ReadFloat()
@@ -46,33 +41,13 @@ func (f *Float) GetSumByte(tdt int16) []byte {
 	return utils.Float64ToByte(f.sum, 0)
 }
 
-func (f *Float) UpdateStats(fValue interface{}) {
+func (f *Float) UpdateStats(t int64, fValue interface{}) {
 	value := fValue.(float32)
-	if !f.isEmpty {
-		f.InitializeStats(value, value, value, value, float64(value))
-		f.isEmpty = true
-	} else {
-		f.UpdateValue(value, value, value, value, float64(value))
-	}
+	f.update(t, value, float64(value), less[float32])
 }
 
-func (f *Float) UpdateValue(max float32, min float32, first float32, last float32, sum float64) {
-	if max > f.max {
-		f.max = max
-	}
-	if min < f.min {
-		f.min = min
-	}
-	f.sum += sum
-	f.last = last
-}
-
-func (f *Float) InitializeStats(max float32, min float32, first float32, last float32, sum float64) {
-	f.max = max
-	f.min = min
-	f.first = first
-	f.last = last
-	f.sum = sum
+func (f *Float) Merge(other Statistics) {
+	f.merge(&other.(*Float).values, less[float32])
 }
 
 func (s *Float) GetSerializedSize() int {
@@ -6,15 +6,10 @@ import (
 )
 
 type Integer struct {
-	max     int32
-	min     int32
-	first   int32
-	last    int32
-	sum     float64
-	isEmpty bool
+	values[int32]
 }
 
-func (s *Integer) Deserialize(reader *utils.FileReader) {
+func (s *Integer) Deserialize(reader Reader) {
 	s.min = reader.ReadInt()
 	s.max = reader.ReadInt()
 	s.first = reader.ReadInt()
@@ -46,33 +41,13 @@ func (i *Integer) GetSumByte(tdt int16) []byte {
 	return utils.Float64ToByte(i.sum, 0)
 }
 
-func (i *Integer) UpdateStats(iValue interface{}) {
+func (i *Integer) UpdateStats(t int64, iValue interface{}) {
 	value := iValue.(int32)
-	if !i.isEmpty {
-		i.InitializeStats(value, value, value, value, float64(value))
-		i.isEmpty = true
-	} else {
-		i.UpdateValue(value, value, value, value, float64(value))
-	}
+	i.update(t, value, float64(value), less[int32])
 }
 
-func (i *Integer) UpdateValue(max int32, min int32, first int32, last int32, sum float64) {
-	if max > i.max {
-		i.max = max
-	}
-	if min < i.min {
-		i.min = min
-	}
-	i.sum += sum
-	i.last = last
-}
-
-func (i *Integer) InitializeStats(max int32, min int32, first int32, last int32, sum float64) {
-	i.max = max
-	i.min = min
-	i.first = first
-	i.last = last
-	i.sum = sum
+func (i *Integer) Merge(other Statistics) {
+	i.merge(&other.(*Integer).values, less[int32])
 }
 
 func (s *Integer) GetSerializedSize() int {
@@ -6,15 +6,10 @@ import (
 )
 
 type Long struct {
-	max     int64
-	min     int64
-	first   int64
-	last    int64
-	sum     float64
-	isEmpty bool
+	values[int64]
 }
 
-func (s *Long) Deserialize(reader *utils.FileReader) {
+func (s *Long) Deserialize(reader Reader) {
 	s.min = reader.ReadLong()
 	s.max = reader.ReadLong()
 	s.first = reader.ReadLong()
@@ -46,33 +41,13 @@ func (l *Long) GetSumByte(tdt int16) []byte {
 	return utils.Float64ToByte(l.sum, 0)
 }
 
-func (l *Long) UpdateStats(lValue interface{}) {
+func (l *Long) UpdateStats(t int64, lValue interface{}) {
 	value := lValue.(int64)
-	if !l.isEmpty {
-		l.InitializeStats(value, value, value, value, float64(value))
-		l.isEmpty = true
-	} else {
-		l.UpdateValue(value, value, value, value, float64(value))
-	}
+	l.update(t, value, float64(value), less[int64])
 }
 
-func (l *Long) UpdateValue(max int64, min int64, first int64, last int64, sum float64) {
-	if max > l.max {
-		l.max = max
-	}
-	if min < l.min {
-		l.min = min
-	}
-	l.sum += sum
-	l.last = last
-}
-
-func (l *Long) InitializeStats(max int64, min int64, first int64, last int64, sum float64) {
-	l.max = max
-	l.min = min
-	l.first = first
-	l.last = last
-	l.sum = sum
+func (l *Long) Merge(other Statistics) {
+	l.merge(&other.(*Long).values, less[int64])
 }
 
 func (s *Long) GetSerializedSize() int {
@@ -2,13 +2,27 @@ package statistics
 
 import (
 	"bytes"
+	"math"
 	"strconv"
 	"tsfile/common/constant"
 	"tsfile/common/utils"
 )
 
+// Reader is what statistics are deserialized from, a utils.FileReader or utils.BytesReader
+type Reader interface {
+	ReadBool() bool
+	ReadInt() int32
+	ReadLong() int64
+	ReadFloat() float32
+	ReadDouble() float64
+	ReadStringBinary() []byte
+}
+
+// Statistics of the values of a page, a chunk or a series. The count and the
+// time range are not serialized with the values, SetRange restores them from
+// the header they belong to.
 type Statistics interface {
-	Deserialize(reader *utils.FileReader)
+	Deserialize(reader Reader)
 	GetSerializedSize() int
 	GetMaxByte(tdt int16) []byte
 	GetMinByte(tdt int16) []byte
@@ -16,10 +30,20 @@ type Statistics interface {
 	GetLastByte(tdt int16) []byte
 	GetSumByte(tdt int16) []byte
 	SizeOfDaum() int
-	UpdateStats(value interface{})
+	// UpdateStats adds the value of time t
+	UpdateStats(t int64, value interface{})
+	// Merge adds the statistics of the same data type
+	Merge(other Statistics)
+	Count() int64
+	// FirstTime returns the smallest time of the values
+	FirstTime() int64
+	// LastTime returns the largest time of the values
+	LastTime() int64
+	IsEmpty() bool
+	SetRange(count int64, firstTime int64, lastTime int64)
 }
 
-func Deserialize(reader *utils.FileReader, dataType constant.TSDataType) Statistics {
+func Deserialize(reader Reader, dataType constant.TSDataType) Statistics {
 	var statistics Statistics
 
 	switch dataType {
@@ -70,21 +94,21 @@ func Serialize(s Statistics, buffer *bytes.Buffer, tsDataType int16) int {
 	if s.SizeOfDaum() == 0 {
 		return 0
 	} else if s.SizeOfDaum() != -1 {
-		buffer.Write(s.GetMaxByte(tsDataType))
 		buffer.Write(s.GetMinByte(tsDataType))
+		buffer.Write(s.GetMaxByte(tsDataType))
 		buffer.Write(s.GetFirstByte(tsDataType))
 		buffer.Write(s.GetLastByte(tsDataType))
 		buffer.Write(s.GetSumByte(tsDataType))
 		length = s.SizeOfDaum()*4 + 8
 	} else {
-		maxData := s.GetMaxByte(tsDataType)
-		buffer.Write(utils.Int32ToByte(int32(len(maxData)), 0))
-		maxLen, _ := buffer.Write(maxData)
-		length += maxLen
 		minData := s.GetMinByte(tsDataType)
 		buffer.Write(utils.Int32ToByte(int32(len(minData)), 0))
 		minLen, _ := buffer.Write(minData)
 		length += minLen
+		maxData := s.GetMaxByte(tsDataType)
+		buffer.Write(utils.Int32ToByte(int32(len(maxData)), 0))
+		maxLen, _ := buffer.Write(maxData)
+		length += maxLen
 		firstData := s.GetFirstByte(tsDataType)
 		buffer.Write(utils.Int32ToByte(int32(len(firstData)), 0))
 		firstLen, _ := buffer.Write(firstData)
@@ -101,3 +125,46 @@ func Serialize(s Statistics, buffer *bytes.Buffer, tsDataType int16) int {
 	}
 	return length
 }
+
+// FromDigest restores the statistics of a chunk from the values in its digest,
+// the count and the time range are the ones of the chunk. It returns nil if the
+// values do not fit the data type.
+func FromDigest(dataType constant.TSDataType, min []byte, max []byte, first []byte, last []byte, sum []byte,
+	count int64, firstTime int64, lastTime int64) Statistics {
+	statistics := GetStatsByType(int16(dataType))
+	if len(sum) != constant.DOUBLE_LEN {
+		return nil
+	}
+	if size := statistics.SizeOfDaum(); size != -1 {
+		for _, v := range [][]byte{min, max, first, last} {
+			if len(v) != size {
+				return nil
+			}
+		}
+	}
+	var buf bytes.Buffer
+	for _, v := range [][]byte{min, max, first, last} {
+		if dataType == constant.TEXT {
+			buf.Write(utils.Int32ToByte(int32(len(v)), 0))
+		}
+		buf.Write(v)
+	}
+	buf.Write(sum)
+	statistics.Deserialize(digestReader{utils.NewBytesReader(buf.Bytes())})
+	statistics.SetRange(count, firstTime, lastTime)
+	return statistics
+}
+
+// digestReader reads the floating point values of a digest big-endian, as they
+// are written, BytesReader reads them little-endian as the PLAIN encoding does.
+type digestReader struct {
+	*utils.BytesReader
+}
+
+func (r digestReader) ReadFloat() float32 {
+	return math.Float32frombits(uint32(r.ReadInt()))
+}
+
+func (r digestReader) ReadDouble() float64 {
+	return math.Float64frombits(uint64(r.ReadLong()))
+}
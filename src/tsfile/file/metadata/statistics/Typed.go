@@ -0,0 +1,126 @@
+package statistics
+
+import (
+	"cmp"
+)
+
+// TypedStatistics gives the values of statistics as the Go type of their data
+// type, int32, int64, float32, float64, bool or string.
+type TypedStatistics[T any] interface {
+	Statistics
+	Min() T
+	Max() T
+	First() T
+	Last() T
+	Sum() float64
+}
+
+// values are the statistics every data type keeps, less orders the values.
+// The first and last values are the ones of the smallest and largest time.
+type values[T any] struct {
+	min       T
+	max       T
+	first     T
+	last      T
+	sum       float64
+	count     int64
+	firstTime int64
+	lastTime  int64
+}
+
+func (s *values[T]) Min() T {
+	return s.min
+}
+
+func (s *values[T]) Max() T {
+	return s.max
+}
+
+func (s *values[T]) First() T {
+	return s.first
+}
+
+func (s *values[T]) Last() T {
+	return s.last
+}
+
+func (s *values[T]) Sum() float64 {
+	return s.sum
+}
+
+func (s *values[T]) Count() int64 {
+	return s.count
+}
+
+func (s *values[T]) FirstTime() int64 {
+	return s.firstTime
+}
+
+func (s *values[T]) LastTime() int64 {
+	return s.lastTime
+}
+
+func (s *values[T]) IsEmpty() bool {
+	return s.count == 0
+}
+
+func (s *values[T]) SetRange(count int64, firstTime int64, lastTime int64) {
+	s.count = count
+	s.firstTime = firstTime
+	s.lastTime = lastTime
+}
+
+func (s *values[T]) update(t int64, value T, sum float64, less func(a, b T) bool) {
+	if s.count == 0 {
+		s.min, s.max, s.first, s.last = value, value, value, value
+		s.firstTime, s.lastTime = t, t
+	} else {
+		if less(value, s.min) {
+			s.min = value
+		}
+		if less(s.max, value) {
+			s.max = value
+		}
+		if t < s.firstTime {
+			s.first, s.firstTime = value, t
+		}
+		if t >= s.lastTime {
+			s.last, s.lastTime = value, t
+		}
+	}
+	s.sum += sum
+	s.count++
+}
+
+func (s *values[T]) merge(other *values[T], less func(a, b T) bool) {
+	if other.count == 0 {
+		return
+	}
+	if s.count == 0 {
+		*s = *other
+		return
+	}
+	if less(other.min, s.min) {
+		s.min = other.min
+	}
+	if less(s.max, other.max) {
+		s.max = other.max
+	}
+	if other.firstTime < s.firstTime {
+		s.first, s.firstTime = other.first, other.firstTime
+	}
+	if other.lastTime >= s.lastTime {
+		s.last, s.lastTime = other.last, other.lastTime
+	}
+	s.sum += other.sum
+	s.count += other.count
+}
+
+func less[T cmp.Ordered](a, b T) bool {
+	return a < b
+}
+
+// false is less than true
+func lessBool(a, b bool) bool {
+	return !a && b
+}
@@ -5,3 +5,11 @@ package filter
 type Filter interface {
 	Satisfy(val interface{}) bool
 }
+
+// SeriesOf returns the series whose values the filter tests in a RowRecord
+func SeriesOf(f Filter) []string {
+	if s, ok := f.(interface{ Series() []string }); ok {
+		return s.Series()
+	}
+	return nil
+}
@@ -22,6 +22,10 @@ func NewRowRecordValFilter(seriesName string, filter Filter) *RowRecordValFilter
 	return &RowRecordValFilter{seriesName: seriesName, filter: filter, seriesIndex: constant.INDEX_NOT_SET}
 }
 
+func (s *RowRecordValFilter) Series() []string {
+	return []string{s.seriesName}
+}
+
 func (s *RowRecordValFilter) Satisfy(val interface{}) bool {
 	if m, ok := val.(*datatype.RowRecord); ok {
 		if s.seriesIndex == constant.INDEX_NOT_SET {
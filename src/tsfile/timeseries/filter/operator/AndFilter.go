@@ -7,6 +7,14 @@ type AndFilter struct {
 	Filters []filter.Filter
 }
 
+func (f *AndFilter) Series() []string {
+	var series []string
+	for _, filt := range f.Filters {
+		series = append(series, filter.SeriesOf(filt)...)
+	}
+	return series
+}
+
 func (f *AndFilter) Satisfy(val interface{}) bool {
 	if f.Filters == nil {
 		return true
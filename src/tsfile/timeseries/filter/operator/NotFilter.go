@@ -8,6 +8,10 @@ type NotFilter struct {
 	inner filter.Filter
 }
 
+func (f *NotFilter) Series() []string {
+	return filter.SeriesOf(f.inner)
+}
+
 func (f *NotFilter) Satisfy(val interface{}) bool {
 	return !f.inner.Satisfy(val)
 }
@@ -7,6 +7,14 @@ type OrFilter struct {
 	filters []filter.Filter
 }
 
+func (f *OrFilter) Series() []string {
+	var series []string
+	for _, filt := range f.filters {
+		series = append(series, filter.SeriesOf(filt)...)
+	}
+	return series
+}
+
 func (f *OrFilter) Satisfy(val interface{}) bool {
 	if f.filters == nil {
 		return true
@@ -35,7 +35,7 @@ func (set *TimestampQueryDataSet) fetch() {
 	//if set.tGen.HasNext() {
 	//	currTime, err := set.tGen.Next()
 	//	if err != nil {
-	//		log.Error("cannot generate next timestamp", err)
+	//		log.Error("cannot generate next timestamp: %v", err)
 	//		set.exhausted = true
 	//		return
 	//	}
@@ -43,19 +43,20 @@ func (set *TimestampQueryDataSet) fetch() {
 	//		set.current = set.r.Current()
 	//	}
 	//}
-	if set.rGen.HasNext() {
+	// skip the timestamps where none of the selected series has a value
+	for set.rGen.HasNext() {
 		currRecord, err := set.rGen.Next()
 		if err != nil {
-			log.Error("cannot generate next timestamp", err)
+			log.Error("cannot generate next timestamp: %v", err)
 			set.exhausted = true
 			return
 		}
 		if set.r.Seek(currRecord.Timestamp()) {
 			set.current = set.r.Current()
+			return
 		}
-	} else {
-		set.exhausted = true
 	}
+	set.exhausted = true
 }
 
 func (set *TimestampQueryDataSet) HasNext() bool {
@@ -86,8 +87,9 @@ func (set *TimestampQueryDataSet) Next() (*datatype.RowRecord, error) {
 		set.exhausted = true
 		return nil, errors.New("Dataset exhausted!");
 	}
+	// the row record is reused by the seekable reader, the next one is
+	// fetched by HasNext after the caller is done with this one
 	set.current = nil
-	set.fetch()
 	return ret, nil
 }
 
@@ -0,0 +1,203 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"tsfile/common/conf"
+	"tsfile/common/constant"
+	"tsfile/file/metadata"
+	"tsfile/timeseries/query"
+	"tsfile/timeseries/read"
+	"tsfile/timeseries/write/sensorDescriptor"
+	"tsfile/timeseries/write/tsFileWriter"
+)
+
+// Golden files written by the Java TsFile v0.8, see testdata/java_v0.8/README.txt
+var goldenDir = filepath.Join("testdata", "java_v0.8")
+
+const goldenPointNum = 1000
+
+type goldenCase struct {
+	dataType    constant.TSDataType
+	encoding    constant.TSEncoding
+	compression constant.CompressionType
+}
+
+// name of the golden file, e.g. INT32_TS_2DIFF_SNAPPY.tsfile
+func (c goldenCase) name() string {
+	dataTypes := []string{"BOOLEAN", "INT32", "INT64", "FLOAT", "DOUBLE", "TEXT"}
+	encodings := map[constant.TSEncoding]string{constant.PLAIN: "PLAIN", constant.RLE: "RLE",
+		constant.TS_2DIFF: "TS_2DIFF", constant.GORILLA: "GORILLA"}
+	compressions := map[constant.CompressionType]string{constant.UNCOMPRESSED: "UNCOMPRESSED", constant.SNAPPY: "SNAPPY"}
+	return dataTypes[c.dataType] + "_" + encodings[c.encoding] + "_" + compressions[c.compression] + ".tsfile"
+}
+
+// goldenCases are the data types, encodings and compressions shared with the Java v0.8
+func goldenCases() []goldenCase {
+	encodings := map[constant.TSDataType][]constant.TSEncoding{
+		constant.BOOLEAN: {constant.PLAIN, constant.RLE},
+		constant.INT32:   {constant.PLAIN, constant.RLE, constant.TS_2DIFF},
+		constant.INT64:   {constant.PLAIN, constant.RLE, constant.TS_2DIFF},
+		constant.FLOAT:   {constant.PLAIN, constant.RLE, constant.TS_2DIFF, constant.GORILLA},
+		constant.DOUBLE:  {constant.PLAIN, constant.RLE, constant.TS_2DIFF, constant.GORILLA},
+		constant.TEXT:    {constant.PLAIN},
+	}
+	var cases []goldenCase
+	for dataType := constant.BOOLEAN; dataType <= constant.TEXT; dataType++ {
+		for _, encoding := range encodings[dataType] {
+			for _, compression := range []constant.CompressionType{constant.UNCOMPRESSED, constant.SNAPPY} {
+				cases = append(cases, goldenCase{dataType, encoding, compression})
+			}
+		}
+	}
+	return cases
+}
+
+// goldenValue is the value of root.d0.s0 at time i of the golden files, the
+// floating point values have two decimal digits for conf.FloatPrecision
+func goldenValue(dataType constant.TSDataType, i int) interface{} {
+	switch dataType {
+	case constant.BOOLEAN:
+		return i%3 == 0 || (i > 200 && i < 300)
+	case constant.INT32:
+		return int32(i*7 - 3000)
+	case constant.INT64:
+		return int64(i) * int64(i) * 1000
+	case constant.FLOAT:
+		return float32(i) / 4
+	case constant.DOUBLE:
+		return float64(i)/4 - 100
+	default:
+		return "v" + strconv.Itoa(i%17)
+	}
+}
+
+func writeGoldenCase(c goldenCase, path string) error {
+	writer, err := tsFileWriter.NewTsFileWriter(path)
+	if err != nil {
+		return err
+	}
+	// the version the Java TsFile v0.8 reads
+	if err := writer.SetVersion(metadata.VERSION_3); err != nil {
+		return err
+	}
+	des, _ := sensorDescriptor.NewWithCompress("s0", c.dataType, c.encoding, c.compression)
+	if err := writer.AddSensor(des); err != nil {
+		return err
+	}
+	for i := 0; i < goldenPointNum; i++ {
+		record, _ := tsFileWriter.NewTsRecordUseTimestamp(int64(i), "root.d0")
+		var pt *tsFileWriter.DataPoint
+		switch v := goldenValue(c.dataType, i).(type) {
+		case bool:
+			pt, _ = tsFileWriter.NewBool("s0", c.dataType, v)
+		case int32:
+			pt, _ = tsFileWriter.NewInt("s0", c.dataType, v)
+		case int64:
+			pt, _ = tsFileWriter.NewLong("s0", c.dataType, v)
+		case float32:
+			pt, _ = tsFileWriter.NewFloat("s0", c.dataType, v)
+		case float64:
+			pt, _ = tsFileWriter.NewDouble("s0", c.dataType, v)
+		case string:
+			pt, _ = tsFileWriter.NewString("s0", c.dataType, v)
+		}
+		record.AddTuple(pt)
+		writer.Write(record)
+	}
+	if !writer.Close() {
+		return fmt.Errorf("cannot close %s", path)
+	}
+	return nil
+}
+
+// checkGoldenFile reads the file sequentially and through the Engine, it
+// returns where its footer starts
+func checkGoldenFile(c goldenCase, path string, t *testing.T) int64 {
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	engine := new(Engine)
+	engine.Open(f)
+	defer engine.Close()
+	if f.ReadHeadMagic() != conf.MAGIC_STRING || f.ReadTailMagic() != conf.MAGIC_STRING {
+		t.Fatalf("%s: expected the magic %s", path, conf.MAGIC_STRING)
+	}
+	fileMetadata := f.ReadFileMetadata()
+	if dataType := fileMetadata.GetDataType("root.d0", "s0"); dataType != c.dataType {
+		t.Fatalf("%s: expected root.d0.s0 of data type %d, got %d", path, c.dataType, dataType)
+	}
+	pointNum := int64(0)
+	for f.HasNextRowGroup() {
+		rowGroupHeader := f.ReadRowGroupHeader()
+		for i := 0; i < int(rowGroupHeader.GetNumberOfChunks()); i++ {
+			chunkHeader := f.ReadChunkHeader()
+			if chunkHeader.GetEncodingType() != c.encoding || chunkHeader.GetCompressionType() != c.compression {
+				t.Fatalf("%s: unexpected chunk header %v", path, chunkHeader)
+			}
+			for j := 0; j < chunkHeader.GetNumberOfPages(); j++ {
+				pageHeader := f.ReadPageHeader(chunkHeader.GetDataType())
+				f.ReadPage(pageHeader, chunkHeader.GetCompressionType())
+				pointNum += int64(pageHeader.GetNumberOfValues())
+			}
+		}
+	}
+	if pointNum != goldenPointNum {
+		t.Fatalf("%s: expected %d points in the pages, got %d", path, goldenPointNum, pointNum)
+	}
+
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0"})
+	dataSet := engine.Query(exp)
+	cnt := 0
+	for dataSet.HasNext() {
+		record, err := dataSet.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record.Timestamp() != int64(cnt) || record.Values()[0] != goldenValue(c.dataType, cnt) {
+			t.Fatalf("%s: expected [%d, %v] got %v", path, cnt, goldenValue(c.dataType, cnt), record)
+		}
+		cnt++
+	}
+	if cnt != goldenPointNum {
+		t.Fatalf("%s: expected %d rows got %d", path, goldenPointNum, cnt)
+	}
+	return f.MetadataPos()
+}
+
+func TestJavaCompatibility(t *testing.T) {
+	goldenFilePath := "temp_golden_TsFile"
+	defer os.Remove(goldenFilePath)
+
+	var missing []string
+	for _, c := range goldenCases() {
+		os.Remove(goldenFilePath)
+		if err := writeGoldenCase(c, goldenFilePath); err != nil {
+			t.Fatalf("%s: %v", c.name(), err)
+		}
+		footerPos := checkGoldenFile(c, goldenFilePath, t)
+
+		javaFilePath := filepath.Join(goldenDir, c.name())
+		if _, err := os.Stat(javaFilePath); err != nil {
+			missing = append(missing, c.name())
+			continue
+		}
+		javaFooterPos := checkGoldenFile(c, javaFilePath, t)
+		// the footers differ, e.g. in the createdBy
+		written, _ := os.ReadFile(goldenFilePath)
+		java, _ := os.ReadFile(javaFilePath)
+		if !bytes.Equal(written[:footerPos], java[:javaFooterPos]) {
+			t.Fatalf("%s: the row groups written differ from those of the Java TsFile", c.name())
+		}
+	}
+	if len(missing) > 0 {
+		t.Fatalf("%d golden files of the Java TsFile missing in %s, generate them with generate.sh: %v",
+			len(missing), goldenDir, missing)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"tsfile/common/constant"
 	"tsfile/file/header"
 	"tsfile/file/metadata"
+	"tsfile/timeseries/filter"
 	"tsfile/timeseries/query"
 	"tsfile/timeseries/query/dataset"
 	impl2 "tsfile/timeseries/query/dataset/impl"
@@ -41,11 +42,28 @@ func (e *Engine) decideQuerySet(exp *query.QueryExpression) dataset.IQueryDataSe
 	if len(exp.ConditionPaths()) == 0 {
 		exp.SetConditionPaths(exp.SelectPaths())
 	}
+	// the rows the filter tests need the series it reads
+	conditionPaths := append([]string(nil), exp.ConditionPaths()...)
+	for _, path := range filter.SeriesOf(exp.Filter()) {
+		if !contains(conditionPaths, path) {
+			conditionPaths = append(conditionPaths, path)
+		}
+	}
+	exp.SetConditionPaths(conditionPaths)
 	selectReaderMap := e.constructSeekableReaderMap(exp)
 	conditionReaderMap := e.consturctReaderMapFromPaths(exp.ConditionPaths())
 	return impl2.NewTimestampQueryDataSet(exp.SelectPaths(), exp.ConditionPaths(), selectReaderMap, conditionReaderMap, exp.Filter())
 }
 
+func contains(paths []string, path string) bool {
+	for _, p := range paths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
 func (e *Engine) consturctReaderMapFromPaths(paths []string) map[string]reader.TimeValuePairReader {
 	readerMap := make(map[string]reader.TimeValuePairReader)
 	for _, path := range paths {
@@ -81,38 +99,48 @@ func (e *Engine) constructSeekableReaderMap(exp *query.QueryExpression) map[stri
 }
 
 func (e *Engine) constructReader(path string) reader.TimeValuePairReader {
-	dataType, encoding, offsets, sizes, _ := e.getPageInfo(path, false)
-	return basic.NewSeriesReader(offsets, sizes, e.reader, dataType, encoding)
+	dataType, pages, _ := e.getPageInfo(path, false)
+	return basic.NewSeriesReader(pages.offsets, pages.sizes, e.reader, dataType, pages.encodings, pages.timeEncodings, pages.compressions)
 }
 
 func (e *Engine) constructSeekableReader(path string) reader.ISeekableTimeValuePairReader {
-	dataType, encoding, offsets, sizes, headers := e.getPageInfo(path, true)
-	return seek.NewSeekableSeriesReader(offsets, sizes, e.reader, headers, dataType, encoding)
+	dataType, pages, headers := e.getPageInfo(path, true)
+	return seek.NewSeekableSeriesReader(pages.offsets, pages.sizes, e.reader, headers, dataType, pages.encodings, pages.timeEncodings, pages.compressions)
 }
 
-func (e *Engine) getPageInfo(path string, needHeader bool) (dataType constant.TSDataType, encoding constant.TSEncoding,
-	offsets []int64, sizes []int, pageHeaders []*header.PageHeader) {
+// pageInfo holds the position of every page of a series and how it is
+// written, the chunks of a series may be encoded or compressed differently
+type pageInfo struct {
+	offsets       []int64
+	sizes         []int
+	encodings     []constant.TSEncoding
+	timeEncodings []constant.TSEncoding
+	compressions  []constant.CompressionType
+}
+
+func (e *Engine) getPageInfo(path string, needHeader bool) (dataType constant.TSDataType, pages *pageInfo, pageHeaders []*header.PageHeader) {
 	pathSplits := strings.Split(path, constant.PATH_SEPARATOR)
 	pathLevelLen := len(pathSplits)
 	if pathLevelLen < 2 {
-		log.Println(fmt.Println("Invalid path : %s", path))
-		return 0, 0, nil, nil, nil
+		log.Println(fmt.Sprintf("Invalid path : %s", path))
+		return 0, new(pageInfo), nil
 	}
 	deviceId := strings.Join(pathSplits[0:pathLevelLen-1], constant.PATH_SEPARATOR)
 	sensorId := pathSplits[pathLevelLen-1]
 
-	dataType = e.getDataType(sensorId)
+	dataType = e.fileMeta.GetDataType(deviceId, sensorId)
 	if dataType == constant.INVALID {
 		log.Println(fmt.Sprintf("No such timeseries in this file : %s", path))
-		return 0, 0, nil, nil, nil
+		return 0, new(pageInfo), nil
 	}
 
 	deviceMeta, ok := e.fileMeta.DeviceMap()[deviceId]
 	if !ok {
 		log.Println(fmt.Sprintf("No such timeseries in this file : %s", path))
-		return 0, 0, nil, nil, nil
+		return 0, new(pageInfo), nil
 	}
 
+	pages = new(pageInfo)
 	var headers []*header.PageHeader
 	// find the offsets, sizes and headers(optional) of all pages of this path
 	for ele, i := deviceMeta.GetRowGroups(), 0; i < len(ele); i++ {
@@ -123,12 +151,14 @@ func (e *Engine) getPageInfo(path string, needHeader bool) (dataType constant.TS
 				continue
 			}
 			chunkHeader := e.reader.ReadChunkHeaderAt(chunkMeta.FileOffsetOfCorrespondingData())
-			encoding = chunkHeader.GetEncodingType()
 			pos := e.reader.Pos()
 			for i := 0; i < chunkHeader.GetNumberOfPages(); i++ {
 				pageHeader := e.reader.ReadPageHeaderAt(dataType, pos)
-				offsets = append(offsets, e.reader.Pos())
-				sizes = append(sizes, int(pageHeader.GetCompressedSize()))
+				pages.offsets = append(pages.offsets, e.reader.Pos())
+				pages.sizes = append(pages.sizes, int(pageHeader.GetCompressedSize()))
+				pages.encodings = append(pages.encodings, chunkHeader.GetEncodingType())
+				pages.timeEncodings = append(pages.timeEncodings, chunkHeader.GetTimeEncodingType())
+				pages.compressions = append(pages.compressions, chunkHeader.GetCompressionType())
 				pos = e.reader.Pos() + int64(pageHeader.GetCompressedSize())
 				if needHeader {
 					headers = append(headers, pageHeader)
@@ -136,12 +166,5 @@ func (e *Engine) getPageInfo(path string, needHeader bool) (dataType constant.TS
 			}
 		}
 	}
-	return dataType, encoding, offsets, sizes, headers
-}
-
-func (e *Engine) getDataType(path string) constant.TSDataType {
-	if tsMeta, ok := e.fileMeta.TimeSeriesMetadataMap()[path]; ok {
-		return tsMeta.DataType()
-	}
-	return constant.INVALID
+	return dataType, pages, headers
 }
@@ -1,7 +1,13 @@
 package engine
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"tsfile/timeseries/filter"
 	"tsfile/timeseries/filter/operator"
@@ -10,6 +16,10 @@ import (
 	"tsfile/timeseries/write/tsFileWriter"
 	"tsfile/timeseries/write/sensorDescriptor"
 	"tsfile/common/constant"
+	"tsfile/common/utils"
+	"tsfile/encoding"
+	"tsfile/encoding/decoder"
+	"tsfile/encoding/encoder"
 	"errors"
 )
 
@@ -44,19 +54,19 @@ func prepareTsFile() (err error) {
 		record, _ := tsFileWriter.NewTsRecordUseTimestamp(t, "root.d0")
 		pt, _ := tsFileWriter.NewInt("s0", constant.INT32, d0s0_val[i])
 		record.AddTuple(pt)
-		writer.Write(*record)
+		writer.Write(record)
 	}
 	for i, t := range d0s1_time {
 		record, _ := tsFileWriter.NewTsRecordUseTimestamp(t, "root.d0")
 		pt, _ := tsFileWriter.NewInt("s1", constant.INT32, d0s1_val[i])
 		record.AddTuple(pt)
-		writer.Write(*record)
+		writer.Write(record)
 	}
 	for i, t := range d1s0_time {
 		record, _ := tsFileWriter.NewTsRecordUseTimestamp(t, "root.d1")
 		pt, _ := tsFileWriter.NewInt("s0", constant.INT32, d1s0_val[i])
 		record.AddTuple(pt)
-		writer.Write(*record)
+		writer.Write(record)
 	}
 
 	if !writer.Close() {
@@ -73,12 +83,15 @@ func TestEngine(t *testing.T) {
 	}
 
 	f := new(read.TsFileSequenceReader)
-	f.Open(tempFilePath)
+	if err := f.Open(tempFilePath); err != nil {
+		t.Fatal(err)
+	}
 	engine := new(Engine)
 	engine.Open(f)
 	defer func() {
 		engine.Close()
 		f.Close()
+		os.Remove(tempFilePath)
 	}()
 
 	// test a non-existing series
@@ -109,7 +122,7 @@ func TestEngine(t *testing.T) {
 
 	// test an existing series but no value satisfies the given condition
 	paths = []string{series[0]}
-	var filt filter.Filter = filter.NewRowRecordValFilter(series[0], &operator.IntGtFilter{5})
+	var filt filter.Filter = filter.NewRowRecordValFilter(series[0], &operator.IntGtFilter{Ref: 5})
 	exp.SetSelectPaths(paths)
 	exp.SetFilter(filt)
 	dataSet = engine.Query(exp)
@@ -119,7 +132,7 @@ func TestEngine(t *testing.T) {
 
 	// test an existing series with some satisfying values
 	paths = []string{series[0]}
-	filt = filter.NewRowRecordValFilter(series[0], &operator.IntLtEqFilter{3})
+	filt = filter.NewRowRecordValFilter(series[0], &operator.IntLtEqFilter{Ref: 3})
 	exp.SetSelectPaths(paths)
 	exp.SetFilter(filt)
 	dataSet = engine.Query(exp)
@@ -145,9 +158,9 @@ func TestEngine(t *testing.T) {
 	dataSet = engine.Query(exp)
 	cnt = int32(0)
 	var s0Vals []interface{}
-	s0Vals = append(s0Vals, 1, 2, 3, 4, 5, nil)
+	s0Vals = append(s0Vals, int32(1), int32(2), int32(3), int32(4), int32(5), nil)
 	var s1Vals []interface{}
-	s1Vals = append(s1Vals,5, 4, nil, 3, 2, 1 )
+	s1Vals = append(s1Vals,int32(5), int32(4), nil, int32(3), int32(2), int32(1))
 	for dataSet.HasNext() {
 		record, err := dataSet.Next()
 		if err != nil {
@@ -155,8 +168,8 @@ func TestEngine(t *testing.T) {
 		}
 		checkPath(paths, record.Paths(), t)
 		if record.Timestamp() != int64(cnt+1) ||
-			record.Values()[0].(int32) != s0Vals[cnt] ||
-			record.Values()[1].(int32) != s1Vals[cnt] {
+			record.Values()[0] != s0Vals[cnt] ||
+			record.Values()[1] != s1Vals[cnt] {
 			t.Fatal(fmt.Sprintf("Expected [%d, %d, %d] got %v", cnt+1, s0Vals[cnt], s1Vals[cnt], record))
 		}
 		cnt++
@@ -164,7 +177,7 @@ func TestEngine(t *testing.T) {
 
 	// test selecting multiple series with conditions that can't be satisfied
 	paths = []string{series[0], series[1]}
-	filt = &filter.RowRecordTimeFilter{&operator.LongGtEqFilter{10}}
+	filt = &filter.RowRecordTimeFilter{Filter: &operator.LongGtEqFilter{Ref: 10}}
 	exp.SetSelectPaths(paths)
 	exp.SetFilter(filt)
 	dataSet = engine.Query(exp)
@@ -175,16 +188,16 @@ func TestEngine(t *testing.T) {
 	// test selecting multiple series with satisfiable conditions
 	// and the condition path is among the select paths
 	paths = []string{series[0], series[1]}
-	filt = filter.NewRowRecordValFilter(series[0], &operator.IntGtEqFilter{4})
+	filt = filter.NewRowRecordValFilter(series[0], &operator.IntGtEqFilter{Ref: 4})
 	exp.SetSelectPaths(paths)
 	exp.SetConditionPaths([]string{series[0]})
 	exp.SetFilter(filt)
 	dataSet = engine.Query(exp)
 	cnt = int32(0)
 	s0Vals = nil
-	s0Vals = append(s0Vals, 4, 5)
+	s0Vals = append(s0Vals, int32(4), int32(5))
 	s1Vals = nil
-	s1Vals = append(s1Vals, 3, 2)
+	s1Vals = append(s1Vals, int32(3), int32(2))
 	for dataSet.HasNext() {
 		record, err := dataSet.Next()
 		if err != nil {
@@ -192,8 +205,8 @@ func TestEngine(t *testing.T) {
 		}
 		checkPath(paths, record.Paths(), t)
 		if record.Timestamp() != int64(cnt+4) ||
-			record.Values()[0].(int32) != s0Vals[cnt] ||
-			record.Values()[1].(int32) != s1Vals[cnt] {
+			record.Values()[0] != s0Vals[cnt] ||
+			record.Values()[1] != s1Vals[cnt] {
 			t.Fatal(fmt.Sprintf("Expected [%d, %d, %d] got %v", cnt+4, s0Vals[cnt], s1Vals[cnt], record))
 		}
 		cnt++
@@ -202,16 +215,16 @@ func TestEngine(t *testing.T) {
 	// test selecting multiple series with satisfiable conditions
 	// and the condition path is outside the select paths
 	paths = []string{series[0], series[1]}
-	filt = filter.NewRowRecordValFilter(series[2], &operator.IntGtEqFilter{4})
+	filt = filter.NewRowRecordValFilter(series[2], &operator.IntGtEqFilter{Ref: 4})
 	exp.SetConditionPaths([]string{series[2]})
 	exp.SetSelectPaths(paths)
 	exp.SetFilter(filt)
 	dataSet = engine.Query(exp)
 	cnt = int32(0)
 	s0Vals = nil
-	s0Vals = append(s0Vals, 4, 5)
+	s0Vals = append(s0Vals, int32(4), int32(5))
 	s1Vals = nil
-	s1Vals = append(s1Vals, 3, 2)
+	s1Vals = append(s1Vals, int32(3), int32(2))
 	for dataSet.HasNext() {
 		record, err := dataSet.Next()
 		if err != nil {
@@ -219,8 +232,8 @@ func TestEngine(t *testing.T) {
 		}
 		checkPath(paths, record.Paths(), t)
 		if record.Timestamp() != int64(cnt+4) ||
-			record.Values()[0].(int32) != s0Vals[cnt] ||
-			record.Values()[1].(int32) != s1Vals[cnt] {
+			record.Values()[0] != s0Vals[cnt] ||
+			record.Values()[1] != s1Vals[cnt] {
 			t.Fatal(fmt.Sprintf("Expected [%d, %d, %d] got %v", cnt+4, s0Vals[cnt], s1Vals[cnt], record))
 		}
 		cnt++
@@ -229,8 +242,8 @@ func TestEngine(t *testing.T) {
 	// test selecting multiple series with satisfiable conditions
 	// and the condition paths share some common paths with the select paths
 	paths = []string{series[0], series[1]}
-	filt = &operator.AndFilter{[]filter.Filter{filter.NewRowRecordValFilter(series[2], &operator.IntGtEqFilter{4}),
-		filter.NewRowRecordValFilter(series[1], &operator.IntGtEqFilter{3})}}
+	filt = &operator.AndFilter{Filters: []filter.Filter{filter.NewRowRecordValFilter(series[2], &operator.IntGtEqFilter{Ref: 4}),
+		filter.NewRowRecordValFilter(series[1], &operator.IntGtEqFilter{Ref: 3})}}
 
 	exp.SetConditionPaths([]string{series[2]})
 	exp.SetSelectPaths(paths)
@@ -238,9 +251,9 @@ func TestEngine(t *testing.T) {
 	dataSet = engine.Query(exp)
 	cnt = int32(0)
 	s0Vals = nil
-	s0Vals = append(s0Vals, 4)
+	s0Vals = append(s0Vals, int32(4))
 	s1Vals = nil
-	s1Vals = append(s1Vals, 3)
+	s1Vals = append(s1Vals, int32(3))
 	for dataSet.HasNext() {
 		record, err := dataSet.Next()
 		if err != nil {
@@ -248,8 +261,8 @@ func TestEngine(t *testing.T) {
 		}
 		checkPath(paths, record.Paths(), t)
 		if record.Timestamp() != int64(cnt+4) ||
-			record.Values()[0].(int32) != s0Vals[cnt] ||
-			record.Values()[1].(int32) != s1Vals[cnt] {
+			record.Values()[0] != s0Vals[cnt] ||
+			record.Values()[1] != s1Vals[cnt] {
 			t.Fatal(fmt.Sprintf("Expected [%d, %d, %d] got %v", cnt+4, s0Vals[cnt], s1Vals[cnt], record))
 		}
 		cnt++
@@ -266,3 +279,546 @@ func checkPath(pathA []string, pathB []string, t *testing.T) {
 		}
 	}
 }
+
+func TestEngineRleLongs(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "rle_TsFile")
+	writer, err := tsFileWriter.NewTsFileWriter(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	des, _ := sensorDescriptor.New("s0", constant.INT64, constant.RLE)
+	writer.AddSensor(des)
+	// bit packed runs of several widths, and repeated runs
+	valueAt := func(i int) int64 {
+		if i > 50 {
+			return 1 << 40
+		}
+		return int64(i*i) << uint(i%40)
+	}
+	for i := 1; i <= 100; i++ {
+		record, _ := tsFileWriter.NewTsRecordUseTimestamp(int64(i), "root.d0")
+		pt, _ := tsFileWriter.NewLong("s0", constant.INT64, valueAt(i))
+		record.AddTuple(pt)
+		writer.Write(record)
+	}
+	if !writer.Close() {
+		t.Fatal("Cannot close the the TsFile")
+	}
+
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(file); err != nil {
+		t.Fatal(err)
+	}
+	engine := new(Engine)
+	engine.Open(f)
+	defer engine.Close()
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0"})
+	dataSet := engine.Query(exp)
+	cnt := 0
+	for dataSet.HasNext() {
+		record, err := dataSet.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cnt++
+		if record.Timestamp() != int64(cnt) || record.Values()[0] != valueAt(cnt) {
+			t.Fatal(fmt.Sprintf("Expected [%d, %d] got %v", cnt, valueAt(cnt), record))
+		}
+	}
+	if cnt != 100 {
+		t.Fatal(fmt.Sprintf("Expected 100 rows got %d", cnt))
+	}
+}
+
+func TestEngineChunkEncodings(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "chunk_encodings_TsFile")
+	writer, err := tsFileWriter.NewTsFileWriter(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s0, _ := sensorDescriptor.New("s0", constant.INT64, constant.TS_2DIFF)
+	s1, _ := sensorDescriptor.New("s1", constant.INT64, constant.PLAIN)
+	writer.AddSensor(s0)
+	writer.AddSensor(s1)
+	write := func(from int, to int) {
+		for i := from; i < to; i++ {
+			record, _ := tsFileWriter.NewTsRecordUseTimestamp(int64(i), "root.d0")
+			pt, _ := tsFileWriter.NewLong("s0", constant.INT64, int64(i*3))
+			record.AddTuple(pt)
+			pt, _ = tsFileWriter.NewLong("s1", constant.INT64, int64(i))
+			record.AddTuple(pt)
+			writer.Write(record)
+		}
+	}
+	// the chunks of the second row group have other time encodings
+	write(0, 100)
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	s0.SetTimeEncoding(constant.PLAIN)
+	s1.SetTimeEncoding(constant.PLAIN)
+	write(100, 200)
+	if !writer.Close() {
+		t.Fatal("Cannot close the the TsFile")
+	}
+
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(file); err != nil {
+		t.Fatal(err)
+	}
+	engine := new(Engine)
+	engine.Open(f)
+	defer engine.Close()
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0"})
+	dataSet := engine.Query(exp)
+	cnt := 0
+	for dataSet.HasNext() {
+		record, err := dataSet.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record.Timestamp() != int64(cnt) || record.Values()[0] != int64(cnt*3) {
+			t.Fatal(fmt.Sprintf("Expected [%d, %d] got %v", cnt, cnt*3, record))
+		}
+		cnt++
+	}
+	if cnt != 200 {
+		t.Fatal(fmt.Sprintf("Expected 200 rows got %d", cnt))
+	}
+
+	// the seekable readers of the selected series
+	exp.SetSelectPaths([]string{"root.d0.s0"})
+	exp.SetConditionPaths([]string{"root.d0.s1"})
+	exp.SetFilter(filter.NewRowRecordValFilter("root.d0.s1", &operator.LongGtEqFilter{Ref: 50}))
+	dataSet = engine.Query(exp)
+	cnt = 50
+	for dataSet.HasNext() {
+		record, err := dataSet.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record.Timestamp() != int64(cnt) || record.Values()[0] != int64(cnt*3) {
+			t.Fatal(fmt.Sprintf("Expected [%d, %d] got %v", cnt, cnt*3, record))
+		}
+		cnt++
+	}
+	if cnt != 200 {
+		t.Fatal(fmt.Sprintf("Expected rows up to 200 got %d", cnt))
+	}
+}
+
+func TestEngineMergedEncodings(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{filepath.Join(dir, "plain_TsFile"), filepath.Join(dir, "diff_TsFile")}
+	merged := filepath.Join(dir, "merged_TsFile")
+	for i, encoding := range []constant.TSEncoding{constant.PLAIN, constant.TS_2DIFF} {
+		writer, err := tsFileWriter.NewTsFileWriter(files[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		des, _ := sensorDescriptor.New("s0", constant.INT64, encoding)
+		writer.AddSensor(des)
+		for j := i * 100; j < i*100+100; j++ {
+			record, _ := tsFileWriter.NewTsRecordUseTimestamp(int64(j), "root.d0")
+			pt, _ := tsFileWriter.NewLong("s0", constant.INT64, int64(j*j))
+			record.AddTuple(pt)
+			writer.Write(record)
+		}
+		if !writer.Close() {
+			t.Fatal("Cannot close the the TsFile")
+		}
+	}
+	// the chunk of the first file is not written like the series, which has
+	// the encoding of the latest file
+	report, err := tsFileWriter.MergeTsFiles(files, merged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.CopiedChunks != 1 || report.RewrittenChunks != 1 {
+		t.Fatalf("expected the PLAIN chunk encoded again, got %+v", report)
+	}
+	if problems := read.Check(merged); len(problems) != 0 {
+		t.Fatalf("expected a valid file, got %v", problems)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(merged); err != nil {
+		t.Fatal(err)
+	}
+	engine := new(Engine)
+	engine.Open(f)
+	defer engine.Close()
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0"})
+	dataSet := engine.Query(exp)
+	cnt := 0
+	for dataSet.HasNext() {
+		record, err := dataSet.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record.Timestamp() != int64(cnt) || record.Values()[0] != int64(cnt*cnt) {
+			t.Fatal(fmt.Sprintf("Expected [%d, %d] got %v", cnt, cnt*cnt, record))
+		}
+		cnt++
+	}
+	if cnt != 200 {
+		t.Fatal(fmt.Sprintf("Expected 200 rows got %d", cnt))
+	}
+}
+
+func TestEngineEncodings(t *testing.T) {
+	encodingFilePath := filepath.Join(t.TempDir(), "encoding_TsFile")
+
+	writer, err := tsFileWriter.NewTsFileWriter(encodingFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	des, _ := sensorDescriptor.New("rle", constant.BOOLEAN, constant.RLE)
+	writer.AddSensor(des)
+	des, _ = sensorDescriptor.New("bitmap", constant.BOOLEAN, constant.BITMAP)
+	writer.AddSensor(des)
+	des, _ = sensorDescriptor.New("enum", constant.INT32, constant.BITMAP)
+	writer.AddSensor(des)
+	des, _ = sensorDescriptor.New("auto", constant.INT32, constant.AUTO)
+	writer.AddSensor(des)
+
+	// runs longer and shorter than a rle run, enum values out of the varint byte
+	boolAt := func(i int) bool { return i%3 == 0 || (i > 20 && i < 60) }
+	enumAt := func(i int) int32 { return []int32{-1, 7, 300}[i%3] }
+	for i := 1; i <= 100; i++ {
+		record, _ := tsFileWriter.NewTsRecordUseTimestamp(int64(i), "root.d0")
+		pt, _ := tsFileWriter.NewBool("rle", constant.BOOLEAN, boolAt(i))
+		record.AddTuple(pt)
+		pt, _ = tsFileWriter.NewBool("bitmap", constant.BOOLEAN, boolAt(i))
+		record.AddTuple(pt)
+		pt, _ = tsFileWriter.NewInt("enum", constant.INT32, enumAt(i))
+		record.AddTuple(pt)
+		pt, _ = tsFileWriter.NewInt("auto", constant.INT32, enumAt(i))
+		record.AddTuple(pt)
+		writer.Write(record)
+	}
+	if !writer.Close() {
+		t.Fatal("Cannot close the the TsFile")
+	}
+
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(encodingFilePath); err != nil {
+		t.Fatal(err)
+	}
+	engine := new(Engine)
+	engine.Open(f)
+	defer engine.Close()
+
+	paths := []string{"root.d0.rle", "root.d0.bitmap", "root.d0.enum", "root.d0.auto"}
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths(paths)
+	dataSet := engine.Query(exp)
+	cnt := 0
+	for dataSet.HasNext() {
+		record, err := dataSet.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cnt++
+		if record.Timestamp() != int64(cnt) || record.Values()[0] != boolAt(cnt) ||
+			record.Values()[1] != boolAt(cnt) || record.Values()[2] != enumAt(cnt) || record.Values()[3] != enumAt(cnt) {
+			t.Fatal(fmt.Sprintf("Expected [%d, %v, %v, %d, %d] got %v", cnt, boolAt(cnt), boolAt(cnt), enumAt(cnt), enumAt(cnt), record))
+		}
+	}
+	if cnt != 100 {
+		t.Fatal(fmt.Sprintf("Expected 100 rows got %d", cnt))
+	}
+}
+
+func TestEngineGorilla(t *testing.T) {
+	gorillaFilePath := filepath.Join(t.TempDir(), "gorilla_TsFile")
+
+	writer, err := tsFileWriter.NewTsFileWriter(gorillaFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	des, _ := sensorDescriptor.New("int", constant.INT32, constant.GORILLA)
+	des.SetTimeEncoding(constant.GORILLA_TS)
+	writer.AddSensor(des)
+	des, _ = sensorDescriptor.New("long", constant.INT64, constant.GORILLA)
+	des.SetTimeEncoding(constant.GORILLA_TS)
+	writer.AddSensor(des)
+
+	// regular intervals with jitter and jumps of every delta of delta range,
+	// values repeating, close to and far from the previous one
+	times := make([]int64, 0)
+	timestamp := int64(1000)
+	for i := 0; i < 200; i++ {
+		timestamp += 10 + []int64{0, 0, 50, -5, 200, 2000, 1 << 40}[i%7]
+		times = append(times, timestamp)
+	}
+	intAt := func(i int) int32 { return []int32{5, 5, 6, -7, 1 << 30, 1<<30 + 1}[i%6] }
+	longAt := func(i int) int64 { return []int64{5, -1, 1 << 62, 1<<62 + 8, 0}[i%5] * int64(i) }
+	for i, timestamp := range times {
+		record, _ := tsFileWriter.NewTsRecordUseTimestamp(timestamp, "root.d0")
+		pt, _ := tsFileWriter.NewInt("int", constant.INT32, intAt(i))
+		record.AddTuple(pt)
+		pt, _ = tsFileWriter.NewLong("long", constant.INT64, longAt(i))
+		record.AddTuple(pt)
+		writer.Write(record)
+	}
+	if !writer.Close() {
+		t.Fatal("Cannot close the the TsFile")
+	}
+
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(gorillaFilePath); err != nil {
+		t.Fatal(err)
+	}
+	engine := new(Engine)
+	engine.Open(f)
+	defer engine.Close()
+
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.int", "root.d0.long"})
+	dataSet := engine.Query(exp)
+	cnt := 0
+	for dataSet.HasNext() {
+		record, err := dataSet.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record.Timestamp() != times[cnt] || record.Values()[0] != intAt(cnt) || record.Values()[1] != longAt(cnt) {
+			t.Fatal(fmt.Sprintf("Expected [%d, %d, %d] got %v", times[cnt], intAt(cnt), longAt(cnt), record))
+		}
+		cnt++
+	}
+	if cnt != len(times) {
+		t.Fatal(fmt.Sprintf("Expected %d rows got %d", len(times), cnt))
+	}
+}
+
+// quantizedEncoder keeps two digits of a double in an int16
+type quantizedEncoder struct{}
+
+func (q *quantizedEncoder) Encode(value interface{}, buffer *bytes.Buffer) {
+	binary.Write(buffer, binary.BigEndian, int16(math.Round(value.(float64)*100)))
+}
+
+func (q *quantizedEncoder) Flush(buffer *bytes.Buffer) {}
+
+func (q *quantizedEncoder) GetOneItemMaxSize() int { return 2 }
+
+func (q *quantizedEncoder) GetMaxByteSize() int64 { return 0 }
+
+type quantizedDecoder struct {
+	reader *utils.BytesReader
+}
+
+func (q *quantizedDecoder) Init(data []byte) { q.reader = utils.NewBytesReader(data) }
+
+func (q *quantizedDecoder) HasNext() bool { return q.reader.Len() > 0 }
+
+func (q *quantizedDecoder) Next() interface{} { return float64(q.reader.ReadShort()) / 100 }
+
+func TestEngineCustomEncoding(t *testing.T) {
+	newEncoder := func(constant.TSDataType) encoder.Encoder { return &quantizedEncoder{} }
+	newDecoder := func(constant.TSDataType) decoder.Decoder { return &quantizedDecoder{} }
+	if encoding.Register("QUANTIZED", constant.PLAIN, newEncoder, newDecoder, constant.DOUBLE) == nil {
+		t.Fatal("Built-in encodings should not be replaced")
+	}
+	if err := encoding.Register("QUANTIZED", 100, newEncoder, newDecoder, constant.DOUBLE); err != nil {
+		t.Fatal(err)
+	}
+
+	customFilePath := filepath.Join(t.TempDir(), "custom_TsFile")
+
+	writer, err := tsFileWriter.NewTsFileWriter(customFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	des, _ := sensorDescriptor.New("vibration", constant.DOUBLE, constant.GetEncodingByName("QUANTIZED"))
+	writer.AddSensor(des)
+	valueAt := func(i int) float64 { return float64(i%50-25) / 4 }
+	for i := 1; i <= 100; i++ {
+		record, _ := tsFileWriter.NewTsRecordUseTimestamp(int64(i), "root.d0")
+		pt, _ := tsFileWriter.NewDouble("vibration", constant.DOUBLE, valueAt(i))
+		record.AddTuple(pt)
+		writer.Write(record)
+	}
+	if !writer.Close() {
+		t.Fatal("Cannot close the the TsFile")
+	}
+
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(customFilePath); err != nil {
+		t.Fatal(err)
+	}
+	engine := new(Engine)
+	engine.Open(f)
+	defer engine.Close()
+
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.vibration"})
+	dataSet := engine.Query(exp)
+	cnt := 0
+	for dataSet.HasNext() {
+		record, err := dataSet.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cnt++
+		if record.Timestamp() != int64(cnt) || record.Values()[0] != valueAt(cnt) {
+			t.Fatal(fmt.Sprintf("Expected [%d, %v] got %v", cnt, valueAt(cnt), record))
+		}
+	}
+	if cnt != 100 {
+		t.Fatal(fmt.Sprintf("Expected 100 rows got %d", cnt))
+	}
+}
+
+func TestEngineUnknownCompression(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "unknown_compression_TsFile")
+	writer, err := tsFileWriter.NewTsFileWriter(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	des, _ := sensorDescriptor.NewWithCompress("zipped", constant.INT64, constant.PLAIN, constant.SNAPPY)
+	writer.AddSensor(des)
+	for i := 1; i <= 100; i++ {
+		record, _ := tsFileWriter.NewTsRecordUseTimestamp(int64(i), "root.d0")
+		pt, _ := tsFileWriter.NewLong("zipped", constant.INT64, int64(i))
+		record.AddTuple(pt)
+		writer.Write(record)
+	}
+	if !writer.Close() {
+		t.Fatal("Cannot close the the TsFile")
+	}
+	// the chunk header names a compression type without a decompressor
+	data, _ := os.ReadFile(file)
+	pos := bytes.Index(data, append([]byte{0, 0, 0, 6}, "zipped"...))
+	if pos < 0 {
+		t.Fatal("chunk header not found")
+	}
+	binary.BigEndian.PutUint16(data[pos+4+6+4+2+4:], 99)
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(file); err != nil {
+		t.Fatal(err)
+	}
+	engine := new(Engine)
+	engine.Open(f)
+	defer engine.Close()
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.zipped"})
+	if engine.Query(exp).HasNext() {
+		t.Fatal("expected the series to end at the page that can not be decompressed")
+	}
+	found := false
+	for _, problem := range read.Check(file) {
+		found = found || strings.Contains(problem.String(), "unsupported compression type 99")
+	}
+	if !found {
+		t.Fatal("expected the page to be reported")
+	}
+}
+
+func TestEngineUnknownEncoding(t *testing.T) {
+	// the file of a writer that registered an encoding the reader does not have
+	newEncoder := func(constant.TSDataType) encoder.Encoder { return &quantizedEncoder{} }
+	if err := encoder.RegisterEncoder(101, newEncoder, constant.DOUBLE); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "unknown_TsFile")
+	writer, err := tsFileWriter.NewTsFileWriter(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	des, _ := sensorDescriptor.New("vibration", constant.DOUBLE, 101)
+	writer.AddSensor(des)
+	for i := 1; i <= 100; i++ {
+		record, _ := tsFileWriter.NewTsRecordUseTimestamp(int64(i), "root.d0")
+		pt, _ := tsFileWriter.NewDouble("vibration", constant.DOUBLE, float64(i))
+		record.AddTuple(pt)
+		writer.Write(record)
+	}
+	if !writer.Close() {
+		t.Fatal("Cannot close the the TsFile")
+	}
+
+	if _, err := decoder.CreateDecoder(101, constant.DOUBLE); err == nil {
+		t.Fatal("expected no decoder of the encoding")
+	}
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(file); err != nil {
+		t.Fatal(err)
+	}
+	engine := new(Engine)
+	engine.Open(f)
+	defer engine.Close()
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.vibration"})
+	if engine.Query(exp).HasNext() {
+		t.Fatal("expected the series to end at the page that can not be decoded")
+	}
+	problems := read.Check(file)
+	if len(problems) == 0 || !strings.Contains(problems[0].String(), "unsupported encoding 101") {
+		t.Fatalf("expected the page to be reported, got %v", problems)
+	}
+}
+
+func TestEngineCompression(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "compression_TsFile")
+	writer, err := tsFileWriter.NewTsFileWriter(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressions := []constant.CompressionType{constant.UNCOMPRESSED, constant.SNAPPY, constant.GZIP,
+		constant.LZ4, constant.ZSTD}
+	sensors := []string{"uncompressed", "snappy", "gzip", "lz4", "zstd"}
+	var paths []string
+	for i, compression := range compressions {
+		des, _ := sensorDescriptor.NewWithCompress(sensors[i], constant.INT64, constant.TS_2DIFF, compression)
+		writer.AddSensor(des)
+		paths = append(paths, "root.d0."+sensors[i])
+	}
+	for i := 1; i <= 1000; i++ {
+		record, _ := tsFileWriter.NewTsRecordUseTimestamp(int64(i), "root.d0")
+		for _, sensor := range sensors {
+			pt, _ := tsFileWriter.NewLong(sensor, constant.INT64, int64(i*i))
+			record.AddTuple(pt)
+		}
+		writer.Write(record)
+	}
+	if !writer.Close() {
+		t.Fatal("Cannot close the the TsFile")
+	}
+
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(file); err != nil {
+		t.Fatal(err)
+	}
+	engine := new(Engine)
+	engine.Open(f)
+	defer engine.Close()
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths(paths)
+	dataSet := engine.Query(exp)
+	cnt := 0
+	for dataSet.HasNext() {
+		record, err := dataSet.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cnt++
+		for i, value := range record.Values() {
+			if record.Timestamp() != int64(cnt) || value != int64(cnt*cnt) {
+				t.Fatal(fmt.Sprintf("Expected [%d, %d] of %s got %v", cnt, cnt*cnt, paths[i], record))
+			}
+		}
+	}
+	if cnt != 1000 {
+		t.Fatal(fmt.Sprintf("Expected 1000 rows got %d", cnt))
+	}
+}
@@ -0,0 +1,6 @@
+#!/bin/sh
+# Writes the golden files of TestJavaCompatibility into this directory with the
+# Java TsFile v0.8, needs a JDK 8 or later and Maven.
+set -e
+dir=$(cd "$(dirname "$0")" && pwd)
+mvn -q -f "$dir/generator/pom.xml" compile exec:java -Dexec.args="$dir"
@@ -0,0 +1,86 @@
+import java.io.File;
+
+import org.apache.iotdb.tsfile.file.metadata.enums.CompressionType;
+import org.apache.iotdb.tsfile.file.metadata.enums.TSDataType;
+import org.apache.iotdb.tsfile.file.metadata.enums.TSEncoding;
+import org.apache.iotdb.tsfile.utils.Binary;
+import org.apache.iotdb.tsfile.write.TsFileWriter;
+import org.apache.iotdb.tsfile.write.record.TSRecord;
+import org.apache.iotdb.tsfile.write.record.datapoint.BooleanDataPoint;
+import org.apache.iotdb.tsfile.write.record.datapoint.DataPoint;
+import org.apache.iotdb.tsfile.write.record.datapoint.DoubleDataPoint;
+import org.apache.iotdb.tsfile.write.record.datapoint.FloatDataPoint;
+import org.apache.iotdb.tsfile.write.record.datapoint.IntDataPoint;
+import org.apache.iotdb.tsfile.write.record.datapoint.LongDataPoint;
+import org.apache.iotdb.tsfile.write.record.datapoint.StringDataPoint;
+import org.apache.iotdb.tsfile.write.schema.MeasurementSchema;
+
+/**
+ * Writes the golden files described in README.txt into the directory given as
+ * the single argument, with the default configuration of the TsFile v0.8.
+ */
+public class GenerateGolden {
+
+  private static final int POINT_NUM = 1000;
+
+  private static final TSEncoding[][] ENCODINGS = {
+      {TSEncoding.PLAIN, TSEncoding.RLE},
+      {TSEncoding.PLAIN, TSEncoding.RLE, TSEncoding.TS_2DIFF},
+      {TSEncoding.PLAIN, TSEncoding.RLE, TSEncoding.TS_2DIFF},
+      {TSEncoding.PLAIN, TSEncoding.RLE, TSEncoding.TS_2DIFF, TSEncoding.GORILLA},
+      {TSEncoding.PLAIN, TSEncoding.RLE, TSEncoding.TS_2DIFF, TSEncoding.GORILLA},
+      {TSEncoding.PLAIN},
+  };
+
+  private static final TSDataType[] DATA_TYPES = {TSDataType.BOOLEAN, TSDataType.INT32,
+      TSDataType.INT64, TSDataType.FLOAT, TSDataType.DOUBLE, TSDataType.TEXT};
+
+  private static final CompressionType[] COMPRESSIONS = {CompressionType.UNCOMPRESSED,
+      CompressionType.SNAPPY};
+
+  public static void main(String[] args) throws Exception {
+    File dir = new File(args.length > 0 ? args[0] : ".");
+    for (int t = 0; t < DATA_TYPES.length; t++) {
+      for (TSEncoding encoding : ENCODINGS[t]) {
+        for (CompressionType compression : COMPRESSIONS) {
+          File file = new File(dir, DATA_TYPES[t] + "_" + encoding + "_" + compression + ".tsfile");
+          write(file, DATA_TYPES[t], encoding, compression);
+          System.out.println(file);
+        }
+      }
+    }
+  }
+
+  private static void write(File file, TSDataType dataType, TSEncoding encoding,
+      CompressionType compression) throws Exception {
+    if (file.exists() && !file.delete()) {
+      throw new IllegalStateException("cannot remove " + file);
+    }
+    TsFileWriter writer = new TsFileWriter(file);
+    writer.addMeasurement(new MeasurementSchema("s0", dataType, encoding, compression));
+    for (int i = 0; i < POINT_NUM; i++) {
+      TSRecord record = new TSRecord(i, "root.d0");
+      record.addTuple(point(dataType, i));
+      writer.write(record);
+    }
+    writer.close();
+  }
+
+  // the value of root.d0.s0 at time i, goldenValue in Compatibility_test.go
+  private static DataPoint point(TSDataType dataType, int i) {
+    switch (dataType) {
+      case BOOLEAN:
+        return new BooleanDataPoint("s0", i % 3 == 0 || (i > 200 && i < 300));
+      case INT32:
+        return new IntDataPoint("s0", i * 7 - 3000);
+      case INT64:
+        return new LongDataPoint("s0", (long) i * i * 1000);
+      case FLOAT:
+        return new FloatDataPoint("s0", i / 4f);
+      case DOUBLE:
+        return new DoubleDataPoint("s0", i / 4d - 100);
+      default:
+        return new StringDataPoint("s0", new Binary("v" + i % 17));
+    }
+  }
+}
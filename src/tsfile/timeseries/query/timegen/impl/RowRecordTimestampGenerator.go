@@ -30,7 +30,7 @@ func (gen *RowRecordTimestampGenerator) fetch() {
 	for gen.reader.HasNext() {
 		record, err := gen.reader.Next()
 		if err != nil {
-			log.Error("cannot read next RowRecord", err)
+			log.Error("cannot read next RowRecord: %v", err)
 			gen.exhausted = true
 			gen.currTime = constant.INVALID_TIMESTAMP
 		}
@@ -0,0 +1,504 @@
+package read
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"tsfile/common/conf"
+	"tsfile/common/constant"
+	"tsfile/common/utils"
+	"tsfile/compress"
+	"tsfile/encoding/decoder"
+	"tsfile/file/header"
+	"tsfile/file/metadata"
+	"tsfile/file/metadata/statistics"
+)
+
+// Problem is an inconsistency of a tsfile found by Check
+type Problem struct {
+	// Block is "file", "footer", "row group", "chunk" or "page", the repair
+	// of a file also reports the "bytes" it skipped
+	Block string `json:"block"`
+	// Device and Sensor are empty if the block is before them
+	Device string `json:"device,omitempty"`
+	Sensor string `json:"sensor,omitempty"`
+	// Offset is where the block starts
+	Offset  int64  `json:"offset"`
+	Message string `json:"message"`
+}
+
+func (p *Problem) String() string {
+	msg := p.Block
+	if p.Device != "" {
+		msg += " of " + p.Device
+		if p.Sensor != "" {
+			msg += "." + p.Sensor
+		}
+	}
+	return msg + " at offset " + strconv.FormatInt(p.Offset, 10) + ": " + p.Message
+}
+
+// Check validates the whole file: the magic strings, the checksums, every
+// header against the footer, and every page against its header once decoded.
+// It returns the problems found, none for a valid file. A block that can not be
+// parsed ends the walk through the row groups.
+func Check(file string) []*Problem {
+	c := &checker{file: file}
+	if !c.checkMagic() {
+		return c.problems
+	}
+	c.f = new(TsFileSequenceReader)
+	if err := c.f.Open(file); err != nil {
+		c.report("footer", 0, "%v", err)
+		return c.problems
+	}
+	defer c.f.Close()
+
+	if err := c.f.Verify(); err != nil {
+		if corruption, ok := err.(*CorruptionError); ok {
+			c.problems = append(c.problems, &Problem{Block: corruption.Block, Device: corruption.Device,
+				Sensor: corruption.Sensor, Offset: corruption.Offset, Message: "checksum mismatch"})
+		}
+	}
+	if !c.readFooter() {
+		return c.problems
+	}
+	c.walk()
+	c.checkFooter()
+	c.checkIndex()
+	return c.problems
+}
+
+// chunkInfo is what the walk found of a chunk, for the footer checks
+type chunkInfo struct {
+	device    string
+	sensor    string
+	points    int64
+	startTime int64
+	endTime   int64
+}
+
+type checker struct {
+	file     string
+	f        *TsFileSequenceReader
+	problems []*Problem
+	// the block parsed, the device and sensor of the blocks checked
+	block  string
+	offset int64
+	device string
+	sensor string
+
+	fileMetadata *metadata.FileMetaData
+	// the row groups and chunks of the footer by their offset
+	rowGroups map[int64]*metadata.RowGroupMetaData
+	chunks    map[int64]*metadata.ChunkMetaData
+	// the ones found by the walk
+	walkedRowGroups map[int64]bool
+	walkedChunks    map[int64]*chunkInfo
+}
+
+func (c *checker) report(block string, offset int64, format string, args ...interface{}) {
+	p := &Problem{Block: block, Offset: offset, Message: fmt.Sprintf(format, args...)}
+	if block == "row group" || block == "chunk" || block == "page" {
+		p.Device = c.device
+	}
+	if block == "chunk" || block == "page" {
+		p.Sensor = c.sensor
+	}
+	c.problems = append(c.problems, p)
+}
+
+// checkMagic reads the magic strings without the reader, which refuses to open
+// a file without them
+func (c *checker) checkMagic() bool {
+	fin, err := os.Open(c.file)
+	if err != nil {
+		c.report("file", 0, "%v", err)
+		return false
+	}
+	defer fin.Close()
+	stat, err := fin.Stat()
+	if err != nil {
+		c.report("file", 0, "%v", err)
+		return false
+	}
+	magicLen := int64(len(conf.MAGIC_STRING))
+	if stat.Size() < 2*magicLen+int64(constant.INT_LEN) {
+		c.report("file", 0, "size %d is too small for a tsfile", stat.Size())
+		return false
+	}
+	magic := make([]byte, magicLen)
+	if _, err := fin.ReadAt(magic, 0); err != nil || string(magic) != conf.MAGIC_STRING {
+		c.report("file", 0, "head magic %q, expected %q", magic, conf.MAGIC_STRING)
+	}
+	if _, err := fin.ReadAt(magic, stat.Size()-magicLen); err != nil || string(magic) != conf.MAGIC_STRING {
+		c.report("file", stat.Size()-magicLen, "tail magic %q, expected %q, the file is not sealed", magic, conf.MAGIC_STRING)
+	}
+	return len(c.problems) == 0
+}
+
+func (c *checker) readFooter() (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.report("footer", c.f.MetadataPos(), "cannot be parsed: %v", r)
+			ok = false
+		}
+	}()
+	c.fileMetadata = c.f.ReadFileMetadata()
+	c.rowGroups = make(map[int64]*metadata.RowGroupMetaData)
+	c.chunks = make(map[int64]*metadata.ChunkMetaData)
+	for _, deviceId := range utils.SortedKeys(c.fileMetadata.DeviceMap()) {
+		for _, rowGroup := range c.fileMetadata.DeviceMap()[deviceId].GetRowGroups() {
+			c.rowGroups[rowGroup.GetFileOffsetOfCorrespondingData()] = rowGroup
+			for _, chunk := range rowGroup.GetChunkMetaDataSli() {
+				c.chunks[chunk.FileOffsetOfCorrespondingData()] = chunk
+			}
+		}
+	}
+	return true
+}
+
+// walk reads the row groups one after another, a row group or chunk that does
+// not end where its header says is skipped by the size in the header
+func (c *checker) walk() {
+	c.walkedRowGroups = make(map[int64]bool)
+	c.walkedChunks = make(map[int64]*chunkInfo)
+	defer func() {
+		if r := recover(); r != nil {
+			c.report(c.block, c.offset, "cannot be parsed: %v", r)
+		}
+	}()
+
+	c.f.reader.Seek(int64(len(conf.MAGIC_STRING)), io.SeekStart)
+	for c.f.HasNextRowGroup() {
+		if !c.walkRowGroup() {
+			return
+		}
+	}
+}
+
+func (c *checker) walkRowGroup() bool {
+	offset := c.f.Pos()
+	c.block, c.offset, c.device, c.sensor = "row group", offset, "", ""
+	rowGroupHeader := c.f.ReadRowGroupHeader()
+	c.device = rowGroupHeader.GetDevice()
+	end := offset + rowGroupHeader.GetDataSize()
+	if rowGroupHeader.GetDataSize() <= 0 || end > c.f.MetadataPos() {
+		c.report("row group", offset, "size %d runs past the footer at %d", rowGroupHeader.GetDataSize(), c.f.MetadataPos())
+		return false
+	}
+	c.walkedRowGroups[offset] = true
+	if rowGroup, ok := c.rowGroups[offset]; !ok {
+		c.report("row group", offset, "not in the footer")
+	} else {
+		if rowGroup.GetDeviceId() != c.device {
+			c.report("row group", offset, "the footer has the device %s", rowGroup.GetDeviceId())
+		}
+		if rowGroup.GetTotalByteSize() != rowGroupHeader.GetDataSize() {
+			c.report("row group", offset, "size %d, the footer has %d", rowGroupHeader.GetDataSize(), rowGroup.GetTotalByteSize())
+		}
+		if len(rowGroup.GetChunkMetaDataSli()) != int(rowGroupHeader.GetNumberOfChunks()) {
+			c.report("row group", offset, "%d chunks, the footer has %d", rowGroupHeader.GetNumberOfChunks(), len(rowGroup.GetChunkMetaDataSli()))
+		}
+	}
+
+	for i := 0; i < int(rowGroupHeader.GetNumberOfChunks()) && c.f.Pos() < end; i++ {
+		if !c.walkChunk(end) {
+			break
+		}
+	}
+	if c.f.Pos() != end {
+		c.report("row group", offset, "%d chunks end at %d, the header has %d bytes ending at %d",
+			rowGroupHeader.GetNumberOfChunks(), c.f.Pos(), rowGroupHeader.GetDataSize(), end)
+		c.f.reader.Seek(end, io.SeekStart)
+	}
+	return true
+}
+
+// walkChunk is false if the chunk runs past the end of its row group
+func (c *checker) walkChunk(rowGroupEnd int64) bool {
+	offset := c.f.Pos()
+	c.block, c.offset, c.sensor = "chunk", offset, ""
+	chunkHeader := c.f.ReadChunkHeader()
+	c.sensor = chunkHeader.GetSensor()
+	dataType := chunkHeader.GetDataType()
+	end := c.f.Pos() + int64(chunkHeader.GetDataSize())
+	if end > rowGroupEnd {
+		c.report("chunk", offset, "size %d runs past the row group ending at %d", chunkHeader.GetDataSize(), rowGroupEnd)
+		return false
+	}
+	if fileDataType := c.fileMetadata.GetDataType(c.device, c.sensor); fileDataType != dataType {
+		c.report("chunk", offset, "data type %s, the footer has %s", dataType, fileDataType)
+	}
+	if !c.f.MayContain(c.device + constant.PATH_SEPARATOR + c.sensor) {
+		c.report("chunk", offset, "not in the bloom filter of the footer")
+	}
+
+	chunk := &chunkInfo{device: c.device, sensor: c.sensor, startTime: math.MaxInt64, endTime: math.MinInt64}
+	c.walkedChunks[offset] = chunk
+	chunkStatistics := statistics.GetStatsByType(int16(dataType))
+	lastTime := int64(math.MinInt64)
+	for j := 0; j < chunkHeader.GetNumberOfPages() && c.f.Pos() < end; j++ {
+		pageOffset := c.f.Pos()
+		c.block, c.offset = "page", pageOffset
+		pageHeader := c.f.ReadPageHeader(dataType)
+		if c.f.Pos()+int64(pageHeader.GetCompressedSize()) > end {
+			c.report("page", pageOffset, "size %d runs past the chunk ending at %d", pageHeader.GetCompressedSize(), end)
+			break
+		}
+		data := c.f.ReadRaw(c.f.Pos(), int(pageHeader.GetCompressedSize()))
+		if pageStatistics := c.checkPage(pageOffset, pageHeader, data, chunkHeader, &lastTime); pageStatistics != nil {
+			chunkStatistics.Merge(pageStatistics)
+		}
+		chunk.points += int64(pageHeader.GetNumberOfValues())
+		chunk.startTime = min(chunk.startTime, pageHeader.Min_timestamp())
+		chunk.endTime = max(chunk.endTime, pageHeader.Max_timestamp())
+	}
+	if c.f.Pos() != end {
+		c.report("chunk", offset, "%d pages end at %d, the header has %d bytes ending at %d",
+			chunkHeader.GetNumberOfPages(), c.f.Pos(), chunkHeader.GetDataSize(), end)
+		c.f.reader.Seek(end, io.SeekStart)
+	}
+	c.checkChunk(offset, end-offset, chunk, chunkStatistics, dataType)
+	return true
+}
+
+// checkPage decodes the page and returns the statistics of its values, nil if
+// it can not be decoded
+func (c *checker) checkPage(offset int64, pageHeader *header.PageHeader, data []byte, chunkHeader *header.ChunkHeader,
+	lastTime *int64) (computed statistics.Statistics) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.report("page", offset, "cannot be decoded: %v", r)
+			computed = nil
+		}
+	}()
+	decompressor, err := compress.GetDecompressor(chunkHeader.GetCompressionType())
+	if err != nil {
+		c.report("page", offset, "cannot be decompressed: %v", err)
+		return nil
+	}
+	data, err = decompressor.Decompress(data)
+	if err != nil {
+		c.report("page", offset, "cannot be decompressed: %v", err)
+		return nil
+	}
+	if len(data) != int(pageHeader.GetUncompressedSize()) {
+		c.report("page", offset, "uncompressed size %d, the header has %d", len(data), pageHeader.GetUncompressedSize())
+	}
+
+	reader := utils.NewBytesReader(data)
+	timeLength := int(reader.ReadUnsignedVarInt())
+	pos := reader.Pos()
+	if timeLength < 0 || pos+timeLength > len(data) {
+		c.report("page", offset, "timestamps of %d bytes run past the page of %d bytes", timeLength, len(data))
+		return nil
+	}
+	timeDecoder, err := decoder.CreateDecoder(chunkHeader.GetTimeEncodingType(), constant.INT64)
+	if err != nil {
+		c.report("page", offset, "cannot be decoded: %v", err)
+		return nil
+	}
+	valueDecoder, err := decoder.CreateDecoder(chunkHeader.GetEncodingType(), chunkHeader.GetDataType())
+	if err != nil {
+		c.report("page", offset, "cannot be decoded: %v", err)
+		return nil
+	}
+	timeDecoder.Init(data[pos : pos+timeLength])
+	valueDecoder.Init(data[pos+timeLength:])
+	for _, d := range []decoder.Decoder{timeDecoder, valueDecoder} {
+		if err := decoder.Err(d); err != nil {
+			c.report("page", offset, "cannot be decoded: %v", err)
+			return nil
+		}
+	}
+
+	computed = statistics.GetStatsByType(int16(chunkHeader.GetDataType()))
+	ordered := true
+	for timeDecoder.HasNext() && valueDecoder.HasNext() {
+		t := timeDecoder.Next().(int64)
+		if t <= *lastTime && ordered {
+			c.report("page", offset, "time %d is not after %d", t, *lastTime)
+			ordered = false
+		}
+		*lastTime = t
+		computed.UpdateStats(t, valueDecoder.Next())
+	}
+	if timeDecoder.HasNext() != valueDecoder.HasNext() {
+		c.report("page", offset, "the numbers of timestamps and values differ")
+	}
+
+	if computed.Count() != int64(pageHeader.GetNumberOfValues()) {
+		c.report("page", offset, "%d values, the header has %d", computed.Count(), pageHeader.GetNumberOfValues())
+	} else if computed.Count() > 0 && (computed.FirstTime() != pageHeader.Min_timestamp() || computed.LastTime() != pageHeader.Max_timestamp()) {
+		c.report("page", offset, "times [%d, %d], the header has [%d, %d]",
+			computed.FirstTime(), computed.LastTime(), pageHeader.Min_timestamp(), pageHeader.Max_timestamp())
+	} else if field := StatisticsMismatch(*pageHeader.GetStatistics(), computed); field != "" {
+		c.report("page", offset, "the %s of the values differs from the one of the header", field)
+	}
+	return computed
+}
+
+func (c *checker) checkChunk(offset int64, size int64, chunk *chunkInfo, computed statistics.Statistics, dataType constant.TSDataType) {
+	chunkMetaData, ok := c.chunks[offset]
+	if !ok {
+		c.report("chunk", offset, "not in the footer")
+		return
+	}
+	if chunkMetaData.Sensor() != chunk.sensor {
+		c.report("chunk", offset, "the footer has the sensor %s", chunkMetaData.Sensor())
+	}
+	if chunkMetaData.TotalByteSizeOfPagesOnDisk() != size {
+		c.report("chunk", offset, "size %d, the footer has %d", size, chunkMetaData.TotalByteSizeOfPagesOnDisk())
+	}
+	if chunkMetaData.GetNumOfPoints() != chunk.points {
+		c.report("chunk", offset, "%d points, the footer has %d", chunk.points, chunkMetaData.GetNumOfPoints())
+	}
+	if chunkMetaData.GetStartTime() != chunk.startTime || chunkMetaData.GetEndTime() != chunk.endTime {
+		c.report("chunk", offset, "times [%d, %d], the footer has [%d, %d]",
+			chunk.startTime, chunk.endTime, chunkMetaData.GetStartTime(), chunkMetaData.GetEndTime())
+	}
+	// the statistics of approximated values are the ones of the values written
+	if _, _, lossy := chunkMetaData.GetDigest().GetLossyFilter(); lossy {
+		return
+	}
+	if stored := chunkMetaData.GetStatistics(dataType); stored != nil && computed.Count() == chunk.points {
+		if field := StatisticsMismatch(stored, computed); field != "" {
+			c.report("chunk", offset, "the %s of the values differs from the one of the footer", field)
+		}
+	}
+}
+
+// checkFooter looks for the row groups and chunks of the footer the walk has
+// not found, and checks the time ranges of the devices
+func (c *checker) checkFooter() {
+	footer := c.f.MetadataPos()
+	for _, offset := range sortedOffsets(c.rowGroups) {
+		if !c.walkedRowGroups[offset] {
+			c.report("footer", footer, "the row group of %s at offset %d is not in the file", c.rowGroups[offset].GetDeviceId(), offset)
+		}
+	}
+	for _, offset := range sortedOffsets(c.chunks) {
+		if _, ok := c.walkedChunks[offset]; !ok {
+			c.report("footer", footer, "the chunk of %s at offset %d is not in the file", c.chunks[offset].Sensor(), offset)
+		}
+	}
+
+	deviceMap := c.fileMetadata.DeviceMap()
+	for _, deviceId := range utils.SortedKeys(deviceMap) {
+		startTime, endTime, ok := c.deviceTimes(deviceId)
+		device := deviceMap[deviceId]
+		if ok && (device.GetStartTime() != startTime || device.GetEndTime() != endTime) {
+			c.report("footer", footer, "device %s has the times [%d, %d], its chunks [%d, %d]",
+				deviceId, device.GetStartTime(), device.GetEndTime(), startTime, endTime)
+		}
+	}
+}
+
+// deviceTimes is the time range of the chunks of the device the walk found
+func (c *checker) deviceTimes(deviceId string) (int64, int64, bool) {
+	startTime, endTime, ok := int64(math.MaxInt64), int64(math.MinInt64), false
+	for _, chunk := range c.walkedChunks {
+		if chunk.device == deviceId && chunk.points > 0 {
+			startTime, endTime, ok = min(startTime, chunk.startTime), max(endTime, chunk.endTime), true
+		}
+	}
+	return startTime, endTime, ok
+}
+
+// checkIndex checks the device table of the footer against the walk
+func (c *checker) checkIndex() {
+	footer := c.f.MetadataPos()
+	defer func() {
+		if r := recover(); r != nil {
+			c.report("footer", footer, "the index cannot be parsed: %v", r)
+		}
+	}()
+	index := c.f.ReadIndex()
+	if index == nil {
+		return
+	}
+	for _, deviceId := range index.Devices() {
+		device, _ := index.GetDevice(deviceId)
+		if _, ok := c.fileMetadata.DeviceMap()[device.DeviceId]; !ok {
+			c.report("footer", footer, "the index has the device %s the footer has not", device.DeviceId)
+			continue
+		}
+		if startTime, endTime, ok := c.deviceTimes(device.DeviceId); ok && (device.StartTime != startTime || device.EndTime != endTime) {
+			c.report("footer", footer, "the index has the times [%d, %d] of %s, its chunks [%d, %d]",
+				device.StartTime, device.EndTime, device.DeviceId, startTime, endTime)
+		}
+		series := c.f.ReadSeriesIndex(device)
+		for _, sensorId := range utils.SortedKeys(series) {
+			for _, chunkIndex := range series[sensorId].Chunks {
+				chunk, ok := c.walkedChunks[chunkIndex.Offset]
+				if !ok || chunk.device != device.DeviceId || chunk.sensor != sensorId {
+					c.report("footer", footer, "the index has a chunk of %s%s%s at offset %d the file has not",
+						device.DeviceId, constant.PATH_SEPARATOR, sensorId, chunkIndex.Offset)
+				} else if chunk.startTime != chunkIndex.StartTime || chunk.endTime != chunkIndex.EndTime {
+					c.report("footer", footer, "the index has the times [%d, %d] of the chunk at offset %d, its pages [%d, %d]",
+						chunkIndex.StartTime, chunkIndex.EndTime, chunkIndex.Offset, chunk.startTime, chunk.endTime)
+				}
+			}
+		}
+	}
+}
+
+func sortedOffsets[V any](m map[int64]V) []int64 {
+	offsets := make([]int64, 0, len(m))
+	for offset := range m {
+		offsets = append(offsets, offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	return offsets
+}
+
+// StatisticsMismatch names the first of count, min, max, first, last and sum
+// that differs, empty if none does. Floating point values are compared up to
+// conf.FloatPrecision, the encodings rounding them keep the statistics of the
+// values before.
+func StatisticsMismatch(stored statistics.Statistics, computed statistics.Statistics) string {
+	if stored.Count() != computed.Count() {
+		return "count"
+	}
+	names := []string{"min", "max", "first", "last", "sum"}
+	storedValues, computedValues := floatValues(stored), floatValues(computed)
+	if storedValues != nil && computedValues != nil {
+		tolerance := math.Pow10(-conf.FloatPrecision)
+		for i, name := range names {
+			if name == "sum" {
+				tolerance *= float64(computed.Count())
+			}
+			a, b := storedValues[i], computedValues[i]
+			if a != b && !(math.IsNaN(a) && math.IsNaN(b)) && math.Abs(a-b) > tolerance+1e-6*math.Abs(a) {
+				return name
+			}
+		}
+		return ""
+	}
+	// the data type is not used by the statistics, they know theirs
+	storedBytes := [][]byte{stored.GetMinByte(0), stored.GetMaxByte(0), stored.GetFirstByte(0), stored.GetLastByte(0), stored.GetSumByte(0)}
+	computedBytes := [][]byte{computed.GetMinByte(0), computed.GetMaxByte(0), computed.GetFirstByte(0), computed.GetLastByte(0), computed.GetSumByte(0)}
+	for i, name := range names {
+		if !bytes.Equal(storedBytes[i], computedBytes[i]) {
+			return name
+		}
+	}
+	return ""
+}
+
+// floatValues are min, max, first, last and sum of FLOAT and DOUBLE statistics
+func floatValues(s statistics.Statistics) []float64 {
+	switch s := s.(type) {
+	case statistics.TypedStatistics[float32]:
+		return []float64{float64(s.Min()), float64(s.Max()), float64(s.First()), float64(s.Last()), s.Sum()}
+	case statistics.TypedStatistics[float64]:
+		return []float64{s.Min(), s.Max(), s.First(), s.Last(), s.Sum()}
+	default:
+		return nil
+	}
+}
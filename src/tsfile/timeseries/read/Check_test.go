@@ -0,0 +1,74 @@
+package read_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"tsfile/common/conf"
+	"tsfile/common/constant"
+	"tsfile/timeseries/read"
+	"tsfile/timeseries/write/sensorDescriptor"
+	"tsfile/timeseries/write/tsFileWriter"
+)
+
+// the tests are outside the package read for writing their files, the writer
+// imports it
+
+func writeLongs(w *tsFileWriter.TsFileWriter, device string, from int, to int) {
+	for i := from; i < to; i++ {
+		record, _ := tsFileWriter.NewTsRecordUseTimestamp(int64(i), device)
+		pt, _ := tsFileWriter.NewLong("s0", constant.INT64, int64(i))
+		record.AddTuple(pt)
+		w.Write(record)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	checkFilePath := filepath.Join(t.TempDir(), "check_TsFile")
+
+	w, err := tsFileWriter.NewTsFileWriter(checkFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	des, _ := sensorDescriptor.New("s0", constant.INT64, constant.PLAIN)
+	w.AddSensor(des)
+	writeLongs(w, "root.d0", 1, 101)
+	// a second row group of the device
+	w.Flush()
+	writeLongs(w, "root.d0", 101, 201)
+	writeLongs(w, "root.d1", -50, 50)
+	w.Close()
+	if problems := read.Check(checkFilePath); len(problems) != 0 {
+		t.Fatalf("expected no problems, got %v", problems)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(checkFilePath); err != nil {
+		t.Fatal(err)
+	}
+	f.ReadRowGroupHeader()
+	f.ReadChunkHeader()
+	pageOffset := f.Pos()
+	pageHeader := f.ReadPageHeader(constant.INT64)
+	// the last byte of the value 100, the max of the page
+	valueOffset := f.Pos() + int64(pageHeader.GetCompressedSize()) - 1
+	f.Close()
+
+	data, _ := os.ReadFile(checkFilePath)
+	bad := append([]byte(nil), data...)
+	bad[valueOffset] ^= 0x40
+	os.WriteFile(checkFilePath, bad, 0666)
+	problems := read.Check(checkFilePath)
+	if len(problems) < 2 || problems[0].Block != "page" || problems[0].Device != "root.d0" || problems[0].Sensor != "s0" ||
+		problems[0].Offset != pageOffset || !strings.Contains(problems[1].Message, "max") {
+		t.Fatalf("expected a checksum and a max problem of the page at %d, got %v", pageOffset, problems)
+	}
+
+	// not sealed
+	os.WriteFile(checkFilePath, data[:len(data)-1], 0666)
+	problems = read.Check(checkFilePath)
+	if len(problems) != 1 || problems[0].Block != "file" || problems[0].Offset != int64(len(data)-len(conf.MAGIC_STRING)-1) {
+		t.Fatalf("expected a problem of the tail magic, got %v", problems)
+	}
+}
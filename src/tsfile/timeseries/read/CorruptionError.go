@@ -0,0 +1,29 @@
+package read
+
+import (
+	"strconv"
+)
+
+// CorruptionError is a block of a tsfile whose CRC32C does not match the one
+// written with it.
+type CorruptionError struct {
+	File string
+	// Block is "footer", "row group header", "chunk header" or "page"
+	Block string
+	// Device and Sensor are empty if the block is before them
+	Device string
+	Sensor string
+	// Offset is where the block starts, for a page the one of its header
+	Offset int64
+}
+
+func (e *CorruptionError) Error() string {
+	msg := "tsfile " + e.File + ": corrupted " + e.Block
+	if e.Device != "" {
+		msg += " of " + e.Device
+		if e.Sensor != "" {
+			msg += "." + e.Sensor
+		}
+	}
+	return msg + " at offset " + strconv.FormatInt(e.Offset, 10)
+}
@@ -3,9 +3,11 @@ package read
 import (
 	//"bufio"
 	"encoding/binary"
+	"errors"
+	"hash/crc32"
 	"io"
-	"log"
 	"os"
+	"strconv"
 	"tsfile/common/conf"
 	"tsfile/common/constant"
 	"tsfile/common/utils"
@@ -20,32 +22,80 @@ type TsFileSequenceReader struct {
 	size          int64
 	metadata_pos  int64
 	metadata_size int
+	version       int
+
+	// checksums of the blocks, set if they are verified on read
+	checksums map[int64]metadata.Checksum
+	// the first block of the verified reads whose checksum does not match
+	err *CorruptionError
+	// the device and sensor of the blocks read, named by a CorruptionError
+	device string
+	sensor string
+	// read by MayContain, empty if the file has none
+	bloomFilter *metadata.BloomFilter
 }
 
-func (f *TsFileSequenceReader) Open(file string) {
+// Open checks the head and tail magic and the version of the file, it fails
+// for files not sealed and versions not supported.
+func (f *TsFileSequenceReader) Open(file string) error {
 	f.fileName = file
 
 	fin, err := os.Open(file)
-	if err == nil {
-		stat, _ := fin.Stat()
-		f.size = stat.Size()
-
-		// get matadata pos&size
-		buf := make([]byte, 4)
-		_, err := fin.ReadAt(buf, f.size-int64(len(conf.MAGIC_STRING))-4)
-		if err == nil {
-			f.metadata_size = int(binary.BigEndian.Uint32(buf))
-			f.metadata_pos = f.size - int64(len(conf.MAGIC_STRING)) - 4 - int64(f.metadata_size)
-		}
+	if err != nil {
+		return err
+	}
+	stat, _ := fin.Stat()
+	f.size = stat.Size()
+	f.reader = utils.NewFileReader(fin)
 
-		// get pointer ready for reading RowGroupHeader
-		f.reader = utils.NewFileReader(fin)
-		f.reader.Seek(int64(len(conf.MAGIC_STRING)), io.SeekStart)
+	magicLen := int64(len(conf.MAGIC_STRING))
+	if f.size < 2*magicLen+int64(constant.INT_LEN) || f.ReadHeadMagic() != conf.MAGIC_STRING {
+		fin.Close()
+		return errors.New("tsfile " + file + ": not a tsfile")
+	}
+	if f.ReadTailMagic() != conf.MAGIC_STRING {
+		fin.Close()
+		return errors.New("tsfile " + file + ": not sealed, recover it first")
+	}
 
+	// get matadata pos&size
+	buf := f.reader.ReadAt(constant.INT_LEN, f.size-magicLen-int64(constant.INT_LEN))
+	f.metadata_size = int(binary.BigEndian.Uint32(buf))
+	f.metadata_pos = f.size - magicLen - int64(constant.INT_LEN) - int64(f.metadata_size)
+	if f.metadata_size <= 0 || f.metadata_pos < magicLen {
+		fin.Close()
+		return errors.New("tsfile " + file + ": invalid footer size " + strconv.Itoa(f.metadata_size))
+	}
+	if err := f.readVersion(); err != nil {
+		fin.Close()
+		return errors.New("tsfile " + file + ": " + err.Error())
+	}
+
+	// get pointer ready for reading RowGroupHeader
+	f.reader.Seek(magicLen, io.SeekStart)
+	return nil
+}
+
+// readVersion takes the version from the end of the footer, only files of
+// VERSION_3 are read whole for it
+func (f *TsFileSequenceReader) readVersion() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New("invalid footer")
+		}
+	}()
+	tailSize := min(metadata.FOOTER_CRC_SIZE, f.metadata_size)
+	if version, ok := metadata.TailVersion(f.reader.ReadAt(tailSize, f.metadata_pos+int64(f.metadata_size-tailSize))); ok && version >= metadata.VERSION_4 {
+		f.version = version
 	} else {
-		log.Println("Failed to open file: " + file)
-		panic(err)
+		f.version = f.ReadFileMetadata().GetCurrentVersion()
 	}
+	return metadata.CheckVersion(f.version)
+}
+
+// Version is the version of the format of the file
+func (f *TsFileSequenceReader) Version() int {
+	return f.version
 }
 
 func (f *TsFileSequenceReader) ReadHeadMagic() string {
@@ -65,25 +115,198 @@ func (f *TsFileSequenceReader) ReadFileMetadata() *metadata.FileMetaData {
 	fileMetadata := new(metadata.FileMetaData)
 
 	data := f.reader.ReadAt(f.metadata_size, f.metadata_pos)
+	if f.checksums != nil {
+		if valid, _ := metadata.VerifyFooter(data); !valid {
+			f.fail(f.corruption("footer", f.metadata_pos))
+			return fileMetadata
+		}
+	}
 	fileMetadata.Deserialize(data)
 
 	return fileMetadata
 }
 
+// tailSection finds the section of the footer ending at end by its trailer, it
+// returns the tag and the position and length of the bytes before the trailer
+func (f *TsFileSequenceReader) tailSection(end int64) (int16, int64, int, bool) {
+	trailerPos := end - int64(metadata.TAIL_TRAILER_SIZE)
+	if trailerPos < f.metadata_pos {
+		return 0, 0, 0, false
+	}
+	tag, length, ok := metadata.TailSection(f.reader.ReadAt(metadata.TAIL_TRAILER_SIZE, trailerPos))
+	if !ok || trailerPos-int64(length) < f.metadata_pos {
+		return 0, 0, 0, false
+	}
+	return tag, trailerPos - int64(length), length, true
+}
+
+// ReadIndex reads the device table of the footer without the rest of it, nil
+// if the file is written without an index.
+func (f *TsFileSequenceReader) ReadIndex() *metadata.FileIndex {
+	tag, pos, length, ok := f.tailSection(f.metadata_pos + int64(f.metadata_size-metadata.FOOTER_CRC_SIZE))
+	if ok && tag == metadata.FOOTER_BLOOM_FILTER {
+		// skip the bloom filter and the tag and length starting its section
+		tag, pos, length, ok = f.tailSection(pos - int64(constant.SHORT_LEN+constant.INT_LEN))
+	}
+	if !ok || tag != metadata.FOOTER_INDEX {
+		return nil
+	}
+	return metadata.DeserializeFileIndex(f.reader.ReadAt(length, pos))
+}
+
+// MayContain reads the bloom filter of the footer without the rest of it, it
+// is false only if the file has no chunk of the series of the path, e.g.
+// root.d0.s0. Files written without a bloom filter may contain any path.
+func (f *TsFileSequenceReader) MayContain(path string) bool {
+	if f.bloomFilter == nil {
+		f.bloomFilter = &metadata.BloomFilter{}
+		tag, pos, length, ok := f.tailSection(f.metadata_pos + int64(f.metadata_size-metadata.FOOTER_CRC_SIZE))
+		if ok && tag == metadata.FOOTER_BLOOM_FILTER {
+			if bloomFilter := metadata.DeserializeBloomFilter(f.reader.ReadAt(length, pos)); bloomFilter != nil {
+				f.bloomFilter = bloomFilter
+			}
+		}
+	}
+	return f.bloomFilter.MayContain(path)
+}
+
+// ReadDeviceMetadata reads the metadata of one device found by ReadIndex
+func (f *TsFileSequenceReader) ReadDeviceMetadata(device *metadata.DeviceIndex) *metadata.DeviceMetaData {
+	offset, length := device.MetadataRange()
+	return metadata.DeserializeDeviceMetaData(f.reader.ReadAt(length, f.metadata_pos+offset))
+}
+
+// ReadSeriesIndex reads the chunks of each sensor of one device found by ReadIndex
+func (f *TsFileSequenceReader) ReadSeriesIndex(device *metadata.DeviceIndex) map[string]*metadata.SeriesIndex {
+	offset, length := device.SeriesRange()
+	return metadata.DeserializeSeriesIndex(f.reader.ReadAt(length, f.metadata_pos+offset))
+}
+
+// SetVerifyChecksums makes the reads check the blocks they read against the
+// checksums in the footer, before parsing them. A block that does not match is
+// not parsed, the read returns an empty header or footer, HasNextRowGroup is
+// false from then on and Err returns the *CorruptionError. One of the footer
+// is returned at once. Files written without checksums can not be verified.
+func (f *TsFileSequenceReader) SetVerifyChecksums(verify bool) error {
+	f.err = nil
+	if !verify {
+		f.checksums = nil
+		return nil
+	}
+	data := f.reader.ReadAt(f.metadata_size, f.metadata_pos)
+	if valid, ok := metadata.VerifyFooter(data); !ok {
+		return errors.New("tsfile " + f.fileName + ": written without checksums")
+	} else if !valid {
+		return f.corruption("footer", f.metadata_pos)
+	}
+	fileMetadata := new(metadata.FileMetaData)
+	fileMetadata.Deserialize(data)
+	f.checksums = fileMetadata.GetChecksums()
+	return nil
+}
+
+// Verify reads the whole file checking all checksums, it returns the
+// *CorruptionError of the first block that does not match. The reader is at
+// the first row group again after it.
+func (f *TsFileSequenceReader) Verify() error {
+	checksums, err := f.checksums, f.err
+	defer func() {
+		f.checksums, f.err = checksums, err
+		f.reader.Seek(int64(len(conf.MAGIC_STRING)), io.SeekStart)
+	}()
+	if err := f.SetVerifyChecksums(true); err != nil {
+		return err
+	}
+
+	f.reader.Seek(int64(len(conf.MAGIC_STRING)), io.SeekStart)
+	for f.HasNextRowGroup() {
+		rowGroupHeader := f.ReadRowGroupHeader()
+		for i := 0; i < int(rowGroupHeader.GetNumberOfChunks()); i++ {
+			chunkHeader := f.ReadChunkHeader()
+			for j := 0; j < chunkHeader.GetNumberOfPages(); j++ {
+				pageHeader := f.ReadPageHeader(chunkHeader.GetDataType())
+				f.reader.Seek(f.reader.Pos()+int64(pageHeader.GetCompressedSize()), io.SeekStart)
+			}
+		}
+	}
+	return f.Err()
+}
+
+// Err returns the *CorruptionError of the first block of the verified reads
+// whose checksum does not match, nil if there is none
+func (f *TsFileSequenceReader) Err() error {
+	if f.err == nil {
+		return nil
+	}
+	return f.err
+}
+
+func (f *TsFileSequenceReader) fail(corruption *CorruptionError) {
+	if f.err == nil {
+		f.err = corruption
+	}
+}
+
+// verifyBlock checks the block at the current position if it has a checksum,
+// it is false if the block must not be parsed
+func (f *TsFileSequenceReader) verifyBlock(block string) bool {
+	if f.err != nil {
+		return false
+	}
+	if f.checksums == nil {
+		return true
+	}
+	offset := f.reader.Pos()
+	checksum, ok := f.checksums[offset]
+	if !ok {
+		return true
+	}
+	data := f.reader.ReadAt(int(checksum.Length), offset)
+	if crc32.Checksum(data, metadata.CRC32C) != checksum.Crc {
+		f.fail(f.corruption(block, offset))
+		return false
+	}
+	return true
+}
+
+func (f *TsFileSequenceReader) corruption(block string, offset int64) *CorruptionError {
+	e := &CorruptionError{File: f.fileName, Block: block, Offset: offset}
+	if block == "chunk header" || block == "page" {
+		e.Device = f.device
+	}
+	if block == "page" {
+		e.Sensor = f.sensor
+	}
+	return e
+}
+
+// MetadataPos is where the footer starts, i.e. the end of the last row group
+func (f *TsFileSequenceReader) MetadataPos() int64 {
+	return f.metadata_pos
+}
+
 func (f *TsFileSequenceReader) HasNextRowGroup() bool {
-	return f.reader.Pos() < f.metadata_pos
+	return f.err == nil && f.reader.Pos() < f.metadata_pos
 }
 
 func (f *TsFileSequenceReader) ReadRowGroupHeader() *header.RowGroupHeader {
 	header := new(header.RowGroupHeader)
+	if !f.verifyBlock("row group header") {
+		return header
+	}
 	header.Deserialize(f.reader)
+	f.device = header.GetDevice()
 
 	return header
 }
 
 func (f *TsFileSequenceReader) ReadChunkHeader() *header.ChunkHeader {
 	header := new(header.ChunkHeader)
+	if !f.verifyBlock("chunk header") {
+		return header
+	}
 	header.Deserialize(f.reader)
+	f.sensor = header.GetSensor()
 
 	return header
 }
@@ -102,20 +325,29 @@ func (f *TsFileSequenceReader) ReadChunkAt(header *header.ChunkHeader, positionO
 	return f.ReadChunk(header)
 }
 
+// ReadChunkAndHeader returns the bytes of the chunk at position, its header
+// included
 func (f *TsFileSequenceReader) ReadChunkAndHeader(position int64) []byte {
 	header := f.ReadChunkHeaderAt(position)
 	length := header.GetSerializedSize() + header.GetDataSize()
 
+	f.reader.Seek(position, io.SeekStart)
 	return f.reader.ReadSlice(length)
 }
 
+// ReadRaw returns a copy, the page readers keep the data while other readers
+// share the buffer of the file reader
 func (f *TsFileSequenceReader) ReadRaw(position int64, length int) []byte {
 	f.reader.Seek(position, io.SeekStart)
-	return f.reader.ReadSlice(length)
+	return append([]byte(nil), f.reader.ReadSlice(length)...)
 }
 
+// ReadPageHeader verifies the page with its data if checksums are verified
 func (f *TsFileSequenceReader) ReadPageHeader(dataType constant.TSDataType) *header.PageHeader {
 	header := new(header.PageHeader)
+	if !f.verifyBlock("page") {
+		return header
+	}
 	header.Deserialize(f.reader, dataType)
 
 	return header
@@ -127,7 +359,10 @@ func (f *TsFileSequenceReader) ReadPageHeaderAt(dataType constant.TSDataType, of
 }
 
 func (f *TsFileSequenceReader) ReadPage(header *header.PageHeader, compression constant.CompressionType) []byte {
-	unCompressor := compress.GetDecompressor(compression)
+	unCompressor, err := compress.GetDecompressor(compression)
+	if err != nil {
+		panic(err)
+	}
 	data := f.reader.ReadSlice(int(header.GetCompressedSize()))
 
 	if unCompressedData, err := unCompressor.Decompress(data); err == nil {
@@ -137,6 +372,11 @@ func (f *TsFileSequenceReader) ReadPage(header *header.PageHeader, compression c
 	}
 }
 
+// SkipPage moves past the data of the page without reading it
+func (f *TsFileSequenceReader) SkipPage(header *header.PageHeader) {
+	f.reader.Skip(header.GetCompressedSize())
+}
+
 func (f *TsFileSequenceReader) Pos() int64 {
 	return f.reader.Pos()
 }
@@ -13,6 +13,22 @@ type PageDataReader struct {
 	DataType     constant.TSDataType
 	ValueDecoder decoder.Decoder
 	TimeDecoder  decoder.Decoder
+	// why the page can not be decoded, returned by the next call of Next
+	err error
+}
+
+// NewPageDataReader reads the pages of the given encodings, it fails if one of
+// them has no decoder.
+func NewPageDataReader(dataType constant.TSDataType, encoding constant.TSEncoding, timeEncoding constant.TSEncoding) (*PageDataReader, error) {
+	valueDecoder, err := decoder.CreateDecoder(encoding, dataType)
+	if err != nil {
+		return nil, err
+	}
+	timeDecoder, err := decoder.CreateDecoder(timeEncoding, constant.INT64)
+	if err != nil {
+		return nil, err
+	}
+	return &PageDataReader{DataType: dataType, ValueDecoder: valueDecoder, TimeDecoder: timeDecoder}, nil
 }
 
 func (r *PageDataReader) Read(data []byte) {
@@ -22,13 +38,21 @@ func (r *PageDataReader) Read(data []byte) {
 
 	r.TimeDecoder.Init(data[pos : timeInputStreamLength+pos])
 	r.ValueDecoder.Init(data[timeInputStreamLength+pos:])
+	if r.err = decoder.Err(r.TimeDecoder); r.err == nil {
+		r.err = decoder.Err(r.ValueDecoder)
+	}
 }
 
 func (r *PageDataReader) HasNext() bool {
-	return r.TimeDecoder.HasNext() && r.ValueDecoder.HasNext()
+	return r.err != nil || r.TimeDecoder.HasNext() && r.ValueDecoder.HasNext()
 }
 
 func (r *PageDataReader) Next() (*datatype.TimeValuePair, error) {
+	if r.err != nil {
+		err := r.err
+		r.err = nil
+		return nil, err
+	}
 	// TODO: catch errors
 	return &datatype.TimeValuePair{Timestamp: r.TimeDecoder.Next().(int64), Value: r.ValueDecoder.Next()}, nil
 }
@@ -65,7 +65,7 @@ func (r *RowRecordReader) HasNext() bool {
 	}
 	err := r.fillCache()
 	if err != nil {
-		log.Error("Cannot read next record ", err)
+		log.Error("Cannot read next record: %v", err)
 		r.exhausted = true
 	} else if r.currTime == math.MaxInt64 {
 		r.exhausted = true
@@ -2,7 +2,7 @@ package basic
 
 import (
 	"tsfile/common/constant"
-	"tsfile/encoding/decoder"
+	"tsfile/compress"
 	"tsfile/timeseries/read"
 	"tsfile/timeseries/read/datatype"
 	"tsfile/timeseries/read/reader"
@@ -19,7 +19,13 @@ type SeriesReader struct {
 	FileReader *read.TsFileSequenceReader
 	PageReader reader.TimeValuePairReader
 	DType      constant.TSDataType
-	Encoding   constant.TSEncoding
+	// encodings and compression of every page, as recorded in the header of
+	// its chunk, the chunks of a series may differ
+	Encodings     []constant.TSEncoding
+	TimeEncodings []constant.TSEncoding
+	Compressions  []constant.CompressionType
+	// why the next page can not be read, returned by Next
+	pageErr error
 }
 
 func (r *SeriesReader) Read(data []byte) {
@@ -31,6 +37,9 @@ func (r *SeriesReader) Skip() {
 }
 
 func (r *SeriesReader) HasNext() bool {
+	if r.pageErr != nil {
+		return true
+	}
 	if r.PageReader != nil {
 		if r.PageReader.HasNext() {
 			return true
@@ -48,30 +57,58 @@ func (r *SeriesReader) HasNext() bool {
 }
 
 func (r *SeriesReader) Next() (*datatype.TimeValuePair, error) {
-	if r.PageReader.HasNext() {
+	if r.pageErr != nil {
+		return nil, r.stop(r.pageErr)
+	}
+	if r.PageReader != nil && r.PageReader.HasNext() {
 		ret, err := r.PageReader.Next()
 		if err != nil {
-			return nil, err
+			return nil, r.stop(err)
 		}
 		return ret, nil
 	} else {
 		err := r.nextPageReader()
 		if err != nil {
-			return nil, err
+			return nil, r.stop(err)
 		}
 		return r.Next()
 	}
 }
 
+// stop ends the series at a page that can not be read, the pages behind it
+// are not read either
+func (r *SeriesReader) stop(err error) error {
+	r.pageErr = nil
+	r.PageIndex = r.PageLimit
+	return err
+}
+
 func (r *SeriesReader) Close() {
-	r.PageReader.Close()
+	if r.PageReader != nil {
+		r.PageReader.Close()
+	}
 	r.PageReader = nil
 	r.PageIndex = r.PageLimit
 	r.FileReader = nil
 }
 
-func NewSeriesReader(offsets []int64, sizes []int, reader *read.TsFileSequenceReader, dType constant.TSDataType, encoding constant.TSEncoding) *SeriesReader {
-	return &SeriesReader{-1, len(offsets), offsets, sizes, reader, nil, dType, encoding}
+func NewSeriesReader(offsets []int64, sizes []int, reader *read.TsFileSequenceReader, dType constant.TSDataType, encodings []constant.TSEncoding, timeEncodings []constant.TSEncoding, compressions []constant.CompressionType) *SeriesReader {
+	return &SeriesReader{PageIndex: -1, PageLimit: len(offsets), Offsets: offsets, Sizes: sizes, FileReader: reader,
+		DType: dType, Encodings: encodings, TimeEncodings: timeEncodings, Compressions: compressions}
+}
+
+// ReadPageData reads the current page, decompressed
+func (r *SeriesReader) ReadPageData() ([]byte, error) {
+	data := r.FileReader.ReadRaw(r.Offsets[r.PageIndex], r.Sizes[r.PageIndex])
+	compression := r.Compressions[r.PageIndex]
+	if compression == constant.UNCOMPRESSED {
+		return data, nil
+	}
+	decompressor, err := compress.GetDecompressor(compression)
+	if err != nil {
+		return nil, err
+	}
+	return decompressor.Decompress(data)
 }
 
 func (r *SeriesReader) hasNextPageReader() bool {
@@ -83,8 +120,17 @@ func (r *SeriesReader) nextPageReader() error{
 	if r.PageIndex >= r.PageLimit {
 		return errors.New("page exhausted")
 	}
-	r.PageReader = &PageDataReader{DataType: r.DType, ValueDecoder: decoder.CreateDecoder(r.Encoding, r.DType),
-		TimeDecoder: decoder.CreateDecoder(constant.TS_2DIFF, constant.INT64)}
-	r.PageReader.Read(r.FileReader.ReadRaw(r.Offsets[r.PageIndex], r.Sizes[r.PageIndex]))
+	pageReader, err := NewPageDataReader(r.DType, r.Encodings[r.PageIndex], r.TimeEncodings[r.PageIndex])
+	var data []byte
+	if err == nil {
+		data, err = r.ReadPageData()
+	}
+	if err != nil {
+		r.PageReader = nil
+		r.pageErr = err
+		return err
+	}
+	r.PageReader = pageReader
+	r.PageReader.Read(data)
 	return nil
 }
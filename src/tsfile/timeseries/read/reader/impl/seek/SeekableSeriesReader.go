@@ -2,7 +2,6 @@ package seek
 
 import (
 	"tsfile/common/constant"
-	"tsfile/encoding/decoder"
 	"tsfile/file/header"
 	"tsfile/timeseries/read"
 	"tsfile/timeseries/read/datatype"
@@ -35,7 +34,10 @@ func (r *SeekableSeriesReader) Seek(timestamp int64) bool {
 	if pageChanged {
 		if r.PageIndex < r.PageLimit {
 			r.PageIndex--
-			r.nextPageReader()
+			if err := r.nextPageReader(); err != nil {
+				log.Error("cannot read next page: %s", err)
+				return false
+			}
 		} else {
 			return false
 		}
@@ -43,16 +45,21 @@ func (r *SeekableSeriesReader) Seek(timestamp int64) bool {
 
 	// seek within this page
 	if r.current == nil {
-		if r.HasNext() {
-			r.Next()
-		} else {
+		if !r.HasNext() {
+			return false
+		}
+		if _, err := r.Next(); err != nil {
+			log.Error("cannot read next value: %s", err)
 			return false
 		}
 	}
 	for {
 		if r.current.Timestamp < timestamp {
 			if r.HasNext() {
-				r.Next()
+				if _, err := r.Next(); err != nil {
+					log.Error("cannot read next value: %s", err)
+					return false
+				}
 				continue
 			} else {
 				return false
@@ -69,9 +76,9 @@ func (r *SeekableSeriesReader) Current() *datatype.TimeValuePair {
 	return r.current
 }
 
-func NewSeekableSeriesReader(offsets []int64, sizes []int, reader *read.TsFileSequenceReader, pageHeaders []*header.PageHeader, dType constant.TSDataType, encoding constant.TSEncoding) *SeekableSeriesReader {
-	return &SeekableSeriesReader{&basic.SeriesReader{-1, len(offsets),
-		offsets, sizes, reader, nil, dType, encoding}, pageHeaders, nil, false}
+func NewSeekableSeriesReader(offsets []int64, sizes []int, reader *read.TsFileSequenceReader, pageHeaders []*header.PageHeader, dType constant.TSDataType, encodings []constant.TSEncoding, timeEncodings []constant.TSEncoding, compressions []constant.CompressionType) *SeekableSeriesReader {
+	return &SeekableSeriesReader{basic.NewSeriesReader(offsets, sizes, reader, dType, encodings, timeEncodings, compressions),
+		pageHeaders, nil, false}
 }
 
 func (r *SeekableSeriesReader) hasNextPageReader() bool {
@@ -83,9 +90,18 @@ func (r *SeekableSeriesReader) nextPageReader() error {
 	if r.PageIndex >= r.PageLimit {
 		return errors.New("page exhausted")
 	}
-	r.PageReader = &SeekablePageDataReader{&basic.PageDataReader{DataType: r.DType, ValueDecoder: decoder.CreateDecoder(r.Encoding, r.DType),
-		TimeDecoder: decoder.CreateDecoder(constant.TS_2DIFF, constant.INT64)}, nil}
-	r.PageReader.Read(r.FileReader.ReadRaw(r.Offsets[r.PageIndex], r.Sizes[r.PageIndex]))
+	pageReader, err := basic.NewPageDataReader(r.DType, r.Encodings[r.PageIndex], r.TimeEncodings[r.PageIndex])
+	var data []byte
+	if err == nil {
+		data, err = r.ReadPageData()
+	}
+	if err != nil {
+		r.PageReader = nil
+		r.exhausted = true
+		return err
+	}
+	r.PageReader = &SeekablePageDataReader{pageReader, nil}
+	r.PageReader.Read(data)
 	return nil
 }
 
@@ -98,7 +114,7 @@ func (r *SeekableSeriesReader) HasNext() bool {
 			return true
 		} else if r.PageIndex < r.PageLimit-1 {
 			if err := r.nextPageReader(); err != nil {
-				log.Error("cannot read next page", err)
+				log.Error("cannot read next page: %v", err)
 				r.exhausted = true
 				return false
 			}
@@ -107,7 +123,10 @@ func (r *SeekableSeriesReader) HasNext() bool {
 			return false
 		}
 	} else if r.PageIndex < r.PageLimit-1 {
-		r.nextPageReader()
+		if err := r.nextPageReader(); err != nil {
+			log.Error("cannot read next page: %s", err)
+			return false
+		}
 		return r.HasNext()
 	}
 	return false
@@ -120,12 +139,16 @@ func (r *SeekableSeriesReader) Next() (*datatype.TimeValuePair, error) {
 	if r.PageReader.HasNext() {
 		tv, err := r.PageReader.Next()
 		if err != nil {
+			r.exhausted = true
 			return nil, err
 		}
 		r.current = tv
 		return r.current, nil
 	} else {
-		r.nextPageReader()
+		if err := r.nextPageReader(); err != nil {
+			r.exhausted = true
+			return nil, err
+		}
 		return r.Next()
 	}
 }
@@ -19,6 +19,10 @@ type FileSchema struct {
 	currentMaxByteSizeInOneRow int
 	tsMetaData                 map[string]*metadata.TimeSeriesMetaData
 	sensorDataTypeMap          map[string]int16
+	// sensors registered for one device only, they shadow the global ones
+	deviceSensorMap  map[string]map[string]*sensorDescriptor.SensorDescriptor
+	deviceTsMetaData map[string]map[string]*metadata.TimeSeriesMetaData
+	templates        map[string][]*sensorDescriptor.SensorDescriptor
 }
 
 func (f *FileSchema) AddTimeSeriesMetaData(sensorId string, tsDataType int16) {
@@ -34,6 +38,26 @@ func (f *FileSchema) GetSensorDescriptiorMap() map[string]*sensorDescriptor.Sens
 	return f.sensorDescriptorMap
 }
 
+func (f *FileSchema) GetDeviceSensorDescriptorMap() map[string]map[string]*sensorDescriptor.SensorDescriptor {
+	return f.deviceSensorMap
+}
+
+func (f *FileSchema) GetDeviceTimeSeriesMetaDatas() map[string]map[string]*metadata.TimeSeriesMetaData {
+	return f.deviceTsMetaData
+}
+
+// GetSensorDescriptor returns the sensor of the device, a sensor registered
+// for the device wins over the global one with the same id.
+func (f *FileSchema) GetSensorDescriptor(deviceId string, sensorId string) (*sensorDescriptor.SensorDescriptor, bool) {
+	if sensors, ok := f.deviceSensorMap[deviceId]; ok {
+		if sd, ok := sensors[sensorId]; ok {
+			return sd, true
+		}
+	}
+	sd, ok := f.sensorDescriptorMap[sensorId]
+	return sd, ok
+}
+
 func (f *FileSchema) GetCurrentRowMaxSize() int {
 	return f.currentMaxByteSizeInOneRow
 }
@@ -57,11 +81,58 @@ func (f *FileSchema) Registermeasurement(sd *sensorDescriptor.SensorDescriptor)
 	return true
 }
 
+// RegisterDeviceMeasurement adds a sensor to the schema of one device.
+func (f *FileSchema) RegisterDeviceMeasurement(deviceId string, sd *sensorDescriptor.SensorDescriptor) bool {
+	sensors, ok := f.deviceSensorMap[deviceId]
+	if !ok {
+		sensors = make(map[string]*sensorDescriptor.SensorDescriptor)
+		f.deviceSensorMap[deviceId] = sensors
+		f.deviceTsMetaData[deviceId] = make(map[string]*metadata.TimeSeriesMetaData)
+	}
+	if _, exist := sensors[sd.GetSensorId()]; !exist && sd.GetTimeEncoder() != nil && sd.GetValueEncoder() != nil {
+		f.enlargeMaxByteSizeInOneRow(sd.GetTimeEncoder().GetOneItemMaxSize() + sd.GetValueEncoder().GetOneItemMaxSize())
+	}
+	sensors[sd.GetSensorId()] = sd
+	ts, _ := metadata.NewTimeSeriesMetaData(sd.GetSensorId(), sd.GetTsDataType())
+	f.deviceTsMetaData[deviceId][sd.GetSensorId()] = ts
+	return true
+}
+
+// RegisterTemplate names a set of sensors, which can be applied to many
+// devices with ApplyTemplate.
+func (f *FileSchema) RegisterTemplate(name string, sds []*sensorDescriptor.SensorDescriptor) {
+	f.templates[name] = sds
+}
+
+func (f *FileSchema) GetTemplates() map[string][]*sensorDescriptor.SensorDescriptor {
+	return f.templates
+}
+
+func (f *FileSchema) GetTemplate(name string) ([]*sensorDescriptor.SensorDescriptor, bool) {
+	sds, ok := f.templates[name]
+	return sds, ok
+}
+
+// ApplyTemplate registers the sensors of the template for the device.
+func (f *FileSchema) ApplyTemplate(name string, deviceId string) bool {
+	sds, ok := f.templates[name]
+	if !ok {
+		return false
+	}
+	for _, sd := range sds {
+		f.RegisterDeviceMeasurement(deviceId, sd)
+	}
+	return true
+}
+
 func New() (*FileSchema, error) {
 	return &FileSchema{
 		sensorDescriptorMap:  make(map[string]*sensorDescriptor.SensorDescriptor),
 		additionalProperties: make(map[string]string),
 		tsMetaData:           make(map[string]*metadata.TimeSeriesMetaData),
 		sensorDataTypeMap:    make(map[string]int16),
+		deviceSensorMap:      make(map[string]map[string]*sensorDescriptor.SensorDescriptor),
+		deviceTsMetaData:     make(map[string]map[string]*metadata.TimeSeriesMetaData),
+		templates:            make(map[string][]*sensorDescriptor.SensorDescriptor),
 	}, nil
 }
@@ -20,9 +20,13 @@ type SensorDescriptor struct {
 	sensorId           string
 	tsDataType         int16
 	tsEncoding         int16
+	timeEncoding       int16
 	timeCount          int
 	compressor         *compress.Encompress
 	tsCompresstionType int16
+	compressionLevel   int
+	lossyFilterType    int16
+	lossyParams        map[string]string
 
 	//typeConverter		TsDataTypeConverter
 	//encodingConverter	TsEncodingConverter
@@ -52,27 +56,62 @@ func (s *SensorDescriptor) GetTsEncoding() int16 {
 	return s.tsEncoding
 }
 
+// GetTimeEncoding returns the encoding of the timestamps, conf.TimeSeriesEncoder unless set
+func (s *SensorDescriptor) GetTimeEncoding() int16 {
+	return s.timeEncoding
+}
+
+func (s *SensorDescriptor) SetTimeEncoding(te constant.TSEncoding) {
+	s.timeEncoding = int16(te)
+}
+
 func (s *SensorDescriptor) GetCompresstionType() int16 {
 	return s.tsCompresstionType
 }
 
+// GetCompressionLevel returns the level of the compressor, 0 means its default level
+func (s *SensorDescriptor) GetCompressionLevel() int {
+	return s.compressionLevel
+}
+
+func (s *SensorDescriptor) SetCompressionLevel(level int) {
+	s.compressionLevel = level
+}
+
+// SetLossyFilter makes the values pass the SDT, PAA or PLA filter before they are
+// encoded, see compress.NewLossyFilter for the parameters. UNCOMPRESSED removes it.
+func (s *SensorDescriptor) SetLossyFilter(ft constant.CompressionType, params map[string]string) {
+	s.lossyFilterType = int16(ft)
+	s.lossyParams = params
+}
+
+// GetLossyFilter returns the lossy filter type and its parameters, UNCOMPRESSED if none
+func (s *SensorDescriptor) GetLossyFilter() (int16, map[string]string) {
+	return s.lossyFilterType, s.lossyParams
+}
+
 // the return type should be Compressor, after finished Compressor we should modify it.
 func (s *SensorDescriptor) GetCompressor() *compress.Encompress {
 	return s.compressor
 }
 
+// GetTimeEncoder returns nil if the time encoding has no encoder
 func (s *SensorDescriptor) GetTimeEncoder() encoder.Encoder {
-	return encoder.GetEncoder(int16(constant.GetEncodingByName(conf.TimeSeriesEncoder)), int16(constant.INT64))
+	timeEncoder, _ := encoder.GetEncoder(s.timeEncoding, int16(constant.INT64))
+	return timeEncoder
 }
 
+// GetValueEncoder returns nil if the encoding has no encoder of the data type
 func (s *SensorDescriptor) GetValueEncoder() encoder.Encoder {
-	return encoder.GetEncoder(s.GetTsEncoding(), s.GetTsDataType())
+	valueEncoder, _ := encoder.GetEncoder(s.GetTsEncoding(), s.GetTsDataType())
+	return valueEncoder
 }
 
 func (s *SensorDescriptor) Close() bool {
 	return true
 }
 
+// New describes an uncompressed sensor, NewWithCompress takes the compression.
 func New(sId string, tdt constant.TSDataType, te constant.TSEncoding) (*SensorDescriptor, error) {
 	// init compressor
 	enCompressor := new(compress.Encompress)
@@ -80,8 +119,10 @@ func New(sId string, tdt constant.TSDataType, te constant.TSEncoding) (*SensorDe
 		sensorId:           sId,
 		tsDataType:         int16(tdt),
 		tsEncoding:         int16(te),
+		timeEncoding:       int16(constant.GetEncodingByName(conf.TimeSeriesEncoder)),
 		compressor:         enCompressor,
 		tsCompresstionType: int16(constant.UNCOMPRESSED),
+		compressionLevel:   conf.CompressionLevel,
 		timeCount:          -1,
 	}, nil
 }
@@ -93,8 +134,10 @@ func NewWithCompress(sId string, tdt constant.TSDataType, te constant.TSEncoding
 		sensorId:           sId,
 		tsDataType:         int16(tdt),
 		tsEncoding:         int16(te),
+		timeEncoding:       int16(constant.GetEncodingByName(conf.TimeSeriesEncoder)),
 		compressor:         enCompressor,
 		tsCompresstionType: int16(tct),
+		compressionLevel:   conf.CompressionLevel,
 		timeCount:          -1,
 	}, nil
 }
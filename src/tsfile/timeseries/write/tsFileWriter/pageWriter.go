@@ -15,6 +15,7 @@ import (
 	"tsfile/common/log"
 	"tsfile/compress"
 	"tsfile/file/header"
+	"tsfile/file/metadata"
 	"tsfile/file/metadata/statistics"
 	"tsfile/timeseries/write/sensorDescriptor"
 )
@@ -24,11 +25,12 @@ type PageWriter struct {
 	desc            *sensorDescriptor.SensorDescriptor
 	pageBuf         *bytes.Buffer
 	totalValueCount int64
-	maxTimestamp    int64
-	minTimestamp    int64
+	// checksums of the pages in pageBuf
+	pageChecksums []metadata.Checksum
 }
 
 func (p *PageWriter) WritePageHeaderAndDataIntoBuff(dataBuffer *bytes.Buffer, valueCount int, sts statistics.Statistics, maxTimestamp int64, minTimestamp int64) int {
+	start := p.pageBuf.Len()
 	if p.desc.GetCompresstionType() == int16(constant.UNCOMPRESSED) {
 		//this uncompressedSize should be calculate from timeBuf and valueBuf
 		uncompressedSize := dataBuffer.Len()
@@ -38,7 +40,7 @@ func (p *PageWriter) WritePageHeaderAndDataIntoBuff(dataBuffer *bytes.Buffer, va
 			int32(valueCount), sts, maxTimestamp,
 			minTimestamp, p.desc.GetTsDataType())
 		if pageHeaderErr != nil {
-			log.Error("init pageHeader error: ", pageHeaderErr)
+			log.Error("init pageHeader error: %v", pageHeaderErr)
 		}
 		pageBuf := p.pageBuf
 		//pageHeader.PageHeaderToMemory(p.pageBuf, p.desc.GetTsDataType())
@@ -64,12 +66,17 @@ func (p *PageWriter) WritePageHeaderAndDataIntoBuff(dataBuffer *bytes.Buffer, va
 		var compressedSize int
 		var enc []byte
 		aSlice := make([]byte, 0)
-		enc = p.compressor.GetEncompressor(p.desc.GetCompresstionType()).Encompress(aSlice, dataSlice)
+		encompressor, err := p.compressor.GetEncompressor(p.desc.GetCompresstionType(), p.desc.GetCompressionLevel())
+		if err != nil {
+			// AddSensor rejects the sensors without a compressor
+			panic(err)
+		}
+		enc = encompressor.Encompress(aSlice, dataSlice)
 		compressedSize = len(enc)
 
 		pageHeader, pageHeaderErr := header.NewPageHeader(int32(uncompressedSize), int32(compressedSize), int32(valueCount), sts, maxTimestamp, minTimestamp, p.desc.GetTsDataType())
 		if pageHeaderErr != nil {
-			log.Error("init pageHeader error: ", pageHeaderErr)
+			log.Error("init pageHeader error: %v", pageHeaderErr)
 		}
 		// write pageheader to pageBuf
 		//log.Info("start to flush a page header into buffer, buf pos: %d", p.pageBuf.Len())
@@ -87,16 +94,19 @@ func (p *PageWriter) WritePageHeaderAndDataIntoBuff(dataBuffer *bytes.Buffer, va
 		//log.Info("finished to flush a page data into buffer, buf pos: %d", p.pageBuf.Len())
 		p.totalValueCount += int64(valueCount)
 	}
+	p.addPageChecksum(start)
 	return 0
 }
 
 func (p *PageWriter) WriteAllPagesOfSeriesToTsFile(tsFileIoWriter *TsFileIoWriter, seriesStatistics statistics.Statistics, numOfPage int) int64 {
-	if p.minTimestamp == -1 {
-		log.Error("Write page error, minTime: %s, maxTime: %s")
+	if seriesStatistics.IsEmpty() {
+		log.Error("Write page error, no value in the chunk of %s", p.desc.GetSensorId())
 	}
 	// write trunk header to file
-	chunkHeaderSize := tsFileIoWriter.StartFlushChunk(p.desc, p.desc.GetCompresstionType(), p.desc.GetTsDataType(), p.desc.GetTsEncoding(), seriesStatistics, p.maxTimestamp, p.minTimestamp, p.pageBuf.Len(), numOfPage)
+	chunkHeaderSize := tsFileIoWriter.StartFlushChunk(p.desc, p.desc.GetCompresstionType(), p.desc.GetTsDataType(), p.desc.GetTsEncoding(),
+		seriesStatistics, seriesStatistics.LastTime(), seriesStatistics.FirstTime(), p.pageBuf.Len(), numOfPage)
 	preSize := tsFileIoWriter.GetPos()
+	tsFileIoWriter.addPageChecksums(p.pageChecksums)
 	// write all pages to file
 	tsFileIoWriter.WriteBytesToFile(p.pageBuf)
 	//// after write page, reset pageBuf
@@ -107,8 +117,13 @@ func (p *PageWriter) WriteAllPagesOfSeriesToTsFile(tsFileIoWriter *TsFileIoWrite
 	return chunkSize
 }
 
+// addPageChecksum records the checksum of the page written to pageBuf from start
+func (p *PageWriter) addPageChecksum(start int) {
+	p.pageChecksums = append(p.pageChecksums, metadata.NewChecksum(p.pageBuf.Bytes()[start:]))
+}
+
 func (p *PageWriter) Reset() {
-	p.minTimestamp = -1
+	p.pageChecksums = nil
 	p.pageBuf.Reset()
 	p.totalValueCount = 0
 	return
@@ -0,0 +1,255 @@
+package tsFileWriter
+
+import (
+	"errors"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"tsfile/common/log"
+	"tsfile/common/utils"
+	"tsfile/timeseries/write/fileSchema"
+	"tsfile/timeseries/write/sensorDescriptor"
+)
+
+const (
+	// placeholders of the RollingWriter name template
+	ROLLING_INDEX = "{index}"
+	ROLLING_TIME  = "{time}"
+
+	// the size of the file costs a seek and a walk over the buffered data, it
+	// is checked after every flush and every ROLLING_SIZE_CHECK_RECORDS records
+	ROLLING_SIZE_CHECK_RECORDS = 100
+)
+
+// RollingPolicy tells when a RollingWriter seals the current file and starts
+// the next one. A zero threshold is not checked.
+type RollingPolicy struct {
+	// size of the file including the data buffered in memory, in bytes
+	MaxFileSize int64
+	// distance between the first timestamp of the file and the incoming one,
+	// a record at exactly the first timestamp plus MaxTimeSpan starts a new file
+	MaxTimeSpan int64
+	// number of data points written to the file
+	MaxPointCount int64
+}
+
+// RollingWriter writes records to a sequence of tsfiles. The files are named
+// after a template, ROLLING_INDEX is replaced by the sequence number of the
+// file and ROLLING_TIME by the first timestamp written into it. Every file
+// gets the schema of the RollingWriter, its sensors, per device sensors and
+// templates.
+type RollingWriter struct {
+	nameTemplate string
+	policy       RollingPolicy
+	schema       *fileSchema.FileSchema
+	sealedHook   func(path string)
+
+	tsFileWriter *TsFileWriter
+	currentPath  string
+	index        int
+	// the time of the first record of the next file, by MaxTimeSpan
+	rollTime   int64
+	pointCount int64
+	// records written until the size of the file is checked again
+	recordsToSizeCheck int
+}
+
+// AddSensor adds a sensor to all devices, see TsFileWriter.AddSensor.
+func (r *RollingWriter) AddSensor(sd *sensorDescriptor.SensorDescriptor) error {
+	if err := checkSensor(sd); err != nil {
+		return err
+	}
+	if r.tsFileWriter != nil {
+		if err := r.tsFileWriter.AddSensor(sd); err != nil {
+			return err
+		}
+	}
+	r.schema.Registermeasurement(sd)
+	return nil
+}
+
+// AddDeviceSensor adds a sensor to one device only, see
+// TsFileWriter.AddDeviceSensor.
+func (r *RollingWriter) AddDeviceSensor(deviceId string, sd *sensorDescriptor.SensorDescriptor) error {
+	if err := checkSensor(sd); err != nil {
+		return err
+	}
+	if r.tsFileWriter != nil {
+		if err := r.tsFileWriter.AddDeviceSensor(deviceId, sd); err != nil {
+			return err
+		}
+	}
+	r.schema.RegisterDeviceMeasurement(deviceId, sd)
+	return nil
+}
+
+// RegisterTemplate names a set of sensors shared by many devices.
+func (r *RollingWriter) RegisterTemplate(name string, sds ...*sensorDescriptor.SensorDescriptor) {
+	if r.tsFileWriter != nil {
+		r.tsFileWriter.RegisterTemplate(name, sds...)
+	}
+	r.schema.RegisterTemplate(name, sds)
+}
+
+// ApplyTemplate adds the sensors of the template to every given device.
+func (r *RollingWriter) ApplyTemplate(name string, deviceIds ...string) bool {
+	sds, ok := r.schema.GetTemplate(name)
+	if !ok {
+		log.Error("template %s not found", name)
+		return false
+	}
+	for _, sd := range sds {
+		if err := checkSensor(sd); err != nil {
+			log.Error("template %s: %s", name, err)
+			return false
+		}
+	}
+	if r.tsFileWriter != nil && !r.tsFileWriter.ApplyTemplate(name, deviceIds...) {
+		return false
+	}
+	for _, deviceId := range deviceIds {
+		r.schema.ApplyTemplate(name, deviceId)
+	}
+	return true
+}
+
+// SetSealedHook sets the function called with the path of every sealed file.
+func (r *RollingWriter) SetSealedHook(hook func(path string)) {
+	r.sealedHook = hook
+}
+
+func (r *RollingWriter) GetCurrentPath() string {
+	return r.currentPath
+}
+
+func (r *RollingWriter) Write(tr *TsRecord) error {
+	if r.tsFileWriter != nil && r.needRoll(tr) {
+		if err := r.seal(); err != nil {
+			return err
+		}
+	}
+	if r.tsFileWriter == nil {
+		if err := r.open(tr.GetTime()); err != nil {
+			return err
+		}
+	}
+	if r.tsFileWriter.Write(tr) {
+		// the buffered data moved into the file
+		r.recordsToSizeCheck = 0
+	} else {
+		r.recordsToSizeCheck--
+	}
+	r.pointCount += int64(len(tr.GetDataPointSli()))
+	return nil
+}
+
+// Close seals the current file, the RollingWriter can not be used afterwards.
+func (r *RollingWriter) Close() error {
+	if r.tsFileWriter == nil {
+		return nil
+	}
+	return r.seal()
+}
+
+func (r *RollingWriter) needRoll(tr *TsRecord) bool {
+	if r.policy.MaxPointCount > 0 && r.pointCount+int64(len(tr.GetDataPointSli())) > r.policy.MaxPointCount {
+		return true
+	}
+	if tr.GetTime() >= r.rollTime {
+		return true
+	}
+	if r.policy.MaxFileSize > 0 && r.recordsToSizeCheck <= 0 {
+		r.recordsToSizeCheck = ROLLING_SIZE_CHECK_RECORDS
+		fileSize := r.tsFileWriter.tsFileIoWriter.GetPos() + r.tsFileWriter.CalculateMemSizeForAllGroup()
+		if fileSize >= r.policy.MaxFileSize {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RollingWriter) open(firstTime int64) error {
+	path := strings.Replace(r.nameTemplate, ROLLING_INDEX, strconv.Itoa(r.index), -1)
+	path = strings.Replace(path, ROLLING_TIME, strconv.FormatInt(firstTime, 10), -1)
+	// a restarted writer or two files of the same time must not append to a
+	// sealed file
+	if err := checkOutFile("rolling", path); err != nil {
+		return err
+	}
+	tsFileWriter, err := NewTsFileWriter(path)
+	if err != nil {
+		return err
+	}
+	if err := r.applySchema(tsFileWriter); err != nil {
+		tsFileWriter.Close()
+		os.Remove(path)
+		return err
+	}
+	r.tsFileWriter = tsFileWriter
+	r.currentPath = path
+	r.rollTime = math.MaxInt64
+	if r.policy.MaxTimeSpan > 0 && firstTime <= math.MaxInt64-r.policy.MaxTimeSpan {
+		r.rollTime = firstTime + r.policy.MaxTimeSpan
+	}
+	r.pointCount = 0
+	r.recordsToSizeCheck = 0
+	r.index++
+	return nil
+}
+
+// applySchema gives the writer of a new file the sensors and templates added
+// so far, in a fixed order.
+func (r *RollingWriter) applySchema(tsFileWriter *TsFileWriter) error {
+	templates := r.schema.GetTemplates()
+	for _, name := range utils.SortedKeys(templates) {
+		tsFileWriter.RegisterTemplate(name, templates[name]...)
+	}
+	sensors := r.schema.GetSensorDescriptiorMap()
+	for _, sensorId := range utils.SortedKeys(sensors) {
+		if err := tsFileWriter.AddSensor(sensors[sensorId]); err != nil {
+			return err
+		}
+	}
+	deviceSensors := r.schema.GetDeviceSensorDescriptorMap()
+	for _, deviceId := range utils.SortedKeys(deviceSensors) {
+		for _, sensorId := range utils.SortedKeys(deviceSensors[deviceId]) {
+			if err := tsFileWriter.AddDeviceSensor(deviceId, deviceSensors[deviceId][sensorId]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// seal closes the current file, the buffered row groups are flushed before
+// the footer so the file ends at a row group boundary.
+func (r *RollingWriter) seal() error {
+	path := r.currentPath
+	ok := r.tsFileWriter.Close()
+	r.tsFileWriter = nil
+	r.currentPath = ""
+	if !ok {
+		return errors.New("rolling: cannot seal " + path)
+	}
+	log.Info("rolling: sealed %s", path)
+	if r.sealedHook != nil {
+		r.sealedHook(path)
+	}
+	return nil
+}
+
+func NewRollingWriter(nameTemplate string, policy RollingPolicy) (*RollingWriter, error) {
+	if !strings.Contains(nameTemplate, ROLLING_INDEX) && !strings.Contains(nameTemplate, ROLLING_TIME) {
+		return nil, errors.New("rolling: name template needs " + ROLLING_INDEX + " or " + ROLLING_TIME)
+	}
+	schema, err := fileSchema.New()
+	if err != nil {
+		return nil, err
+	}
+	return &RollingWriter{
+		nameTemplate: nameTemplate,
+		policy:       policy,
+		schema:       schema,
+	}, nil
+}
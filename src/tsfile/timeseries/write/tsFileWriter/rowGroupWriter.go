@@ -10,7 +10,7 @@ package tsFileWriter
 
 import (
 	"tsfile/common/log"
-	_ "tsfile/common/utils"
+	"tsfile/common/utils"
 	"tsfile/file/header"
 	"tsfile/timeseries/write/sensorDescriptor"
 )
@@ -63,8 +63,9 @@ func (r *RowGroupWriter) Write(t int64, data []*DataPoint) {
 }
 
 func (r *RowGroupWriter) FlushToFileWriter(tsFileIoWriter *TsFileIoWriter) {
-	for _, v := range r.dataSeriesWriters {
-		v.WriteToFileWriter(tsFileIoWriter)
+	// chunks in the order of the sensors
+	for _, sensorId := range utils.SortedKeys(r.dataSeriesWriters) {
+		r.dataSeriesWriters[sensorId].WriteToFileWriter(tsFileIoWriter)
 	}
 	return
 }
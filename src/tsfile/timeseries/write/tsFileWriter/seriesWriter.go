@@ -10,9 +10,11 @@ package tsFileWriter
 
 import (
 	"encoding/binary"
+	"math"
 	"tsfile/common/conf"
 	"tsfile/common/constant"
 	"tsfile/common/log"
+	"tsfile/compress"
 	"tsfile/file/header"
 	"tsfile/file/metadata/statistics"
 	"tsfile/timeseries/write/sensorDescriptor"
@@ -28,6 +30,8 @@ type SeriesWriter struct {
 	/* page size threshold 	*/
 	psThres             int
 	pageCountUpperBound int
+	/* lossy filter the values pass before encoding, nil if none */
+	lossyFilter compress.LossyFilter
 	/* value writer to encode data*/
 	valueWriter ValueWriter
 	/* value count on a page. It will be reset agter calling */
@@ -63,6 +67,17 @@ func (s *SeriesWriter) GetCurrentChunkSize(sId string) int {
 }
 
 func (s *SeriesWriter) Write(t int64, data *DataPoint) bool {
+	if s.lossyFilter == nil {
+		s.writePoint(t, data.value)
+		return true
+	}
+	for _, p := range s.lossyFilter.Filter(compress.LossyPoint{Time: t, Value: toFloat64(data.value)}) {
+		s.writePoint(p.Time, s.fromFloat64(p.Value))
+	}
+	return true
+}
+
+func (s *SeriesWriter) writePoint(t int64, value interface{}) {
 	s.time = t
 	//s.valueCount = s.valueCount + 1
 
@@ -70,21 +85,47 @@ func (s *SeriesWriter) Write(t int64, data *DataPoint) bool {
 	vw.timeEncoder.Encode(t, vw.timeBuf)
 	switch s.tsDataType {
 	case 0, 1, 2, 3, 4, 5:
-		vw.valueEncoder.Encode(data.value, vw.valueBuf)
+		vw.valueEncoder.Encode(value, vw.valueBuf)
 	default:
 	}
 	//s.valueWriter.Write(t, s.tsDataType, data, s.valueCount)
 	//logcost.CostWriteTimesTest5 += int64(time.Since(tsCurNew))
 	s.valueCount = s.valueCount + 1
 	// statistics ignore here, if necessary, Statistics.java
-	s.pageStatistics.UpdateStats(data.value)
+	s.pageStatistics.UpdateStats(t, value)
 
 	if s.minTimestamp == -1 {
 		s.minTimestamp = t
 	}
 	// check page size and write page data to buffer
 	s.checkPageSizeAndMayOpenNewpage()
-	return true
+}
+
+func toFloat64(value interface{}) float64 {
+	switch v := value.(type) {
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+// fromFloat64 converts a filtered value back to the data type of the series
+func (s *SeriesWriter) fromFloat64(v float64) interface{} {
+	switch constant.TSDataType(s.tsDataType) {
+	case constant.INT32:
+		return int32(math.Round(v))
+	case constant.INT64:
+		return int64(math.Round(v))
+	case constant.FLOAT:
+		return float32(v)
+	}
+	return v
 }
 
 func (s *SeriesWriter) WriteToFileWriter(tsFileIoWriter *TsFileIoWriter) {
@@ -115,6 +156,11 @@ func (s *SeriesWriter) checkPageSizeAndMayOpenNewpage() {
 }
 
 func (s *SeriesWriter) PreFlush() {
+	if s.lossyFilter != nil {
+		for _, p := range s.lossyFilter.Flush() {
+			s.writePoint(p.Time, s.fromFloat64(p.Value))
+		}
+	}
 	if s.valueCount > 0 {
 		s.WritePage()
 	}
@@ -128,6 +174,7 @@ func (s *SeriesWriter) EstimateMaxSeriesMemSize() int64 {
 
 func (s *SeriesWriter) WritePage() {
 	pageWriter := s.pageWriter
+	start := pageWriter.pageBuf.Len()
 	//pageWriter.WritePageHeaderAndDataIntoBuff(s.valueWriter.GetByteBuffer(),
 	//	s.valueCount, s.pageStatistics, s.time, s.minTimestamp)
 	dataBuffer := s.valueWriter.GetByteBuffer()
@@ -140,10 +187,10 @@ func (s *SeriesWriter) WritePage() {
 		var compressedSize int = uncompressedSize
 		pageHeader, pageHeaderErr := header.NewPageHeader(
 			int32(uncompressedSize), int32(compressedSize),
-			int32(valueCount), s.pageStatistics, s.time,
-			s.minTimestamp, pageWriter.desc.GetTsDataType())
+			int32(valueCount), s.pageStatistics, s.pageStatistics.LastTime(),
+			s.pageStatistics.FirstTime(), pageWriter.desc.GetTsDataType())
 		if pageHeaderErr != nil {
-			log.Error("init pageHeader error: ", pageHeaderErr)
+			log.Error("init pageHeader error: %v", pageHeaderErr)
 		}
 		pageBuf := pageWriter.pageBuf
 		//pageHeader.PageHeaderToMemory(p.pageBuf, p.desc.GetTsDataType())
@@ -169,16 +216,21 @@ func (s *SeriesWriter) WritePage() {
 		var compressedSize int
 		var enc []byte
 		aSlice := make([]byte, 0)
-		enc = pageWriter.compressor.GetEncompressor(
-			pageWriter.desc.GetCompresstionType()).Encompress(aSlice, dataSlice)
+		encompressor, err := pageWriter.compressor.GetEncompressor(
+			pageWriter.desc.GetCompresstionType(), pageWriter.desc.GetCompressionLevel())
+		if err != nil {
+			// AddSensor rejects the sensors without a compressor
+			panic(err)
+		}
+		enc = encompressor.Encompress(aSlice, dataSlice)
 		compressedSize = len(enc)
 
 		pageHeader, pageHeaderErr := header.NewPageHeader(
 			int32(uncompressedSize), int32(compressedSize), int32(valueCount),
-			s.pageStatistics, s.time, s.minTimestamp,
+			s.pageStatistics, s.pageStatistics.LastTime(), s.pageStatistics.FirstTime(),
 			pageWriter.desc.GetTsDataType())
 		if pageHeaderErr != nil {
-			log.Error("init pageHeader error: ", pageHeaderErr)
+			log.Error("init pageHeader error: %v", pageHeaderErr)
 		}
 		// write pageheader to pageBuf
 		pageHeader.PageHeaderToMemory(pageWriter.pageBuf,
@@ -192,7 +244,10 @@ func (s *SeriesWriter) WritePage() {
 		pageWriter.totalValueCount += int64(valueCount)
 	}
 
+	pageWriter.addPageChecksum(start)
+
 	// pageStatistics
+	s.seriesStatistics.Merge(s.pageStatistics)
 	s.numOfPages += 1
 
 	s.minTimestamp = -1
@@ -209,6 +264,13 @@ func (s *SeriesWriter) ResetPageStatistics() {
 
 func NewSeriesWriter(dId string, d *sensorDescriptor.SensorDescriptor, pw *PageWriter, pst int) (*SeriesWriter, error) {
 	vw, _ := NewValueWriter(d)
+	var lossyFilter compress.LossyFilter
+	if filterType, params := d.GetLossyFilter(); filterType != int16(constant.UNCOMPRESSED) {
+		var err error
+		if lossyFilter, err = compress.NewLossyFilter(constant.CompressionType(filterType), params); err != nil {
+			return nil, err
+		}
+	}
 	return &SeriesWriter{
 		deviceId:                   dId,
 		desc:                       d,
@@ -222,6 +284,7 @@ func NewSeriesWriter(dId string, d *sensorDescriptor.SensorDescriptor, pw *PageW
 		seriesStatistics:           statistics.GetStatsByType(d.GetTsDataType()),
 		pageStatistics:             statistics.GetStatsByType(d.GetTsDataType()),
 		valueWriter:                *vw,
+		lossyFilter:                lossyFilter,
 		minTimestamp:               -1,
 		valueCount:                 0,
 	}, nil
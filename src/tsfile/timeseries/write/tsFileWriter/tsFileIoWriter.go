@@ -10,8 +10,10 @@ package tsFileWriter
 
 import (
 	"bytes"
+	"io"
 	"os"
 	"tsfile/common/conf"
+	"tsfile/common/constant"
 	"tsfile/common/log"
 	"tsfile/common/utils"
 	"tsfile/file/header"
@@ -29,14 +31,20 @@ type TsFileIoWriter struct {
 	rowGroupMetaDataSli     []*metadata.RowGroupMetaData
 	rowGroupHeader          *header.RowGroupHeader
 	chunkHeader             *header.ChunkHeader
+	// checksums of the headers and pages written, by their offset
+	checksums map[int64]metadata.Checksum
+	// version of the format of the footer written by EndFile
+	version int
+	// the first error writing the file, the file is broken after it
+	writeErr error
 }
 
 const (
-	MAXVALUE = "max_value"
-	MINVALUE = "min_value"
-	FIRST    = "first"
-	SUM      = "sum"
-	LAST     = "last"
+	MAXVALUE = metadata.MAX_VALUE
+	MINVALUE = metadata.MIN_VALUE
+	FIRST    = metadata.FIRST
+	SUM      = metadata.SUM
+	LAST     = metadata.LAST
 )
 
 func (t *TsFileIoWriter) GetTsIoFile() *os.File {
@@ -97,7 +105,12 @@ func (t *TsFileIoWriter) EndFile(fs fileSchema.FileSchema) {
 		tsDeviceMetaData.SetStartTime(startTime)
 		tsDeviceMetaData.SetEndTime(endTime)
 	}
-	tsFileMetaData, _ := metadata.NewTsFileMetaData(tsDeviceMetaDataMap, timeSeriesMap, conf.CurrentVersion)
+	tsFileMetaData, _ := metadata.NewTsFileMetaData(tsDeviceMetaDataMap, timeSeriesMap, t.version)
+	tsFileMetaData.SetDeviceSchemaMap(fs.GetDeviceTimeSeriesMetaDatas())
+	tsFileMetaData.SetChecksums(t.checksums)
+	if t.version >= metadata.VERSION_4 && conf.BloomFilterErrorRate > 0 && conf.BloomFilterErrorRate < 1 {
+		tsFileMetaData.SetBloomFilter(seriesBloomFilter(tsDeviceMetaDataMap))
+	}
 	//footerIndex := t.GetPos()
 	//log.Info("start to flush meta, file pos: %d", footerIndex)
 	size := tsFileMetaData.SerializeTo(t.memBuf)
@@ -111,6 +124,27 @@ func (t *TsFileIoWriter) EndFile(fs fileSchema.FileSchema) {
 	log.Info("file pos: %d", t.GetPos())
 }
 
+// seriesBloomFilter adds the paths of the series with chunks in the file
+func seriesBloomFilter(tsDeviceMetaDataMap map[string]*metadata.DeviceMetaData) *metadata.BloomFilter {
+	paths := make(map[string]bool)
+	for deviceId, tsDeviceMetaData := range tsDeviceMetaDataMap {
+		for _, rowGroup := range tsDeviceMetaData.GetRowGroups() {
+			for _, chunkMetaData := range rowGroup.GetChunkMetaDataSli() {
+				paths[deviceId+constant.PATH_SEPARATOR+chunkMetaData.Sensor()] = true
+			}
+		}
+	}
+	bloomFilter := metadata.NewBloomFilter(conf.BloomFilterErrorRate, len(paths))
+	for path := range paths {
+		bloomFilter.Add(path)
+	}
+	return bloomFilter
+}
+
+func (t *TsFileIoWriter) Close() error {
+	return t.tsIoFile.Close()
+}
+
 func max(x, y int64) int64 {
 	if x < y {
 		return y
@@ -128,7 +162,7 @@ func min(x, y int64) int64 {
 func (t *TsFileIoWriter) WriteMagic() int {
 	n, err := t.tsIoFile.Write([]byte(conf.MAGIC_STRING))
 	if err != nil {
-		log.Error("write start magic to file err: ", err)
+		log.Error("write start magic to file err: %v", err)
 	}
 	return n
 }
@@ -142,6 +176,7 @@ func (t *TsFileIoWriter) StartFlushRowGroup(deviceId string, rowGroupSize int64,
 	//log.Info("rowGroupHeader: %v", rowGroupHeader)
 	rowGroupHeader.RowGroupHeaderToMemory(t.memBuf)
 	t.rowGroupHeader = rowGroupHeader
+	t.addChecksum(t.memBuf.Bytes())
 	// rowgroup header bytebuffer write to file
 	t.WriteBytesToFile(t.memBuf)
 	// truncate bytebuffer to empty
@@ -155,31 +190,63 @@ func (t *TsFileIoWriter) StartFlushChunk(sd *sensorDescriptor.SensorDescriptor,
 	maxTimestamp int64, minTimestamp int64, pageBufSize int, numOfPages int) int {
 	t.currentChunkMetaData, _ = metadata.NewTimeSeriesChunkMetaData(sd.GetSensorId(), t.GetPos(), minTimestamp, maxTimestamp)
 	chunkHeader, _ := header.NewChunkHeader(sd.GetSensorId(), pageBufSize, tsDataType, compressionType, encodingType, numOfPages, 0)
+	chunkHeader.SetTimeEncodingType(constant.TSEncoding(sd.GetTimeEncoding()))
 	chunkHeader.ChunkHeaderToMemory(t.memBuf)
 	t.chunkHeader = chunkHeader
+	t.addChecksum(t.memBuf.Bytes())
 	// chunk header bytebuffer write to file
 	t.WriteBytesToFile(t.memBuf)
 	// truncate bytebuffer to empty
 	t.memBuf.Reset()
 	// set tsdigest
+	tsDigest := newChunkDigest(statistics, tsDataType)
+	if filterType, params := sd.GetLossyFilter(); filterType != int16(constant.UNCOMPRESSED) {
+		tsDigest.SetLossyFilter(constant.CompressionType(filterType), params)
+	}
+	t.currentChunkMetaData.SetDigest(tsDigest)
+	return header.GetChunkSerializedSize(sd.GetSensorId())
+}
+
+// addChecksum records the checksum of the block written next
+func (t *TsFileIoWriter) addChecksum(block []byte) {
+	t.checksums[t.GetPos()] = metadata.NewChecksum(block)
+}
+
+// addPageChecksums records the checksums of the pages written next, one after another
+func (t *TsFileIoWriter) addPageChecksums(checksums []metadata.Checksum) {
+	offset := t.GetPos()
+	for _, checksum := range checksums {
+		t.checksums[offset] = checksum
+		offset += int64(checksum.Length)
+	}
+}
+
+// CopyChunk writes a chunk of another file as it is, with its header. The
+// chunk keeps the digest of its metadata and the checksums of its blocks,
+// given by their offset in the chunk.
+func (t *TsFileIoWriter) CopyChunk(data []byte, chunkMetaData *metadata.ChunkMetaData, checksums map[int64]metadata.Checksum) error {
+	pos := t.GetPos()
+	if _, err := t.tsIoFile.Write(data); err != nil {
+		if t.writeErr == nil {
+			t.writeErr = err
+		}
+		return err
+	}
+	for offset, checksum := range checksums {
+		t.checksums[pos+offset] = checksum
+	}
+	copied, _ := metadata.NewTimeSeriesChunkMetaData(chunkMetaData.Sensor(), pos,
+		chunkMetaData.GetStartTime(), chunkMetaData.GetEndTime())
+	copied.SetTotalByteSizeOfPagesOnDisk(int64(len(data)))
+	copied.SetNumOfPoints(chunkMetaData.GetNumOfPoints())
+	copied.SetDigest(chunkMetaData.GetDigest())
+	t.currentRowGroupMetaData.AddChunkMetaData(copied)
+	return nil
+}
+
+func newChunkDigest(statistics statistics.Statistics, tsDataType int16) *metadata.TsDigest {
 	tsDigest, _ := metadata.NewTsDigest()
 	statisticsMap := make(map[string]*bytes.Buffer)
-	//var max bytes.Buffer
-	//max.Write(statistics.GetMaxByte(tsDataType))
-	//statisticsMap[MAXVALUE] = max
-	//var min bytes.Buffer
-	//min.Write(statistics.GetMinByte(tsDataType))
-	//statisticsMap[MINVALUE] = min
-	//var first bytes.Buffer
-	//first.Write(statistics.GetFirstByte(tsDataType))
-	//statisticsMap[FIRST] = first
-	//var sum bytes.Buffer
-	//sum.Write(statistics.GetSumByte(tsDataType))
-	//statisticsMap[SUM] = sum
-	//var last bytes.Buffer
-	//last.Write(statistics.GetLastByte(tsDataType))
-	//statisticsMap[LAST] = last
-
 	var min bytes.Buffer
 	min.Write(statistics.GetMinByte(tsDataType))
 	statisticsMap[MINVALUE] = &min
@@ -197,28 +264,73 @@ func (t *TsFileIoWriter) StartFlushChunk(sd *sensorDescriptor.SensorDescriptor,
 	statisticsMap[MAXVALUE] = &max
 
 	tsDigest.SetStatistics(statisticsMap)
-	t.currentChunkMetaData.SetDigest(tsDigest)
-	return header.GetChunkSerializedSize(sd.GetSensorId())
+	return tsDigest
 }
 
-func (t *TsFileIoWriter) WriteBytesToFile(buf *bytes.Buffer) {
+func (t *TsFileIoWriter) WriteBytesToFile(buf *bytes.Buffer) error {
 	//声明一个空的slice,容量为timebuf的长度
 	timeSlice := make([]byte, buf.Len())
 	//把buf的内容读入到timeSlice内,因为timeSlice容量为timeSize,所以只读了timeSize个过来
 	buf.Read(timeSlice)
-	t.tsIoFile.Write(timeSlice)
-	return
+	_, err := t.tsIoFile.Write(timeSlice)
+	if err != nil && t.writeErr == nil {
+		t.writeErr = err
+	}
+	return err
+}
+
+// Err returns the first error writing the file
+func (t *TsFileIoWriter) Err() error {
+	return t.writeErr
 }
 
 func NewTsFileIoWriter(file string) (*TsFileIoWriter, error) {
 	newFile, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
 	if err != nil {
-		log.Info("open file:%s failed.", file)
+		return nil, err
 	}
 
 	return &TsFileIoWriter{
 		tsIoFile:            newFile,
 		memBuf:              bytes.NewBuffer([]byte{}),
 		rowGroupMetaDataSli: make([]*metadata.RowGroupMetaData, 0),
+		checksums:           make(map[int64]metadata.Checksum),
+		version:             defaultVersion(),
+	}, nil
+}
+
+// ResumeTsFileIoWriter opens an existing tsfile for writing more row groups.
+// Everything behind pos is cut off, rowGroups are the row groups kept before pos,
+// they go into the footer written by EndFile together with the new ones.
+func ResumeTsFileIoWriter(file string, pos int64, rowGroups []*metadata.RowGroupMetaData) (*TsFileIoWriter, error) {
+	oldFile, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, err
+	}
+	if err := oldFile.Truncate(pos); err != nil {
+		oldFile.Close()
+		return nil, err
+	}
+	// the offset of a file opened for appending is 0 until the first write
+	if _, err := oldFile.Seek(0, io.SeekEnd); err != nil {
+		oldFile.Close()
+		return nil, err
+	}
+
+	return &TsFileIoWriter{
+		tsIoFile:            oldFile,
+		memBuf:              bytes.NewBuffer([]byte{}),
+		rowGroupMetaDataSli: rowGroups,
+		checksums:           make(map[int64]metadata.Checksum),
+		version:             defaultVersion(),
 	}, nil
 }
+
+// defaultVersion is conf.CurrentVersion if it is supported
+func defaultVersion() int {
+	if err := metadata.CheckVersion(conf.CurrentVersion); err != nil {
+		log.Error("format_version: %s, writing %d", err, metadata.LATEST_VERSION)
+		return metadata.LATEST_VERSION
+	}
+	return conf.CurrentVersion
+}
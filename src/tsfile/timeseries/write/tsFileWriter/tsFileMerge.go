@@ -0,0 +1,362 @@
+package tsFileWriter
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"sort"
+	"tsfile/common/conf"
+	"tsfile/common/constant"
+	"tsfile/common/utils"
+	"tsfile/file/header"
+	"tsfile/file/metadata"
+	"tsfile/timeseries/read"
+	"tsfile/timeseries/write/sensorDescriptor"
+)
+
+// MergeReport is what MergeTsFiles wrote
+type MergeReport struct {
+	Files     int `json:"files"`
+	RowGroups int `json:"rowGroups"`
+	// CopiedChunks are written as they are, RewrittenChunks are the chunks
+	// decoded from overlapping ones and encoded again
+	CopiedChunks    int   `json:"copiedChunks"`
+	RewrittenChunks int   `json:"rewrittenChunks"`
+	Points          int64 `json:"points"`
+	// DuplicatePoints are the points dropped for a later one of the same time
+	DuplicatePoints int64 `json:"duplicatePoints"`
+}
+
+// MergeTsFiles writes the points of the sealed files, oldest first, into one
+// new file. The chunks of a device whose times overlap make one row group,
+// the row groups of a device are in the order of time. A chunk overlapping no
+// other chunk of its series is copied as it is if it is written like the
+// series, which takes the encodings and compression of the latest file. The
+// other chunks are decoded and encoded again, a point of a later file
+// replacing one of the same time of an earlier file. The out file must not
+// exist.
+func MergeTsFiles(files []string, out string) (*MergeReport, error) {
+	if len(files) == 0 {
+		return nil, errors.New("merge: no files")
+	}
+	if err := checkOutFile("merge", out, files...); err != nil {
+		return nil, err
+	}
+	m := &merger{report: &MergeReport{Files: len(files)}, chunks: make(map[string][]*mergeChunk)}
+	defer func() {
+		for _, source := range m.sources {
+			source.f.Close()
+		}
+	}()
+	for i, file := range files {
+		if err := m.open(i, file); err != nil {
+			return nil, err
+		}
+	}
+
+	writer, err := NewTsFileWriter(out)
+	if err != nil {
+		return nil, err
+	}
+	m.writer = writer
+	if err := m.addSensors(); err != nil {
+		writer.Close()
+		return nil, err
+	}
+	for _, device := range utils.SortedKeys(m.chunks) {
+		for _, segment := range overlappingChunks(m.chunks[device]) {
+			if err := m.writeRowGroup(device, segment); err != nil {
+				writer.Close()
+				return nil, err
+			}
+		}
+	}
+	if !writer.Close() {
+		return nil, errors.New("merge: cannot close " + out)
+	}
+	return m.report, nil
+}
+
+type merger struct {
+	sources []*mergeSource
+	// the chunks of every device
+	chunks map[string][]*mergeChunk
+	writer *TsFileWriter
+	report *MergeReport
+}
+
+type mergeSource struct {
+	index int
+	file  string
+	f     *read.TsFileSequenceReader
+	// the checksums of the footer and their offsets in order
+	checksums map[int64]metadata.Checksum
+	offsets   []int64
+}
+
+type mergeChunk struct {
+	source *mergeSource
+	device string
+	meta   *metadata.ChunkMetaData
+	header *header.ChunkHeader
+}
+
+// open reads the footer of the file and the headers of its chunks
+func (m *merger) open(index int, file string) error {
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(file); err != nil {
+		return errors.New("merge: " + err.Error())
+	}
+	fileMetaData := f.ReadFileMetadata()
+	source := &mergeSource{index: index, file: file, f: f, checksums: fileMetaData.GetChecksums()}
+	m.sources = append(m.sources, source)
+	for offset := range source.checksums {
+		source.offsets = append(source.offsets, offset)
+	}
+	sort.Slice(source.offsets, func(i, j int) bool { return source.offsets[i] < source.offsets[j] })
+
+	for _, device := range utils.SortedKeys(fileMetaData.DeviceMap()) {
+		for _, rowGroup := range fileMetaData.DeviceMap()[device].GetRowGroups() {
+			for _, chunkMetaData := range rowGroup.GetChunkMetaDataSli() {
+				chunkHeader := f.ReadChunkHeaderAt(chunkMetaData.FileOffsetOfCorrespondingData())
+				m.chunks[device] = append(m.chunks[device], &mergeChunk{source: source, device: device,
+					meta: chunkMetaData, header: chunkHeader})
+			}
+		}
+	}
+	return nil
+}
+
+// addSensors takes the encodings and the compression of a series from its
+// chunk of the latest file, a device differing from the first one of the
+// sensor gets its own sensor
+func (m *merger) addSensors() error {
+	first := make(map[string]*sensorDescriptor.SensorDescriptor)
+	for _, device := range utils.SortedKeys(m.chunks) {
+		latest := make(map[string]*mergeChunk)
+		for _, chunk := range m.chunks[device] {
+			sensor := chunk.header.GetSensor()
+			last, ok := latest[sensor]
+			if ok && last.header.GetDataType() != chunk.header.GetDataType() {
+				return fmt.Errorf("merge: %s%s%s is %s in %s and %s in %s", device, constant.PATH_SEPARATOR, sensor,
+					last.header.GetDataType(), last.source.file, chunk.header.GetDataType(), chunk.source.file)
+			}
+			// the chunks are in the order of the files
+			latest[sensor] = chunk
+		}
+		for _, sensor := range utils.SortedKeys(latest) {
+			sd := latest[sensor].sensor()
+			var err error
+			if sensorFirst, ok := first[sensor]; !ok {
+				if err = m.writer.AddSensor(sd); err == nil {
+					first[sensor] = sd
+				}
+			} else if !sameSensor(sensorFirst, sd) {
+				err = m.writer.AddDeviceSensor(device, sd)
+			}
+			if err != nil {
+				return errors.New("merge: " + err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// sensor describes how the chunk is written
+func (c *mergeChunk) sensor() *sensorDescriptor.SensorDescriptor {
+	sd := newRepairSensor(c.header)
+	if digest := c.meta.GetDigest(); digest != nil {
+		if filterType, params, ok := digest.GetLossyFilter(); ok {
+			sd.SetLossyFilter(filterType, params)
+		}
+	}
+	return sd
+}
+
+// sameSensor tells if the sensors write their chunks the same way
+func sameSensor(a *sensorDescriptor.SensorDescriptor, b *sensorDescriptor.SensorDescriptor) bool {
+	aFilter, aParams := a.GetLossyFilter()
+	bFilter, bParams := b.GetLossyFilter()
+	return a.GetTsDataType() == b.GetTsDataType() && a.GetTsEncoding() == b.GetTsEncoding() &&
+		a.GetTimeEncoding() == b.GetTimeEncoding() && a.GetCompresstionType() == b.GetCompresstionType() &&
+		aFilter == bFilter && maps.Equal(aParams, bParams)
+}
+
+// overlappingChunks groups the chunks of a device by their times, the chunks
+// of a group overlap one another directly or through other chunks of it
+func overlappingChunks(chunks []*mergeChunk) [][]*mergeChunk {
+	sort.SliceStable(chunks, func(i, j int) bool {
+		return chunks[i].meta.GetStartTime() < chunks[j].meta.GetStartTime()
+	})
+	var segments [][]*mergeChunk
+	var end int64
+	for _, chunk := range chunks {
+		if len(segments) == 0 || chunk.meta.GetStartTime() > end {
+			segments = append(segments, nil)
+			end = chunk.meta.GetEndTime()
+		}
+		segments[len(segments)-1] = append(segments[len(segments)-1], chunk)
+		end = max(end, chunk.meta.GetEndTime())
+	}
+	return segments
+}
+
+// writeRowGroup writes the chunks of a group, a series with one chunk in the
+// group written like the series is copied, the others are merged. The pages
+// of a series are all read with the encodings of the series.
+func (m *merger) writeRowGroup(device string, segment []*mergeChunk) error {
+	series := make(map[string][]*mergeChunk)
+	for _, chunk := range segment {
+		series[chunk.header.GetSensor()] = append(series[chunk.header.GetSensor()], chunk)
+	}
+	size := int64(header.GetRowGroupSerializedSize(device))
+	seriesWriters := make(map[string]*SeriesWriter)
+	for sensor, chunks := range series {
+		sd, _ := m.writer.schema.GetSensorDescriptor(device, sensor)
+		if len(chunks) == 1 && sameSensor(sd, chunks[0].sensor()) {
+			size += int64(chunks[0].header.GetSerializedSize() + chunks[0].header.GetDataSize())
+			continue
+		}
+		seriesWriter, err := m.mergeSeries(sd, chunks)
+		if err != nil {
+			return err
+		}
+		seriesWriters[sensor] = seriesWriter
+		size += int64(seriesWriter.GetCurrentChunkSize(sensor))
+	}
+
+	ioWriter := m.writer.tsFileIoWriter
+	start := ioWriter.GetPos()
+	ioWriter.StartFlushRowGroup(device, size, int32(len(series)))
+	for _, sensor := range utils.SortedKeys(series) {
+		if seriesWriter, ok := seriesWriters[sensor]; ok {
+			seriesWriter.WriteToFileWriter(ioWriter)
+			m.report.RewrittenChunks++
+			continue
+		}
+		chunk := series[sensor][0]
+		offset := chunk.meta.FileOffsetOfCorrespondingData()
+		data := chunk.source.f.ReadChunkAndHeader(offset)
+		if err := ioWriter.CopyChunk(data, chunk.meta, chunk.source.chunkChecksums(offset, int64(len(data)))); err != nil {
+			return errors.New("merge: " + err.Error())
+		}
+		m.report.CopiedChunks++
+		m.report.Points += chunk.meta.GetNumOfPoints()
+	}
+	if err := ioWriter.Err(); err != nil {
+		return errors.New("merge: " + err.Error())
+	}
+	ioWriter.EndRowGroup(ioWriter.GetPos() - start)
+	m.report.RowGroups++
+	return nil
+}
+
+// mergeSeries decodes the chunks of a series into one, of the points of a
+// time the one of the latest file is kept, or the latest chunk in a file. The
+// chunks are walked together in the order of time, a page of each decoded at
+// a time. The points passed the lossy filter of the series when they were
+// written first.
+func (m *merger) mergeSeries(sd *sensorDescriptor.SensorDescriptor, chunks []*mergeChunk) (*SeriesWriter, error) {
+	sort.SliceStable(chunks, func(i, j int) bool {
+		if chunks[i].source.index != chunks[j].source.index {
+			return chunks[i].source.index < chunks[j].source.index
+		}
+		return chunks[i].meta.FileOffsetOfCorrespondingData() < chunks[j].meta.FileOffsetOfCorrespondingData()
+	})
+	// in the order of the chunks, the last one with a time wins
+	points := make([]*chunkPoints, len(chunks))
+	for i, chunk := range chunks {
+		points[i] = &chunkPoints{chunk: chunk, i: -1,
+			pos: chunk.meta.FileOffsetOfCorrespondingData() + int64(chunk.header.GetSerializedSize())}
+		if err := points[i].next(); err != nil {
+			return nil, err
+		}
+	}
+
+	pageWriter, _ := NewPageWriter(sd)
+	seriesWriter, err := NewSeriesWriter(chunks[0].device, sd, pageWriter, conf.PageSizeInByte)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		found := false
+		var time int64
+		for _, p := range points {
+			if p.hasPoint() && (!found || p.time() < time) {
+				found, time = true, p.time()
+			}
+		}
+		if !found {
+			break
+		}
+		var value interface{}
+		num := 0
+		for _, p := range points {
+			// a chunk may repeat a time too
+			for p.hasPoint() && p.time() == time {
+				value = p.values[p.i]
+				num++
+				if err := p.next(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		seriesWriter.writePoint(time, value)
+		m.report.Points++
+		m.report.DuplicatePoints += int64(num - 1)
+	}
+	seriesWriter.PreFlush()
+	return seriesWriter, nil
+}
+
+// chunkPoints walks the points of a chunk, decoding one page at a time
+type chunkPoints struct {
+	chunk *mergeChunk
+	// pages decoded and the offset of the next one
+	pages int
+	pos   int64
+	// the points of the current page and the index of the current point
+	times  []int64
+	values []interface{}
+	i      int
+}
+
+func (p *chunkPoints) hasPoint() bool {
+	return p.i < len(p.times)
+}
+
+func (p *chunkPoints) time() int64 {
+	return p.times[p.i]
+}
+
+// next moves to the next point, the next page is decoded at the end of one
+func (p *chunkPoints) next() error {
+	p.i++
+	c := p.chunk
+	f := c.source.f
+	for p.i >= len(p.times) && p.pages < c.header.GetNumberOfPages() {
+		pageHeader := f.ReadPageHeaderAt(c.header.GetDataType(), p.pos)
+		p.pos += int64(pageHeader.GetSerializedSize())
+		data := f.ReadRaw(p.pos, int(pageHeader.GetCompressedSize()))
+		p.pos += int64(pageHeader.GetCompressedSize())
+		p.pages++
+		times, values, err := decodePage(data, pageHeader, c.header)
+		if err != nil {
+			return fmt.Errorf("merge: %s: page %d of the chunk of %s%s%s at offset %d %v", c.source.file, p.pages,
+				c.device, constant.PATH_SEPARATOR, c.header.GetSensor(), c.meta.FileOffsetOfCorrespondingData(), err)
+		}
+		p.times, p.values, p.i = times, values, 0
+	}
+	return nil
+}
+
+// chunkChecksums returns the checksums of the blocks of a chunk by their
+// offset in it, none if the file was written without checksums
+func (s *mergeSource) chunkChecksums(offset int64, length int64) map[int64]metadata.Checksum {
+	checksums := make(map[int64]metadata.Checksum)
+	i := sort.Search(len(s.offsets), func(i int) bool { return s.offsets[i] >= offset })
+	for ; i < len(s.offsets) && s.offsets[i] < offset+length; i++ {
+		checksums[s.offsets[i]-offset] = s.checksums[s.offsets[i]]
+	}
+	return checksums
+}
@@ -0,0 +1,301 @@
+package tsFileWriter
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"tsfile/common/conf"
+	"tsfile/common/constant"
+	"tsfile/common/log"
+	"tsfile/common/utils"
+	"tsfile/file/header"
+	"tsfile/file/metadata"
+	"tsfile/file/metadata/statistics"
+	"tsfile/timeseries/write/sensorDescriptor"
+)
+
+// RecoverTsFile seals a tsfile left open by a crashed writer. The complete row
+// groups are kept and the partial tail is cut off, from the first row group
+// whose pages do not decode to the points of their headers on. Then the
+// records of the write ahead log (if any) are written again and the footer is
+// added.
+// A file that is already sealed is left untouched.
+func RecoverTsFile(file string) error {
+	walLog, err := ReadWal(file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		walLog = nil
+	}
+
+	fin, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	stat, err := fin.Stat()
+	if err != nil {
+		fin.Close()
+		return err
+	}
+	scanner := &tsFileScanner{reader: utils.NewFileReader(fin), size: stat.Size(),
+		deviceSensors: make(map[string][]*sensorDescriptor.SensorDescriptor),
+		checksums:     make(map[int64]metadata.Checksum)}
+	magicLen := int64(len(conf.MAGIC_STRING))
+	if scanner.size >= magicLen && string(scanner.reader.ReadAt(int(magicLen), 0)) != conf.MAGIC_STRING {
+		fin.Close()
+		return errors.New("recover: not a tsfile: " + file)
+	}
+	if scanner.isSealed() {
+		fin.Close()
+		if walLog != nil {
+			return os.Remove(file + WAL_SUFFIX)
+		}
+		return nil
+	}
+	scanner.scanRowGroups()
+	fin.Close()
+
+	// the row groups behind the wal base were written from the logged records,
+	// they are written again from the log
+	rowGroups, end := scanner.rowGroups, scanner.end
+	if walLog != nil {
+		for i, rowGroupEnd := range scanner.rowGroupEnds {
+			if rowGroupEnd > walLog.BasePos {
+				rowGroups, end = scanner.rowGroups[:i], scanner.rowGroupStart(i)
+				break
+			}
+		}
+	}
+
+	var tfiWriter *TsFileIoWriter
+	if end < magicLen {
+		// crashed before the head magic was written
+		tfiWriter, err = ResumeTsFileIoWriter(file, 0, rowGroups)
+		if err == nil {
+			tfiWriter.WriteMagic()
+		}
+	} else {
+		tfiWriter, err = ResumeTsFileIoWriter(file, end, rowGroups)
+	}
+	if err != nil {
+		return err
+	}
+	if walLog != nil && walLog.Version != 0 {
+		tfiWriter.version = walLog.Version
+	}
+	// the blocks kept get their checksums again
+	for offset, checksum := range scanner.checksums {
+		if offset < end {
+			tfiWriter.checksums[offset] = checksum
+		}
+	}
+	log.Info("recover %s: keep %d row groups, cut at %d", file, len(rowGroups), end)
+
+	tsFileWriter := newTsFileWriter(tfiWriter)
+	sensors, deviceSensors := scanner.sensors, scanner.deviceSensors
+	if walLog != nil {
+		sensors = append(sensors, walLog.Sensors...)
+		for deviceId, sds := range walLog.DeviceSensors {
+			deviceSensors[deviceId] = append(deviceSensors[deviceId], sds...)
+		}
+	}
+	for _, sd := range sensors {
+		tsFileWriter.AddSensor(sd)
+	}
+	for deviceId, sds := range deviceSensors {
+		for _, sd := range sds {
+			tsFileWriter.AddDeviceSensor(deviceId, sd)
+		}
+	}
+	if walLog != nil {
+		for _, tr := range walLog.Records {
+			tsFileWriter.Write(tr)
+		}
+	}
+	if !tsFileWriter.Close() {
+		return errors.New("recover: cannot close " + file)
+	}
+	if walLog != nil {
+		return os.Remove(file + WAL_SUFFIX)
+	}
+	return nil
+}
+
+// tsFileScanner walks the row groups of a file without footer and rebuilds
+// their metadata from the headers.
+type tsFileScanner struct {
+	reader       *utils.FileReader
+	size         int64
+	rowGroups    []*metadata.RowGroupMetaData
+	rowGroupEnds []int64
+	sensors      []*sensorDescriptor.SensorDescriptor
+	// sensors whose type differs from the first sensor with the same id
+	deviceSensors map[string][]*sensorDescriptor.SensorDescriptor
+	end           int64
+	// checksums of the headers and pages of the row groups scanned
+	checksums map[int64]metadata.Checksum
+}
+
+func (s *tsFileScanner) isSealed() bool {
+	magicLen := int64(len(conf.MAGIC_STRING))
+	intLen := int64(constant.INT_LEN)
+	if s.size < 2*magicLen+intLen {
+		return false
+	}
+	if string(s.reader.ReadAt(int(magicLen), s.size-magicLen)) != conf.MAGIC_STRING {
+		return false
+	}
+	metadataSize := int64(binary.BigEndian.Uint32(s.reader.ReadAt(constant.INT_LEN, s.size-magicLen-intLen)))
+	return metadataSize <= s.size-2*magicLen-intLen
+}
+
+func (s *tsFileScanner) rowGroupStart(i int) int64 {
+	if i == 0 {
+		return int64(len(conf.MAGIC_STRING))
+	}
+	return s.rowGroupEnds[i-1]
+}
+
+func (s *tsFileScanner) scanRowGroups() {
+	s.end = int64(len(conf.MAGIC_STRING))
+	if s.size < s.end {
+		s.end = 0
+		return
+	}
+	s.reader.Seek(s.end, os.SEEK_SET)
+	sensorTypes := make(map[string]int16)
+	for {
+		rowGroup, sensors, ok := s.scanRowGroup(s.end)
+		if !ok {
+			return
+		}
+		s.addChecksums(s.end, rowGroup)
+		s.rowGroups = append(s.rowGroups, rowGroup)
+		s.end = s.reader.Pos()
+		s.rowGroupEnds = append(s.rowGroupEnds, s.end)
+		for _, sd := range sensors {
+			tsDataType, exist := sensorTypes[sd.GetSensorId()]
+			if !exist {
+				sensorTypes[sd.GetSensorId()] = sd.GetTsDataType()
+				s.sensors = append(s.sensors, sd)
+			} else if tsDataType != sd.GetTsDataType() {
+				deviceId := rowGroup.GetDeviceId()
+				s.deviceSensors[deviceId] = append(s.deviceSensors[deviceId], sd)
+			}
+		}
+	}
+}
+
+// scanRowGroup reads the row group starting at pos, ok is false if it is
+// incomplete or does not look like a row group.
+func (s *tsFileScanner) scanRowGroup(pos int64) (rowGroup *metadata.RowGroupMetaData, sensors []*sensorDescriptor.SensorDescriptor, ok bool) {
+	defer func() {
+		if err := recover(); err != nil {
+			rowGroup, sensors, ok = nil, nil, false
+		}
+	}()
+	if !s.hasString(pos) {
+		return nil, nil, false
+	}
+	rowGroupHeader := new(header.RowGroupHeader)
+	rowGroupHeader.Deserialize(s.reader)
+	rowGroupEnd := pos + rowGroupHeader.GetDataSize()
+	if rowGroupHeader.GetDataSize() <= 0 || rowGroupEnd > s.size {
+		return nil, nil, false
+	}
+	rowGroup, _ = metadata.NewRowGroupMetaData(rowGroupHeader.GetDevice(), 0, pos, make([]*metadata.ChunkMetaData, 0))
+	for i := 0; i < int(rowGroupHeader.GetNumberOfChunks()); i++ {
+		chunkPos := s.reader.Pos()
+		if !s.hasString(chunkPos) {
+			return nil, nil, false
+		}
+		chunkHeader := new(header.ChunkHeader)
+		chunkHeader.Deserialize(s.reader)
+		chunkEnd := s.reader.Pos() + int64(chunkHeader.GetDataSize())
+		if chunkEnd > rowGroupEnd {
+			return nil, nil, false
+		}
+
+		var numOfPoints int64
+		startTime, endTime := int64(0x7fffffffffffffff), int64(0)
+		tsDataType := int16(chunkHeader.GetDataType())
+		chunkStatistics := statistics.GetStatsByType(tsDataType)
+		for j := 0; j < chunkHeader.GetNumberOfPages(); j++ {
+			pageHeader := new(header.PageHeader)
+			pageHeader.Deserialize(s.reader, chunkHeader.GetDataType())
+			dataPos := s.reader.Pos()
+			if dataPos+int64(pageHeader.GetCompressedSize()) > chunkEnd {
+				return nil, nil, false
+			}
+			// there is no footer with checksums, the points must match the
+			// header before the checksums of the blocks are written again
+			data := s.reader.ReadAt(int(pageHeader.GetCompressedSize()), dataPos)
+			if _, _, err := checkPage(data, pageHeader, chunkHeader); err != nil {
+				log.Error("recover: page of %s at %d: %s", chunkHeader.GetSensor(), dataPos, err)
+				return nil, nil, false
+			}
+			s.reader.Seek(dataPos+int64(pageHeader.GetCompressedSize()), os.SEEK_SET)
+			numOfPoints += int64(pageHeader.GetNumberOfValues())
+			startTime = min(startTime, pageHeader.Min_timestamp())
+			endTime = max(endTime, pageHeader.Max_timestamp())
+			chunkStatistics.Merge(*pageHeader.GetStatistics())
+		}
+		if s.reader.Pos() != chunkEnd {
+			return nil, nil, false
+		}
+
+		chunkMetaData, _ := metadata.NewTimeSeriesChunkMetaData(chunkHeader.GetSensor(), chunkPos, startTime, endTime)
+		chunkMetaData.SetTotalByteSizeOfPagesOnDisk(chunkEnd - chunkPos)
+		chunkMetaData.SetNumOfPoints(numOfPoints)
+		chunkMetaData.SetDigest(newChunkDigest(chunkStatistics, tsDataType))
+		rowGroup.AddChunkMetaData(chunkMetaData)
+
+		sd, _ := sensorDescriptor.NewWithCompress(chunkHeader.GetSensor(), chunkHeader.GetDataType(),
+			chunkHeader.GetEncodingType(), chunkHeader.GetCompressionType())
+		sd.SetTimeEncoding(chunkHeader.GetTimeEncodingType())
+		sensors = append(sensors, sd)
+	}
+	if s.reader.Pos() != rowGroupEnd {
+		return nil, nil, false
+	}
+	rowGroup.SetTotalByteSize(rowGroupHeader.GetDataSize())
+	return rowGroup, sensors, true
+}
+
+// addChecksums computes the checksums of the blocks of a complete row group,
+// the way the writer splits it into blocks
+func (s *tsFileScanner) addChecksums(pos int64, rowGroup *metadata.RowGroupMetaData) {
+	// the block from start to the position read to
+	add := func(start int64) {
+		s.checksums[start] = metadata.NewChecksum(s.reader.ReadAt(int(s.reader.Pos()-start), start))
+	}
+	end := s.reader.Pos()
+	s.reader.Seek(pos, os.SEEK_SET)
+	new(header.RowGroupHeader).Deserialize(s.reader)
+	add(pos)
+	for range rowGroup.GetChunkMetaDataSli() {
+		chunkPos := s.reader.Pos()
+		chunkHeader := new(header.ChunkHeader)
+		chunkHeader.Deserialize(s.reader)
+		add(chunkPos)
+		for j := 0; j < chunkHeader.GetNumberOfPages(); j++ {
+			pagePos := s.reader.Pos()
+			pageHeader := new(header.PageHeader)
+			pageHeader.Deserialize(s.reader, chunkHeader.GetDataType())
+			s.reader.Seek(s.reader.Pos()+int64(pageHeader.GetCompressedSize()), os.SEEK_SET)
+			add(pagePos)
+		}
+	}
+	s.reader.Seek(end, os.SEEK_SET)
+}
+
+// hasString checks the length prefix of the string at pos, a torn header
+// must not make the reader allocate a garbage length.
+func (s *tsFileScanner) hasString(pos int64) bool {
+	if pos+int64(constant.INT_LEN) > s.size {
+		return false
+	}
+	length := int64(int32(binary.BigEndian.Uint32(s.reader.ReadAt(constant.INT_LEN, pos))))
+	return length >= 0 && pos+int64(constant.INT_LEN)+length <= s.size
+}
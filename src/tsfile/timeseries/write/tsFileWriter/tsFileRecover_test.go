@@ -0,0 +1,302 @@
+package tsFileWriter
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"tsfile/common/constant"
+	"tsfile/file/metadata"
+	"tsfile/file/metadata/statistics"
+	"tsfile/timeseries/read"
+	"tsfile/timeseries/read/reader/impl/basic"
+	"tsfile/timeseries/write/sensorDescriptor"
+)
+
+// readAllPoints walks the file row group by row group, the values of every
+// device.sensor are returned in file order.
+func readAllPoints(t *testing.T, file string) map[string][]interface{} {
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(file); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if f.ReadTailMagic() != f.ReadHeadMagic() {
+		t.Fatal("file is not sealed")
+	}
+	f.ReadFileMetadata()
+	result := make(map[string][]interface{})
+	for f.HasNextRowGroup() {
+		groupHeader := f.ReadRowGroupHeader()
+		for i := 0; i < int(groupHeader.GetNumberOfChunks()); i++ {
+			chunkHeader := f.ReadChunkHeader()
+			path := groupHeader.GetDevice() + constant.PATH_SEPARATOR + chunkHeader.GetSensor()
+			for j := 0; j < chunkHeader.GetNumberOfPages(); j++ {
+				pageHeader := f.ReadPageHeader(chunkHeader.GetDataType())
+				pageReader, err := basic.NewPageDataReader(chunkHeader.GetDataType(), chunkHeader.GetEncodingType(),
+					chunkHeader.GetTimeEncodingType())
+				if err != nil {
+					t.Fatal(err)
+				}
+				pageReader.Read(f.ReadPage(pageHeader, chunkHeader.GetCompressionType()))
+				for pageReader.HasNext() {
+					pair, _ := pageReader.Next()
+					result[path] = append(result[path], pair.Value)
+				}
+			}
+		}
+	}
+	return result
+}
+
+func writeLongs(w *TsFileWriter, device string, from int, to int) {
+	for i := from; i < to; i++ {
+		record, _ := NewTsRecordUseTimestamp(int64(i), device)
+		pt, _ := NewLong("s0", constant.INT64, int64(i))
+		record.AddTuple(pt)
+		w.Write(record)
+	}
+}
+
+func TestRecoverTsFile(t *testing.T) {
+	recoverFilePath := tempFile(t, "recover_TsFile")
+	if _, err := NewTsFileWriterWithWal(filepath.Join(recoverFilePath, "missing", "TsFile")); err == nil {
+		t.Fatal("expected no file in a missing directory")
+	}
+
+	w, err := NewTsFileWriterWithWal(recoverFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	des, _ := sensorDescriptor.New("s0", constant.INT64, constant.PLAIN)
+	w.AddSensor(des)
+	writeLongs(w, "root.d0", 1, 6)
+	writeLongs(w, "root.d1", 1, 4)
+	w.flushAllRowGroups(false)
+	writeLongs(w, "root.d0", 6, 11)
+
+	// crash: nothing of the second part reached the file, the footer is missing
+	// and a torn row group header is left at the tail
+	w.tsFileIoWriter.WriteBytesToFile(w.tsFileIoWriter.memBuf)
+	w.tsFileIoWriter.tsIoFile.Write([]byte{0, 0, 0, 7, 'r', 'o', 'o'})
+	w.tsFileIoWriter.Close()
+	w.wal.Close()
+
+	if err := RecoverTsFile(recoverFilePath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(recoverFilePath + WAL_SUFFIX); !os.IsNotExist(err) {
+		t.Fatal("wal should be removed after recovery")
+	}
+
+	points := readAllPoints(t, recoverFilePath)
+	if len(points["root.d0.s0"]) != 10 || len(points["root.d1.s0"]) != 3 {
+		t.Fatalf("expected 10 and 3 points, got %v", points)
+	}
+	for i, v := range points["root.d0.s0"] {
+		if v != int64(i+1) {
+			t.Fatalf("expected %d got %d", i+1, v)
+		}
+	}
+
+	// the digests of the recovered chunks hold the statistics of their pages,
+	// the values of s0 are its times
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(recoverFilePath); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	for _, rowGroup := range f.ReadFileMetadata().DeviceMap()["root.d0"].GetRowGroups() {
+		for _, chunk := range rowGroup.GetChunkMetaDataSli() {
+			s := chunk.GetStatistics(constant.INT64).(statistics.TypedStatistics[int64])
+			if s.Count() != chunk.GetNumOfPoints() || s.Min() != chunk.GetStartTime() || s.Max() != chunk.GetEndTime() {
+				t.Fatalf("unexpected statistics of the chunk [%d, %d]: count %d, min %d, max %d",
+					chunk.GetStartTime(), chunk.GetEndTime(), s.Count(), s.Min(), s.Max())
+			}
+			count += s.Count()
+		}
+	}
+	if count != 10 {
+		t.Fatalf("expected statistics of 10 points, got %d", count)
+	}
+	// the blocks kept have checksums again, like the ones written from the wal:
+	// three row groups of one chunk of one page
+	if len(f.ReadFileMetadata().GetChecksums()) != 3*3 {
+		t.Fatalf("expected the checksums of all blocks, got %v", f.ReadFileMetadata().GetChecksums())
+	}
+	if err := f.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// recovering a sealed file changes nothing
+	stat, _ := os.Stat(recoverFilePath)
+	if err := RecoverTsFile(recoverFilePath); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := os.Stat(recoverFilePath); again.Size() != stat.Size() {
+		t.Fatal("sealed file should be left untouched")
+	}
+}
+
+func TestRecoverVersion(t *testing.T) {
+	recoverFilePath := tempFile(t, "recover_TsFile")
+	w, err := NewTsFileWriterWithWal(recoverFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetVersion(metadata.VERSION_3); err != nil {
+		t.Fatal(err)
+	}
+	des, _ := sensorDescriptor.NewWithCompress("s0", constant.INT64, constant.TS_2DIFF, constant.GZIP)
+	des.SetCompressionLevel(1)
+	w.AddSensor(des)
+	writeLongs(w, "root.d0", 1, 6)
+	w.flushAllRowGroups(false)
+	writeLongs(w, "root.d0", 6, 11)
+	// crash with the second part in the wal only
+	w.tsFileIoWriter.Close()
+	w.wal.Close()
+
+	walLog, err := ReadWal(recoverFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if walLog.Version != metadata.VERSION_3 || len(walLog.Sensors) != 1 || walLog.Sensors[0].GetCompressionLevel() != 1 {
+		t.Fatalf("expected the version and the compression level in the wal, got %+v", walLog)
+	}
+	if err := RecoverTsFile(recoverFilePath); err != nil {
+		t.Fatal(err)
+	}
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(recoverFilePath); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if f.Version() != metadata.VERSION_3 {
+		t.Fatalf("expected the recovered file in version 3, got %d", f.Version())
+	}
+	if points := readAllPoints(t, recoverFilePath)["root.d0.s0"]; len(points) != 10 {
+		t.Fatalf("expected 10 points, got %v", points)
+	}
+}
+
+func TestRecoverDamagedRowGroup(t *testing.T) {
+	recoverFilePath := tempFile(t, "recover_TsFile")
+	w, _ := NewTsFileWriter(recoverFilePath)
+	des, _ := sensorDescriptor.New("s0", constant.INT64, constant.PLAIN)
+	w.AddSensor(des)
+	writeLongs(w, "root.d0", 1, 101)
+	w.flushAllRowGroups(false)
+	writeLongs(w, "root.d1", 1, 51)
+	w.flushAllRowGroups(false)
+	// crash without footer
+	w.tsFileIoWriter.Close()
+
+	// the last byte of the value 50, the max of the page of root.d1
+	data, _ := os.ReadFile(recoverFilePath)
+	data[len(data)-1] ^= 0x40
+	os.WriteFile(recoverFilePath, data, 0666)
+	if err := RecoverTsFile(recoverFilePath); err != nil {
+		t.Fatal(err)
+	}
+	points := readAllPoints(t, recoverFilePath)
+	if len(points["root.d0.s0"]) != 100 || len(points["root.d1.s0"]) != 0 {
+		t.Fatalf("expected the row group of root.d1 to be dropped, got %v", points)
+	}
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(recoverFilePath); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := f.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRepairTsFile(t *testing.T) {
+	recoverFilePath := tempFile(t, "recover_TsFile")
+	repairedFilePath := tempFile(t, "repaired_TsFile")
+
+	w, _ := NewTsFileWriter(recoverFilePath)
+	des, _ := sensorDescriptor.New("s0", constant.INT64, constant.PLAIN)
+	w.AddSensor(des)
+	writeLongs(w, "root.d0", 1, 101)
+	w.Flush()
+	writeLongs(w, "root.d0", 101, 201)
+	writeLongs(w, "root.d1", 1, 51)
+	w.Close()
+
+	repair := func() *RepairReport {
+		os.Remove(repairedFilePath)
+		report, err := RepairTsFile(recoverFilePath, repairedFilePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if problems := read.Check(repairedFilePath); len(problems) != 0 {
+			t.Fatalf("expected a valid file, got %v", problems)
+		}
+		// the repaired file is written with checksums, whatever the damaged one has
+		f := new(read.TsFileSequenceReader)
+		if err := f.Open(repairedFilePath); err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if err := f.Verify(); err != nil {
+			t.Fatal(err)
+		}
+		return report
+	}
+	if report := repair(); report.RowGroups != 3 || report.Points != 250 || len(report.Lost) != 0 {
+		t.Fatalf("expected all the points of an intact file, got %+v", report)
+	}
+	if points := readAllPoints(t, repairedFilePath); len(points["root.d0.s0"]) != 200 || len(points["root.d1.s0"]) != 50 {
+		t.Fatalf("expected 200 and 50 points, got %v", points)
+	}
+	// the out file is never appended to
+	intact, _ := os.ReadFile(recoverFilePath)
+	if _, err := RepairTsFile(recoverFilePath, recoverFilePath); err == nil {
+		t.Fatal("expected the input not to be the out file")
+	}
+	if _, err := RepairTsFile(recoverFilePath, repairedFilePath); err == nil {
+		t.Fatal("expected the out file to exist")
+	}
+	if data, _ := os.ReadFile(recoverFilePath); !bytes.Equal(data, intact) {
+		t.Fatal("the input should be left untouched")
+	}
+
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(recoverFilePath); err != nil {
+		t.Fatal(err)
+	}
+	f.ReadRowGroupHeader()
+	f.ReadChunkHeader()
+	pageOffset := f.Pos()
+	pageHeader := f.ReadPageHeader(constant.INT64)
+	// the last byte of the value 100, the max of the page
+	valueOffset := f.Pos() + int64(pageHeader.GetCompressedSize()) - 1
+	footerOffset := f.MetadataPos()
+	f.Close()
+
+	// a damaged value and no footer, the statistics of the page tell the damage
+	data, _ := os.ReadFile(recoverFilePath)
+	data[valueOffset] ^= 0x40
+	os.WriteFile(recoverFilePath, data[:footerOffset+10], 0666)
+	report := repair()
+	if report.Points != 150 || report.LostPoints != 100 || report.SkippedBytes != 10 || len(report.Lost) != 2 {
+		t.Fatalf("expected 100 points lost and 10 bytes skipped, got %+v", report)
+	}
+	if lost := report.Lost[0]; lost.Block != "page" || lost.Device != "root.d0" || lost.Sensor != "s0" || lost.Offset != pageOffset {
+		t.Fatalf("expected the page of root.d0.s0 at %d to be lost, got %v", pageOffset, lost)
+	}
+	points := readAllPoints(t, repairedFilePath)
+	if len(points["root.d0.s0"]) != 100 || points["root.d0.s0"][0] != int64(101) || len(points["root.d1.s0"]) != 50 {
+		t.Fatalf("expected the second row group of root.d0 and root.d1, got %v", points)
+	}
+
+	os.WriteFile(recoverFilePath, []byte("not a tsfile at all, not a tsfile at all"), 0666)
+	if _, err := RepairTsFile(recoverFilePath, repairedFilePath); err == nil {
+		t.Fatal("expected no tsfile")
+	}
+}
@@ -0,0 +1,517 @@
+package tsFileWriter
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"tsfile/common/conf"
+	"tsfile/common/constant"
+	"tsfile/common/utils"
+	"tsfile/compress"
+	"tsfile/encoding/decoder"
+	"tsfile/file/header"
+	"tsfile/file/metadata"
+	"tsfile/file/metadata/statistics"
+	"tsfile/timeseries/read"
+	"tsfile/timeseries/write/sensorDescriptor"
+	"unicode"
+	"unicode/utf8"
+)
+
+// the longest device or sensor a header is taken for while scanning
+const maxRepairNameLength = 4096
+
+// the sizes of a page header without statistics
+const minPageHeaderSize = 3*constant.INT_LEN + 2*constant.LONG_LEN
+
+// RepairReport is what RepairTsFile salvaged and what it lost
+type RepairReport struct {
+	RowGroups int   `json:"rowGroups"`
+	Chunks    int   `json:"chunks"`
+	Pages     int   `json:"pages"`
+	Points    int64 `json:"points"`
+	// Lost are the blocks not recovered and the bytes skipped between headers
+	Lost         []*read.Problem `json:"lost"`
+	LostPoints   int64           `json:"lostPoints"`
+	SkippedBytes int64           `json:"skippedBytes"`
+}
+
+// RepairTsFile writes the points of a damaged tsfile into a new file. It scans
+// forward from the head magic, a row group or chunk is found again by its
+// header wherever it starts, and the pages that can not be decoded are
+// skipped. The footer is not needed, if it is intact the scan stops at it and
+// the checksums it has are verified. The out file must not exist, it is
+// written from its start.
+func RepairTsFile(in string, out string) (*RepairReport, error) {
+	if err := checkOutFile("repair", out, in); err != nil {
+		return nil, err
+	}
+	fin, err := os.Open(in)
+	if err != nil {
+		return nil, err
+	}
+	defer fin.Close()
+	stat, err := fin.Stat()
+	if err != nil {
+		return nil, err
+	}
+	// a damaged head magic is taken if the tail one is intact
+	magicLen := int64(len(conf.MAGIC_STRING))
+	head, tail := make([]byte, magicLen), make([]byte, magicLen)
+	fin.ReadAt(head, 0)
+	fin.ReadAt(tail, stat.Size()-magicLen)
+	if string(head) != conf.MAGIC_STRING && string(tail) != conf.MAGIC_STRING {
+		return nil, errors.New("repair: not a tsfile: " + in)
+	}
+
+	writer, err := NewTsFileWriter(out)
+	if err != nil {
+		return nil, err
+	}
+	r := &repairer{fin: fin, reader: utils.NewFileReader(fin), limit: stat.Size(), writer: writer,
+		report: new(RepairReport), sensors: make(map[string]*sensorDescriptor.SensorDescriptor),
+		pathTypes: make(map[string]constant.TSDataType), lastTimes: make(map[string]int64)}
+	if string(head) != conf.MAGIC_STRING {
+		r.report.Lost = append(r.report.Lost, &read.Problem{Block: "file", Offset: 0,
+			Message: fmt.Sprintf("head magic %q, expected %q", head, conf.MAGIC_STRING)})
+	}
+	r.readFooter(in)
+	r.scan(magicLen)
+	if !writer.Close() {
+		return nil, errors.New("repair: cannot close " + out)
+	}
+	return r.report, nil
+}
+
+// checkOutFile fails for an out file that exists or is one of the inputs, the
+// writer would append to it
+func checkOutFile(prefix string, out string, ins ...string) error {
+	outPath, err := filepath.Abs(out)
+	if err != nil {
+		return err
+	}
+	for _, in := range ins {
+		if inPath, err := filepath.Abs(in); err == nil && inPath == outPath {
+			return errors.New(prefix + ": " + out + " is also an input")
+		}
+	}
+	if _, err := os.Stat(out); err == nil {
+		return errors.New(prefix + ": " + out + " exists")
+	}
+	return nil
+}
+
+type repairer struct {
+	fin    *os.File
+	reader *utils.FileReader
+	// where the row groups end, the footer or the end of the file
+	limit     int64
+	checksums map[int64]metadata.Checksum
+	// bytes of the file from windowPos, for looking for headers
+	window    []byte
+	windowPos int64
+
+	writer *TsFileWriter
+	report *RepairReport
+	// the row group scanned, the device is empty outside of one
+	device      string
+	rowGroupEnd int64
+	// the first sensor added of an id, the data type of a path and its last time
+	sensors   map[string]*sensorDescriptor.SensorDescriptor
+	pathTypes map[string]constant.TSDataType
+	lastTimes map[string]int64
+}
+
+// readFooter trusts the footer only if its checksum matches, or if it parses
+// in a file written without checksums
+func (r *repairer) readFooter(file string) {
+	defer func() {
+		recover()
+	}()
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(file); err != nil {
+		return
+	}
+	defer f.Close()
+	err := f.SetVerifyChecksums(true)
+	if _, corrupted := err.(*read.CorruptionError); corrupted {
+		return
+	}
+	fileMetadata := f.ReadFileMetadata()
+	r.limit = f.MetadataPos()
+	if err == nil {
+		r.checksums = fileMetadata.GetChecksums()
+	}
+}
+
+// scan moves a byte at a time until a header is found
+func (r *repairer) scan(pos int64) {
+	skipped := int64(-1)
+	for pos < r.limit {
+		if r.device != "" && pos >= r.rowGroupEnd {
+			r.device = ""
+		}
+		next, ok := r.repairBlock(pos)
+		if !ok {
+			if skipped < 0 {
+				skipped = pos
+			}
+			pos++
+			continue
+		}
+		if skipped >= 0 {
+			r.skip(skipped, pos)
+			skipped = -1
+		}
+		pos = next
+	}
+	if skipped >= 0 {
+		r.skip(skipped, r.limit)
+	}
+}
+
+func (r *repairer) skip(start int64, end int64) {
+	r.report.SkippedBytes += end - start
+	r.report.Lost = append(r.report.Lost, &read.Problem{Block: "bytes", Offset: start,
+		Message: fmt.Sprintf("%d bytes skipped", end-start)})
+}
+
+// lose reports a block, points are the ones its headers tell of
+func (r *repairer) lose(block string, offset int64, device string, sensor string, points int64, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	if points > 0 {
+		message += fmt.Sprintf(", %d points lost", points)
+	}
+	r.report.LostPoints += points
+	r.report.Lost = append(r.report.Lost, &read.Problem{Block: block, Device: device, Sensor: sensor, Offset: offset,
+		Message: message})
+}
+
+// repairBlock takes a chunk or a row group header at pos, a chunk is expected
+// in a row group and a row group header out of one. It returns where the scan
+// goes on.
+func (r *repairer) repairBlock(pos int64) (int64, bool) {
+	if r.device != "" {
+		if chunkHeader, ok := r.readChunkHeader(pos); ok {
+			return r.repairChunk(pos, chunkHeader), true
+		}
+	}
+	if rowGroupHeader, ok := r.readRowGroupHeader(pos); ok {
+		r.report.RowGroups++
+		r.device = rowGroupHeader.GetDevice()
+		r.rowGroupEnd = min(pos+rowGroupHeader.GetDataSize(), r.limit)
+		return pos + int64(rowGroupHeader.GetSerializedSize()), true
+	}
+	if r.device == "" {
+		// a chunk whose row group header is lost
+		if chunkHeader, ok := r.readChunkHeader(pos); ok {
+			return r.repairChunk(pos, chunkHeader), true
+		}
+	}
+	return 0, false
+}
+
+// readRowGroupHeader takes the bytes at pos for a row group header if they
+// parse as one followed by a chunk header
+func (r *repairer) readRowGroupHeader(pos int64) (rowGroupHeader *header.RowGroupHeader, ok bool) {
+	defer func() {
+		if recover() != nil {
+			rowGroupHeader, ok = nil, false
+		}
+	}()
+	if !r.hasName(pos) {
+		return nil, false
+	}
+	r.reader.Seek(pos, io.SeekStart)
+	rowGroupHeader = new(header.RowGroupHeader)
+	rowGroupHeader.Deserialize(r.reader)
+	// the chunks of a row group cut off by the end of the file are kept
+	end := min(pos+rowGroupHeader.GetDataSize(), r.limit)
+	if rowGroupHeader.GetNumberOfChunks() <= 0 || rowGroupHeader.GetDataSize() <= int64(rowGroupHeader.GetSerializedSize()) ||
+		!r.verify(pos, rowGroupHeader.GetSerializedSize()) {
+		return nil, false
+	}
+	device, rowGroupEnd := r.device, r.rowGroupEnd
+	r.device, r.rowGroupEnd = rowGroupHeader.GetDevice(), end
+	_, ok = r.readChunkHeader(pos + int64(rowGroupHeader.GetSerializedSize()))
+	r.device, r.rowGroupEnd = device, rowGroupEnd
+	return rowGroupHeader, ok
+}
+
+// readChunkHeader takes the bytes at pos for a chunk header if they parse as
+// one of a sensor the writer accepts, ending in the row group scanned
+func (r *repairer) readChunkHeader(pos int64) (chunkHeader *header.ChunkHeader, ok bool) {
+	defer func() {
+		if recover() != nil {
+			chunkHeader, ok = nil, false
+		}
+	}()
+	if !r.hasName(pos) {
+		return nil, false
+	}
+	r.reader.Seek(pos, io.SeekStart)
+	chunkHeader = new(header.ChunkHeader)
+	chunkHeader.Deserialize(r.reader)
+	end := r.reader.Pos() + int64(chunkHeader.GetDataSize())
+	if r.device != "" && end > r.rowGroupEnd || end > r.limit || chunkHeader.GetDataSize() < 0 ||
+		chunkHeader.GetNumberOfPages() <= 0 || chunkHeader.GetNumberOfPages() > chunkHeader.GetDataSize()/minPageHeaderSize ||
+		chunkHeader.GetDataType() < constant.BOOLEAN ||
+		chunkHeader.GetDataType() > constant.TEXT || checkSensor(newRepairSensor(chunkHeader)) != nil ||
+		!r.verify(pos, int32(chunkHeader.GetSerializedSize())) {
+		return nil, false
+	}
+	return chunkHeader, true
+}
+
+func (r *repairer) readPageHeader(pos int64, dataType constant.TSDataType, chunkEnd int64) (pageHeader *header.PageHeader, ok bool) {
+	defer func() {
+		if recover() != nil {
+			pageHeader, ok = nil, false
+		}
+	}()
+	r.reader.Seek(pos, io.SeekStart)
+	pageHeader = new(header.PageHeader)
+	pageHeader.Deserialize(r.reader, dataType)
+	if pageHeader.GetNumberOfValues() <= 0 || pageHeader.GetCompressedSize() < 0 || pageHeader.GetUncompressedSize() < 0 ||
+		pageHeader.Min_timestamp() > pageHeader.Max_timestamp() ||
+		r.reader.Pos()+int64(pageHeader.GetCompressedSize()) > chunkEnd {
+		return nil, false
+	}
+	return pageHeader, true
+}
+
+// repairChunk writes the pages of the chunk that decode, it returns the end of
+// the last page whose header could be read
+func (r *repairer) repairChunk(offset int64, chunkHeader *header.ChunkHeader) int64 {
+	sensor := chunkHeader.GetSensor()
+	reason := ""
+	if r.device == "" {
+		reason = "the header of its row group is lost"
+	} else if err := r.addSensor(chunkHeader); err != nil {
+		reason = err.Error()
+	} else {
+		r.report.Chunks++
+	}
+	pos := offset + int64(chunkHeader.GetSerializedSize())
+	end := pos + int64(chunkHeader.GetDataSize())
+	var lostPoints int64
+	for i := 0; i < chunkHeader.GetNumberOfPages(); i++ {
+		pageHeader, ok := r.readPageHeader(pos, chunkHeader.GetDataType(), end)
+		if !ok {
+			r.lose("chunk", offset, r.device, sensor, 0, "the header of page %d of %d at offset %d cannot be read",
+				i+1, chunkHeader.GetNumberOfPages(), pos)
+			break
+		}
+		points := int64(pageHeader.GetNumberOfValues())
+		if reason != "" {
+			lostPoints += points
+		} else if err := r.repairPage(pos, pageHeader, chunkHeader); err != nil {
+			r.lose("page", pos, r.device, sensor, points, "%v", err)
+		} else {
+			r.report.Pages++
+			r.report.Points += points
+		}
+		pos += int64(pageHeader.GetSerializedSize()) + int64(pageHeader.GetCompressedSize())
+	}
+	if reason != "" {
+		r.lose("chunk", offset, r.device, sensor, lostPoints, "%s", reason)
+	}
+	return pos
+}
+
+func newRepairSensor(chunkHeader *header.ChunkHeader) *sensorDescriptor.SensorDescriptor {
+	sd, _ := sensorDescriptor.NewWithCompress(chunkHeader.GetSensor(), chunkHeader.GetDataType(),
+		chunkHeader.GetEncodingType(), chunkHeader.GetCompressionType())
+	sd.SetTimeEncoding(chunkHeader.GetTimeEncodingType())
+	return sd
+}
+
+// addSensor keeps the encodings and the compression of the chunks, a device
+// whose chunks differ from the first ones of the sensor gets its own sensor
+func (r *repairer) addSensor(chunkHeader *header.ChunkHeader) error {
+	path := r.device + constant.PATH_SEPARATOR + chunkHeader.GetSensor()
+	if dataType, ok := r.pathTypes[path]; ok {
+		if dataType != chunkHeader.GetDataType() {
+			return fmt.Errorf("data type %s, the chunks before have %s", chunkHeader.GetDataType(), dataType)
+		}
+		return nil
+	}
+	sd := newRepairSensor(chunkHeader)
+	var err error
+	if first, ok := r.sensors[sd.GetSensorId()]; !ok {
+		if err = r.writer.AddSensor(sd); err == nil {
+			r.sensors[sd.GetSensorId()] = sd
+		}
+	} else if !sameSensor(first, sd) {
+		err = r.writer.AddDeviceSensor(r.device, sd)
+	}
+	if err != nil {
+		return err
+	}
+	r.pathTypes[path] = chunkHeader.GetDataType()
+	return nil
+}
+
+// repairPage writes the points of the page if it decodes to the points its
+// header describes, after the points written of the series.
+func (r *repairer) repairPage(offset int64, pageHeader *header.PageHeader, chunkHeader *header.ChunkHeader) error {
+	size := pageHeader.GetSerializedSize() + pageHeader.GetCompressedSize()
+	if !r.verify(offset, size) {
+		return errors.New("checksum mismatch")
+	}
+	data := r.reader.ReadAt(int(pageHeader.GetCompressedSize()), offset+int64(pageHeader.GetSerializedSize()))
+	times, values, err := checkPage(data, pageHeader, chunkHeader)
+	if err != nil {
+		return err
+	}
+	path := r.device + constant.PATH_SEPARATOR + chunkHeader.GetSensor()
+	if lastTime, ok := r.lastTimes[path]; ok && times[0] <= lastTime {
+		return fmt.Errorf("time %d is not after %d of the points recovered", times[0], lastTime)
+	}
+	r.lastTimes[path] = times[len(times)-1]
+
+	for i, t := range times {
+		tr, _ := NewTsRecordUseTimestamp(t, r.device)
+		dataPoint := getDataPoint()
+		dataPoint.SetValue(chunkHeader.GetSensor(), values[i])
+		tr.AddTuple(dataPoint)
+		r.writer.Write(tr)
+	}
+	return nil
+}
+
+// checkPage decodes the page and checks its points against its header.
+// Without checksums the statistics of the header are all that tells a
+// damaged value.
+func checkPage(data []byte, pageHeader *header.PageHeader, chunkHeader *header.ChunkHeader) (times []int64, values []interface{}, err error) {
+	times, values, err = decodePage(data, pageHeader, chunkHeader)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(times) != int(pageHeader.GetNumberOfValues()) {
+		return nil, nil, fmt.Errorf("%d values, the header has %d", len(times), pageHeader.GetNumberOfValues())
+	}
+	if len(times) == 0 {
+		return nil, nil, errors.New("no values")
+	}
+	if times[0] != pageHeader.Min_timestamp() || times[len(times)-1] != pageHeader.Max_timestamp() {
+		return nil, nil, fmt.Errorf("times [%d, %d], the header has [%d, %d]", times[0], times[len(times)-1],
+			pageHeader.Min_timestamp(), pageHeader.Max_timestamp())
+	}
+	for i := 1; i < len(times); i++ {
+		if times[i] <= times[i-1] {
+			return nil, nil, fmt.Errorf("time %d is not after %d", times[i], times[i-1])
+		}
+	}
+	computed := statistics.GetStatsByType(int16(chunkHeader.GetDataType()))
+	for i, t := range times {
+		computed.UpdateStats(t, values[i])
+	}
+	if field := read.StatisticsMismatch(*pageHeader.GetStatistics(), computed); field != "" {
+		return nil, nil, fmt.Errorf("the %s of the values differs from the one of the header", field)
+	}
+	return times, values, nil
+}
+
+func decodePage(data []byte, pageHeader *header.PageHeader, chunkHeader *header.ChunkHeader) (times []int64, values []interface{}, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			times, values, err = nil, nil, fmt.Errorf("cannot be decoded: %v", e)
+		}
+	}()
+	decompressor, err := compress.GetDecompressor(chunkHeader.GetCompressionType())
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot be decompressed: %v", err)
+	}
+	data, err = decompressor.Decompress(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot be decompressed: %v", err)
+	}
+	if len(data) != int(pageHeader.GetUncompressedSize()) {
+		return nil, nil, fmt.Errorf("uncompressed size %d, the header has %d", len(data), pageHeader.GetUncompressedSize())
+	}
+	reader := utils.NewBytesReader(data)
+	timeLength := int(reader.ReadUnsignedVarInt())
+	pos := reader.Pos()
+	if timeLength < 0 || pos+timeLength > len(data) {
+		return nil, nil, fmt.Errorf("timestamps of %d bytes run past the page of %d bytes", timeLength, len(data))
+	}
+	timeDecoder, err := decoder.CreateDecoder(chunkHeader.GetTimeEncodingType(), constant.INT64)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot be decoded: %v", err)
+	}
+	valueDecoder, err := decoder.CreateDecoder(chunkHeader.GetEncodingType(), chunkHeader.GetDataType())
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot be decoded: %v", err)
+	}
+	timeDecoder.Init(data[pos : pos+timeLength])
+	valueDecoder.Init(data[pos+timeLength:])
+	for _, d := range []decoder.Decoder{timeDecoder, valueDecoder} {
+		if err := decoder.Err(d); err != nil {
+			return nil, nil, fmt.Errorf("cannot be decoded: %v", err)
+		}
+	}
+	for timeDecoder.HasNext() && valueDecoder.HasNext() {
+		times = append(times, timeDecoder.Next().(int64))
+		values = append(values, valueDecoder.Next())
+	}
+	if timeDecoder.HasNext() != valueDecoder.HasNext() {
+		return nil, nil, errors.New("the numbers of timestamps and values differ")
+	}
+	return times, values, nil
+}
+
+// verify checks the block at pos against the checksum of the footer, if any
+func (r *repairer) verify(pos int64, headerSize int32) bool {
+	checksum, ok := r.checksums[pos]
+	if !ok {
+		return true
+	}
+	if checksum.Length < headerSize {
+		return false
+	}
+	return crc32.Checksum(r.reader.ReadAt(int(checksum.Length), pos), metadata.CRC32C) == checksum.Crc
+}
+
+// hasName looks for the length prefixed device or sensor a header starts
+// with, cheaply, before the header is parsed
+func (r *repairer) hasName(pos int64) bool {
+	prefix := r.peek(pos, constant.INT_LEN)
+	if prefix == nil {
+		return false
+	}
+	length := int(int32(uint32(prefix[0])<<24 | uint32(prefix[1])<<16 | uint32(prefix[2])<<8 | uint32(prefix[3])))
+	if length <= 0 || length > maxRepairNameLength {
+		return false
+	}
+	name := r.peek(pos+int64(constant.INT_LEN), length)
+	if name == nil || !utf8.Valid(name) {
+		return false
+	}
+	for _, c := range string(name) {
+		if !unicode.IsPrint(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// peek returns the n bytes at pos, nil if they run past the limit
+func (r *repairer) peek(pos int64, n int) []byte {
+	if pos+int64(n) > r.limit {
+		return nil
+	}
+	if pos < r.windowPos || pos+int64(n) > r.windowPos+int64(len(r.window)) {
+		size := min(max(int64(n), 64*1024), r.limit-pos)
+		r.window = make([]byte, size)
+		read, _ := r.fin.ReadAt(r.window, pos)
+		r.window, r.windowPos = r.window[:read], pos
+		if read < n {
+			return nil
+		}
+	}
+	return r.window[pos-r.windowPos : pos-r.windowPos+int64(n)]
+}
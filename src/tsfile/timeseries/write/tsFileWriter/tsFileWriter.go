@@ -9,9 +9,20 @@ package tsFileWriter
  */
 
 import (
-	_ "time"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
 	"tsfile/common/conf"
+	"tsfile/common/constant"
 	"tsfile/common/log"
+	"tsfile/common/utils"
+	"tsfile/compress"
+	"tsfile/file/metadata"
+	"tsfile/timeseries/read"
 	"tsfile/timeseries/write/fileSchema"
 	"tsfile/timeseries/write/sensorDescriptor"
 )
@@ -29,15 +40,34 @@ type TsFileWriter struct {
 	lastGroupDevice            *RowGroupWriter
 	lastSeriesWriter           *SeriesWriter
 	lastSessorId               string
+	wal                        *WalWriter
+	// the auto flush goroutine shares the writer with the caller
+	mutex     sync.Mutex
+	flushStop chan struct{}
 }
 
-func (t *TsFileWriter) AddSensor(sd *sensorDescriptor.SensorDescriptor) []byte {
+// AddSensor adds a sensor to all devices, it fails if the pages of the sensor
+// can not be encoded or compressed as described.
+func (t *TsFileWriter) AddSensor(sd *sensorDescriptor.SensorDescriptor) error {
+	if err := checkSensor(sd); err != nil {
+		return err
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if err := checkSensorVersion(sd, t.tsFileIoWriter.version); err != nil {
+		return err
+	}
 	if _, ok := t.schema.GetSensorDescriptiorMap()[sd.GetSensorId()]; !ok {
 		t.schema.GetSensorDescriptiorMap()[sd.GetSensorId()] = sd
 	} else {
 		log.Info("the given sensor has exist!")
 	}
 	t.schema.Registermeasurement(sd)
+	if t.wal != nil {
+		if err := t.wal.WriteSensor(sd); err != nil {
+			log.Error("write sensor to wal error: %s", err)
+		}
+	}
 	t.oneRowMaxSize = t.schema.GetCurrentRowMaxSize()
 	//if t.primaryRowGroupSize <= int64(t.oneRowMaxSize) {
 	//	log.Info("AddSensor error: the potential size of one row is too large.")
@@ -49,6 +79,147 @@ func (t *TsFileWriter) AddSensor(sd *sensorDescriptor.SensorDescriptor) []byte {
 	return nil
 }
 
+func checkSensor(sd *sensorDescriptor.SensorDescriptor) error {
+	if sd.GetValueEncoder() == nil {
+		return errors.New("sensor " + sd.GetSensorId() + ": unsupported encoding " + strconv.Itoa(int(sd.GetTsEncoding())) +
+			" of data type " + strconv.Itoa(int(sd.GetTsDataType())))
+	}
+	if sd.GetTimeEncoder() == nil {
+		return errors.New("sensor " + sd.GetSensorId() + ": unsupported time encoding " + strconv.Itoa(int(sd.GetTimeEncoding())))
+	}
+	if err := compress.CheckCompression(sd.GetCompresstionType(), sd.GetCompressionLevel()); err != nil {
+		return errors.New("sensor " + sd.GetSensorId() + ": " + err.Error())
+	}
+	if filterType, params := sd.GetLossyFilter(); filterType != int16(constant.UNCOMPRESSED) {
+		switch constant.TSDataType(sd.GetTsDataType()) {
+		case constant.INT32, constant.INT64, constant.FLOAT, constant.DOUBLE:
+		default:
+			return errors.New("sensor " + sd.GetSensorId() + ": lossy filter of non numeric data type " +
+				strconv.Itoa(int(sd.GetTsDataType())))
+		}
+		if _, err := compress.NewLossyFilter(constant.CompressionType(filterType), params); err != nil {
+			return errors.New("sensor " + sd.GetSensorId() + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+// checkSensorVersion fails for the sensors the version of the format can not
+// describe, VERSION_3 has the encodings and compressions of the TsFile v0.8,
+// whose times are always TS_2DIFF.
+func checkSensorVersion(sd *sensorDescriptor.SensorDescriptor, version int) error {
+	if version >= metadata.VERSION_4 {
+		return nil
+	}
+	if constant.TSEncoding(sd.GetTsEncoding()) > constant.GORILLA {
+		return errors.New("sensor " + sd.GetSensorId() + ": encoding not in format version " + strconv.Itoa(version))
+	}
+	if constant.TSEncoding(sd.GetTimeEncoding()) != constant.TS_2DIFF {
+		return errors.New("sensor " + sd.GetSensorId() + ": time encoding not in format version " + strconv.Itoa(version))
+	}
+	if constant.CompressionType(sd.GetCompresstionType()) > constant.LZO {
+		return errors.New("sensor " + sd.GetSensorId() + ": compression not in format version " + strconv.Itoa(version))
+	}
+	return nil
+}
+
+// SetVersion makes the writer write the given version of the format, e.g.
+// metadata.VERSION_3 for files read by the TsFile v0.8. It fails if the
+// sensors added can not be written in it, VERSION_3 has no per device sensors.
+func (t *TsFileWriter) SetVersion(version int) error {
+	if err := metadata.CheckVersion(version); err != nil {
+		return err
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, sd := range t.schema.GetSensorDescriptiorMap() {
+		if err := checkSensorVersion(sd, version); err != nil {
+			return err
+		}
+	}
+	if version < metadata.VERSION_4 && len(t.schema.GetDeviceSensorDescriptorMap()) > 0 {
+		return errors.New("per device sensors not in format version " + strconv.Itoa(version))
+	}
+	t.tsFileIoWriter.version = version
+	if t.wal != nil {
+		if err := t.wal.WriteVersion(version); err != nil {
+			log.Error("write version to wal error: %s", err)
+		}
+	}
+	return nil
+}
+
+// AddDeviceSensor adds a sensor to one device only. The same sensor id may be
+// registered with another type for another device, or globally by AddSensor.
+func (t *TsFileWriter) AddDeviceSensor(deviceId string, sd *sensorDescriptor.SensorDescriptor) error {
+	if err := checkSensor(sd); err != nil {
+		return err
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.tsFileIoWriter.version < metadata.VERSION_4 {
+		return errors.New("sensor " + sd.GetSensorId() + ": per device sensors not in format version " +
+			strconv.Itoa(t.tsFileIoWriter.version))
+	}
+	t.addDeviceSensor(deviceId, sd)
+	return nil
+}
+
+// RegisterTemplate names a set of sensors shared by many devices.
+func (t *TsFileWriter) RegisterTemplate(name string, sds ...*sensorDescriptor.SensorDescriptor) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.schema.RegisterTemplate(name, sds)
+}
+
+// ApplyTemplate adds the sensors of the template to every given device.
+func (t *TsFileWriter) ApplyTemplate(name string, deviceIds ...string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	sds, ok := t.schema.GetTemplate(name)
+	if !ok {
+		log.Error("template %s not found", name)
+		return false
+	}
+	if t.tsFileIoWriter.version < metadata.VERSION_4 {
+		log.Error("template %s: per device sensors not in format version %d", name, t.tsFileIoWriter.version)
+		return false
+	}
+	for _, sd := range sds {
+		if err := checkSensor(sd); err != nil {
+			log.Error("template %s: %s", name, err)
+			return false
+		}
+	}
+	for _, deviceId := range deviceIds {
+		for _, sd := range sds {
+			t.addDeviceSensor(deviceId, sd)
+		}
+	}
+	return true
+}
+
+// HasSensor tells whether Write keeps the points of the sensor for the device,
+// those of unknown sensors are dropped.
+func (t *TsFileWriter) HasSensor(deviceId string, sensorId string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	_, ok := t.schema.GetSensorDescriptor(deviceId, sensorId)
+	return ok
+}
+
+func (t *TsFileWriter) addDeviceSensor(deviceId string, sd *sensorDescriptor.SensorDescriptor) {
+	t.schema.RegisterDeviceMeasurement(deviceId, sd)
+	if t.wal != nil {
+		if err := t.wal.WriteDeviceSensor(deviceId, sd); err != nil {
+			log.Error("write sensor to wal error: %s", err)
+		}
+	}
+	t.oneRowMaxSize = t.schema.GetCurrentRowMaxSize()
+	t.rowGroupSizeThreshold = t.primaryRowGroupSize - int64(t.oneRowMaxSize)
+	t.checkMemorySizeAndMayFlushGroup()
+}
+
 //func (t *TsFileWriter)checkMemorySize()(bool){
 //	if t.recordCount >= t.recordCountForNextMemCheck {
 //		// calculate all group size
@@ -68,40 +239,53 @@ func (t *TsFileWriter) AddSensor(sd *sensorDescriptor.SensorDescriptor) []byte {
 /**
  * flush the data in all series writers and their page writers to outputStream.
  * @param isFillRowGroup whether to fill RowGroup
- * @return true - size of tsfile or metadata reaches the threshold.
- * 		 false - otherwise. But this function just return false, the Override of IoTDB may return true.
+ * @return the error writing the row groups to the file or resetting the wal.
  */
-func (t *TsFileWriter) flushAllRowGroups(isFillRowGroup bool) bool {
+func (t *TsFileWriter) flushAllRowGroups(isFillRowGroup bool) error {
 	// flush data to disk
 	if t.recordCount > 0 {
-		totalMemStart := t.tsFileIoWriter.GetPos()
 		if t.recordCount > 0 {
 			for _, v := range t.groupDevices {
 				v.PreFlush()
 			}
-			for k, v := range t.groupDevices {
+			// row groups in the order of the devices, the file only depends on the data
+			for _, k := range utils.SortedKeys(t.groupDevices) {
 				groupDevice := t.groupDevices[k]
 				//rowGroupSize := 1 * 4 + 1 * 8 + len(v.deviceId) + 1 * 4
-				rowGroupSize := v.GetCurrentRowGroupSize()
+				rowGroupSize := groupDevice.GetCurrentRowGroupSize()
+				rowGroupStart := t.tsFileIoWriter.GetPos()
 				// write rowgroup header to file
 				t.tsFileIoWriter.StartFlushRowGroup(k, int64(rowGroupSize), groupDevice.GetSeriesNumber())
 				// write chunk to file
 				groupDevice.FlushToFileWriter(t.tsFileIoWriter)
 				// finished write file(and then write filemeta to file)
-				t.tsFileIoWriter.EndRowGroup(t.tsFileIoWriter.GetPos() - totalMemStart)
+				t.tsFileIoWriter.EndRowGroup(t.tsFileIoWriter.GetPos() - rowGroupStart)
 			}
 		}
 		//log.Info("write to rowGroup end!")
 		t.recordCount = 0
 		t.reset()
+		if err := t.tsFileIoWriter.Err(); err != nil {
+			return err
+		}
+		// the buffered records are in the file now, drop them from wal
+		if t.wal != nil {
+			if err := t.wal.Reset(t.tsFileIoWriter.GetPos(), t.tsFileIoWriter.version, t.schema); err != nil {
+				return err
+			}
+		}
 	}
-	return true
+	return nil
 }
 
 func (t *TsFileWriter) reset() {
 	for k, _ := range t.groupDevices {
 		delete(t.groupDevices, k)
 	}
+	// the cached writers are flushed too, do not write into them again
+	t.lastGroupDevice = nil
+	t.lastSeriesWriter = nil
+	t.lastSessorId = ""
 }
 
 func (t *TsFileWriter) Write(tr *TsRecord) bool {
@@ -115,6 +299,14 @@ func (t *TsFileWriter) Write(tr *TsRecord) bool {
 	var dataSW *SeriesWriter
 	//var dataSWLast *SeriesWriter
 
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.wal != nil {
+		if err := t.wal.WriteRecord(tr); err != nil {
+			log.Error("write record to wal error: %s", err)
+		}
+	}
+
 	// check device
 	var strDeviceID string = tr.GetDeviceId()
 	if t.lastGroupDevice != nil && (t.lastGroupDevice.deviceId == strDeviceID) {
@@ -133,7 +325,6 @@ func (t *TsFileWriter) Write(tr *TsRecord) bool {
 	}
 
 	timeST := tr.GetTime()
-	schemaSensorDescriptorMap := t.schema.GetSensorDescriptiorMap()
 	data := tr.GetDataPointSli()
 	//log.CostWriteTimesTest2 += int64(time.Since(tsCurNew2))
 	for _, v := range data {
@@ -146,9 +337,9 @@ func (t *TsFileWriter) Write(tr *TsRecord) bool {
 
 			if !ok {
 				//if not exist SeriesWriter, new it
-				sensorDescriptor, bExistSensorDesc := schemaSensorDescriptorMap[sessorID]
+				sensorDescriptor, bExistSensorDesc := t.schema.GetSensorDescriptor(strDeviceID, sessorID)
 				if !bExistSensorDesc {
-					log.Error("input sensor is invalid: ", sessorID)
+					log.Error("input sensor is invalid: %v", sessorID)
 				} else {
 					// new pagewriter
 					pw, _ := NewPageWriter(sensorDescriptor)
@@ -167,6 +358,8 @@ func (t *TsFileWriter) Write(tr *TsRecord) bool {
 			//v.Write(t, dataSeriesWriter)
 			if dataSW.GetTsDeviceId() == "" {
 				log.Info("give seriesWriter is null, do nothing and return.")
+			} else if dataSW.lossyFilter != nil {
+				dataSW.Write(timeST, v)
 			} else {
 				//dataSW.Write(timeST, v)
 				dataSW.time = timeST
@@ -183,7 +376,7 @@ func (t *TsFileWriter) Write(tr *TsRecord) bool {
 				//log.CostWriteTimesTest1 += int64(time.Since(tsCurNew2))
 				dataSW.valueCount++
 				// statistics ignore here, if necessary, Statistics.java
-				dataSW.pageStatistics.UpdateStats(valueInterface)
+				dataSW.pageStatistics.UpdateStats(timeST, valueInterface)
 
 				if dataSW.minTimestamp == -1 {
 					dataSW.minTimestamp = timeST
@@ -209,15 +402,94 @@ func (t *TsFileWriter) Write(tr *TsRecord) bool {
 	return t.checkMemorySizeAndMayFlushGroup()
 }
 
+// Flush seals the buffered data of all devices into row groups, so that it is
+// in the file (not necessarily on disk, see Sync) and visible to a recovery.
+func (t *TsFileWriter) Flush() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.flushAllRowGroups(false)
+}
+
+// Sync flushes like Flush and then commits the file and the wal to disk.
+func (t *TsFileWriter) Sync() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if err := t.flushAllRowGroups(false); err != nil {
+		return err
+	}
+	if err := t.tsFileIoWriter.GetTsIoFile().Sync(); err != nil {
+		return err
+	}
+	if t.wal != nil {
+		return t.wal.Sync()
+	}
+	return nil
+}
+
+// SetFlushInterval flushes the buffered data every interval in background,
+// which bounds how long a slow device keeps its points in memory.
+// A zero interval stops flushing by time.
+func (t *TsFileWriter) SetFlushInterval(interval time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.stopAutoFlush()
+	if interval <= 0 {
+		return
+	}
+	t.flushStop = make(chan struct{})
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := t.Flush(); err != nil {
+					log.Error("flush error: %s", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}(t.flushStop)
+}
+
+func (t *TsFileWriter) stopAutoFlush() {
+	if t.flushStop != nil {
+		close(t.flushStop)
+		t.flushStop = nil
+	}
+}
+
 func (t *TsFileWriter) Close() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.stopAutoFlush()
 	// finished write file, and write magic string at file tail
 	//t.tsFileIoWriter.WriteMagic()
 	//t.tsFileIoWriter.tsIoFile.Write([]byte("\n"))
 	//t.tsFileIoWriter.tsIoFile.Close()
 
 	t.CalculateMemSizeForAllGroup()
-	t.flushAllRowGroups(false)
+	if err := t.flushAllRowGroups(false); err != nil {
+		log.Error("flush row groups error: %s", err)
+		t.tsFileIoWriter.Close()
+		return false
+	}
 	t.tsFileIoWriter.EndFile(*t.schema)
+	if err := t.tsFileIoWriter.Err(); err != nil {
+		log.Error("write footer error: %s", err)
+		t.tsFileIoWriter.Close()
+		return false
+	}
+	if err := t.tsFileIoWriter.Close(); err != nil {
+		log.Error("close tsfile error: %s", err)
+		return false
+	}
+	// the file is sealed, nothing left to recover
+	if t.wal != nil {
+		t.wal.Remove()
+		t.wal = nil
+	}
 	t.lastGroupDevice = nil
 	t.lastSeriesWriter = nil
 	t.lastSessorId = ""
@@ -235,7 +507,10 @@ func (t *TsFileWriter) checkMemorySizeAndMayFlushGroup() bool {
 			//	log.Info("tsFileWriter oneRowMaxSize is not correct.")
 			//}
 
-			return t.flushAllRowGroups(false)
+			if err := t.flushAllRowGroups(false); err != nil {
+				log.Error("flush row groups error: %s", err)
+			}
+			return true
 		} else {
 			if t.oneRowMaxSize != 0 {
 				t.recordCountForNextMemCheck = t.recordCount + (t.rowGroupSizeThreshold-memSize)/int64(t.oneRowMaxSize)
@@ -274,17 +549,16 @@ func (t *TsFileWriter) checkIsDeviceExist(tr *TsRecord, schema *fileSchema.FileS
 		//} else { // if exist
 		//	groupDevice = t.groupDevices[tr.GetDeviceId()]
 	}
-	schemaSensorDescriptorMap := schema.GetSensorDescriptiorMap()
 	data := tr.GetDataPointSli()
 	for _, v := range data {
 		//if contain, _ := utils.MapContains(schemaSensorDescriptorMap, v.GetSensorId()); contain {
 		//	//groupDevice.AddSeriesWriter(schemaSensorDescriptorMap[v.GetSensorId()], tsFileConf.PageSizeInByte)
 		//	t.groupDevices[tr.GetDeviceId()].AddSeriesWriter(schemaSensorDescriptorMap[v.GetSensorId()], conf.PageSizeInByte)
-		sensorDescriptor, bExistSensorDesc := schemaSensorDescriptorMap[v.GetSensorId()]
+		sensorDescriptor, bExistSensorDesc := schema.GetSensorDescriptor(tr.GetDeviceId(), v.GetSensorId())
 		if bExistSensorDesc {
 			groupDevice.AddSeriesWriter(sensorDescriptor, conf.PageSizeInByte)
 		} else {
-			log.Error("input sensor is invalid: ", v.GetSensorId())
+			log.Error("input sensor is invalid: %v", v.GetSensorId())
 		}
 		//log.Info("k=%v, v=%v\n", k, v)
 	}
@@ -292,26 +566,181 @@ func (t *TsFileWriter) checkIsDeviceExist(tr *TsRecord, schema *fileSchema.FileS
 }
 
 func NewTsFileWriter(file string) (*TsFileWriter, error) {
+	// tsFileIoWriter
+	tfiWriter, err := NewTsFileIoWriter(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// write start magic
+	if tfiWriter.WriteMagic() != len(conf.MAGIC_STRING) {
+		tfiWriter.Close()
+		return nil, errors.New("cannot write the magic of " + file)
+	}
+
+	return newTsFileWriter(tfiWriter), nil
+}
+
+// NewTsFileWriterWithWal is NewTsFileWriter with a write ahead log beside the
+// file, so that an unclosed file can be brought back by RecoverTsFile.
+func NewTsFileWriterWithWal(file string) (*TsFileWriter, error) {
+	tsFileWriter, err := NewTsFileWriter(file)
+	if err != nil {
+		return nil, err
+	}
+	wal, err := NewWalWriter(file, tsFileWriter.tsFileIoWriter.GetPos())
+	if err != nil {
+		tsFileWriter.tsFileIoWriter.Close()
+		return nil, err
+	}
+	tsFileWriter.wal = wal
+	return tsFileWriter, nil
+}
+
+// OpenTsFileWriterForAppend reopens a sealed tsfile to write more row groups.
+// The old footer is cut off, the old row groups and schema are kept and a
+// footer covering both old and new data is written on Close.
+func OpenTsFileWriterForAppend(file string) (*TsFileWriter, error) {
+	if _, err := os.Stat(file); err != nil {
+		return nil, err
+	}
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(file); err != nil {
+		return nil, errors.New("append: " + err.Error())
+	}
+	defer f.Close()
+	fileMetaData := f.ReadFileMetadata()
+
+	rowGroups := make([]*metadata.RowGroupMetaData, 0)
+	for _, deviceMetaData := range fileMetaData.DeviceMap() {
+		rowGroups = append(rowGroups, deviceMetaData.GetRowGroups()...)
+	}
+	sort.Slice(rowGroups, func(i, j int) bool {
+		return rowGroups[i].GetFileOffsetOfCorrespondingData() < rowGroups[j].GetFileOffsetOfCorrespondingData()
+	})
+
+	// the footer only keeps the data type of a sensor, encoding and
+	// compression are taken from its chunks, which must agree
+	sensors := make([]*sensorDescriptor.SensorDescriptor, 0)
+	timeSeriesMap := fileMetaData.TimeSeriesMetadataMap()
+	for _, sensorId := range utils.SortedKeys(timeSeriesMap) {
+		sd, err := rebuildSensor(f, rowGroups, sensorId, timeSeriesMap[sensorId].DataType(), fileMetaData.DeviceSchemaMap())
+		if err != nil {
+			return nil, err
+		}
+		sensors = append(sensors, sd)
+	}
+	deviceSensors := make(map[string][]*sensorDescriptor.SensorDescriptor)
+	for _, deviceId := range utils.SortedKeys(fileMetaData.DeviceSchemaMap()) {
+		schema := fileMetaData.DeviceSchemaMap()[deviceId]
+		var deviceRowGroups []*metadata.RowGroupMetaData
+		if deviceMetaData, ok := fileMetaData.DeviceMap()[deviceId]; ok {
+			deviceRowGroups = deviceMetaData.GetRowGroups()
+		}
+		for _, sensorId := range utils.SortedKeys(schema) {
+			sd, err := rebuildSensor(f, deviceRowGroups, sensorId, schema[sensorId].DataType(), nil)
+			if err != nil {
+				return nil, err
+			}
+			deviceSensors[deviceId] = append(deviceSensors[deviceId], sd)
+		}
+	}
+	// the footer is only cut off for sensors the writer takes
+	for _, sd := range sensors {
+		if err := checkSensor(sd); err != nil {
+			return nil, errors.New("append: " + err.Error())
+		}
+		if err := checkSensorVersion(sd, f.Version()); err != nil {
+			return nil, errors.New("append: " + err.Error())
+		}
+	}
+	for _, sds := range deviceSensors {
+		for _, sd := range sds {
+			if err := checkSensor(sd); err != nil {
+				return nil, errors.New("append: " + err.Error())
+			}
+		}
+	}
+
+	tfiWriter, err := ResumeTsFileIoWriter(file, f.MetadataPos(), rowGroups)
+	if err != nil {
+		return nil, err
+	}
+	// the file keeps its version
+	tfiWriter.version = f.Version()
+	// all blocks with a checksum are before the footer and kept
+	for offset, checksum := range fileMetaData.GetChecksums() {
+		tfiWriter.checksums[offset] = checksum
+	}
+	tsFileWriter := newTsFileWriter(tfiWriter)
+	for _, sd := range sensors {
+		if err := tsFileWriter.AddSensor(sd); err != nil {
+			// seal the file again with the old footer
+			tsFileWriter.Close()
+			return nil, errors.New("append: " + err.Error())
+		}
+	}
+	for _, deviceId := range utils.SortedKeys(deviceSensors) {
+		for _, sd := range deviceSensors[deviceId] {
+			if err := tsFileWriter.AddDeviceSensor(deviceId, sd); err != nil {
+				tsFileWriter.Close()
+				return nil, errors.New("append: " + err.Error())
+			}
+		}
+	}
+	return tsFileWriter, nil
+}
+
+// rebuildSensor describes the sensor by its chunks, it fails if they are not
+// written the same way. The chunks of the devices whose own schema shadows the
+// sensor are skipped.
+func rebuildSensor(f *read.TsFileSequenceReader, rowGroups []*metadata.RowGroupMetaData, sensorId string,
+	tsDataType constant.TSDataType, shadowed map[string]map[string]*metadata.TimeSeriesMetaData) (*sensorDescriptor.SensorDescriptor, error) {
+	var sd *sensorDescriptor.SensorDescriptor
+	for _, rowGroup := range rowGroups {
+		if _, ok := shadowed[rowGroup.GetDeviceId()][sensorId]; ok {
+			continue
+		}
+		for _, chunkMetaData := range rowGroup.GetChunkMetaDataSli() {
+			if chunkMetaData.Sensor() != sensorId {
+				continue
+			}
+			chunkHeader := f.ReadChunkHeaderAt(chunkMetaData.FileOffsetOfCorrespondingData())
+			chunkSensor, _ := sensorDescriptor.NewWithCompress(sensorId, tsDataType,
+				chunkHeader.GetEncodingType(), chunkHeader.GetCompressionType())
+			chunkSensor.SetTimeEncoding(chunkHeader.GetTimeEncodingType())
+			if digest := chunkMetaData.GetDigest(); digest != nil {
+				if filterType, params, ok := digest.GetLossyFilter(); ok {
+					chunkSensor.SetLossyFilter(filterType, params)
+				}
+			}
+			if sd == nil {
+				sd = chunkSensor
+			} else if !sameSensor(sd, chunkSensor) {
+				return nil, fmt.Errorf("append: the chunk of %s%s%s at %d is not written like the first one of %s",
+					rowGroup.GetDeviceId(), constant.PATH_SEPARATOR, sensorId, chunkMetaData.FileOffsetOfCorrespondingData(), sensorId)
+			}
+		}
+	}
+	if sd == nil {
+		sd, _ = sensorDescriptor.NewWithCompress(sensorId, tsDataType,
+			constant.GetEncodingByName(conf.ValueEncoder), constant.UNCOMPRESSED)
+	}
+	return sd, nil
+}
+
+func newTsFileWriter(tfiWriter *TsFileIoWriter) *TsFileWriter {
 	// file schema
 	fs, fsErr := fileSchema.New()
 	if fsErr != nil {
 		log.Error("init fileSchema failed.")
 	}
 
-	// tsFileIoWriter
-	tfiWriter, tfiwErr := NewTsFileIoWriter(file)
-	if tfiwErr != nil {
-		log.Error("init tsFileWriter error = ", tfiwErr)
-	}
-
-	// write start magic
-	tfiWriter.WriteMagic()
-
 	// init rowGroupSizeThreshold
 	var prgs int64 = int64(conf.GroupSizeInByte)
 	rgst := int64(conf.GroupSizeInByte) - prgs
 
-	return &TsFileWriter{
+	tsFileWriter := &TsFileWriter{
 		tsFileIoWriter:             tfiWriter,
 		schema:                     fs,
 		recordCount:                0,
@@ -319,5 +748,9 @@ func NewTsFileWriter(file string) (*TsFileWriter, error) {
 		primaryRowGroupSize:        prgs,
 		rowGroupSizeThreshold:      rgst,
 		groupDevices:               make(map[string]*RowGroupWriter),
-	}, nil
+	}
+	if conf.FlushIntervalInMs > 0 {
+		tsFileWriter.SetFlushInterval(time.Duration(conf.FlushIntervalInMs) * time.Millisecond)
+	}
+	return tsFileWriter
 }
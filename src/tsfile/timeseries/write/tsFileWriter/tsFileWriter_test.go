@@ -0,0 +1,1053 @@
+package tsFileWriter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"tsfile/common/conf"
+	"tsfile/common/constant"
+	"tsfile/file/metadata"
+	"tsfile/file/metadata/statistics"
+	"tsfile/timeseries/read"
+	"tsfile/timeseries/write/sensorDescriptor"
+)
+
+// tempFile is the path of the file name in a directory removed with the test
+func tempFile(t *testing.T, name string) string {
+	return filepath.Join(t.TempDir(), name)
+}
+
+func TestOpenTsFileWriterForAppend(t *testing.T) {
+	appendFilePath := tempFile(t, "append_TsFile")
+
+	w, _ := NewTsFileWriter(appendFilePath)
+	des, _ := sensorDescriptor.NewWithCompress("s0", constant.INT64, constant.TS_2DIFF, constant.SNAPPY)
+	w.AddSensor(des)
+	writeLongs(w, "root.d0", 1, 6)
+	writeLongs(w, "root.d1", 1, 3)
+	w.Close()
+
+	w, err := OpenTsFileWriterForAppend(appendFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd := w.schema.GetSensorDescriptiorMap()["s0"]
+	if sd.GetTsEncoding() != int16(constant.TS_2DIFF) || sd.GetCompresstionType() != int16(constant.SNAPPY) {
+		t.Fatal("schema of the appended file should be kept")
+	}
+	writeLongs(w, "root.d0", 6, 11)
+	w.Close()
+
+	points := readAllPoints(t, appendFilePath)
+	if len(points["root.d0.s0"]) != 10 || len(points["root.d1.s0"]) != 2 {
+		t.Fatalf("expected 10 and 2 points, got %v", points)
+	}
+	for i, v := range points["root.d0.s0"] {
+		if v != int64(i+1) {
+			t.Fatalf("expected %d got %d", i+1, v)
+		}
+	}
+
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(appendFilePath); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	deviceMetaData := f.ReadFileMetadata().DeviceMap()["root.d0"]
+	if len(deviceMetaData.GetRowGroups()) != 2 {
+		t.Fatal("footer should cover the old and the appended row groups")
+	}
+}
+
+func TestAppendDifferingChunks(t *testing.T) {
+	appendFilePath := tempFile(t, "append_TsFile")
+	w, _ := NewTsFileWriter(appendFilePath)
+	plain, _ := sensorDescriptor.New("s0", constant.INT64, constant.PLAIN)
+	w.AddSensor(plain)
+	writeLongs(w, "root.d0", 1, 6)
+	w.Close()
+	// the chunks of a merged or recovered file may differ in their encoding
+	w, err := OpenTsFileWriterForAppend(appendFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, _ := sensorDescriptor.New("s0", constant.INT64, constant.TS_2DIFF)
+	w.schema.GetSensorDescriptiorMap()["s0"] = diff
+	writeLongs(w, "root.d0", 6, 11)
+	w.Close()
+
+	data, _ := os.ReadFile(appendFilePath)
+	if _, err := OpenTsFileWriterForAppend(appendFilePath); err == nil || !strings.Contains(err.Error(), "root.d0.s0") {
+		t.Fatalf("expected the chunks of root.d0.s0 to differ, got %v", err)
+	}
+	if again, _ := os.ReadFile(appendFilePath); !bytes.Equal(again, data) {
+		t.Fatal("the file should be left untouched")
+	}
+}
+
+func TestRollingWriter(t *testing.T) {
+	dir := t.TempDir()
+	sealed := make([]string, 0)
+
+	w, err := NewRollingWriter(filepath.Join(dir, "rolling_"+ROLLING_INDEX+"_TsFile"), RollingPolicy{MaxPointCount: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetSealedHook(func(path string) {
+		sealed = append(sealed, path)
+	})
+	des, _ := sensorDescriptor.New("s0", constant.INT64, constant.PLAIN)
+	w.AddSensor(des)
+	for i := 1; i <= 25; i++ {
+		record, _ := NewTsRecordUseTimestamp(int64(i), "root.d0")
+		pt, _ := NewLong("s0", constant.INT64, int64(i))
+		record.AddTuple(pt)
+		if err := w.Write(record); err != nil {
+			t.Fatal(err)
+		}
+		if i == 15 {
+			// the sensors of a device and of a template reach the current
+			// file and the next ones
+			text, _ := sensorDescriptor.New("s0", constant.TEXT, constant.PLAIN)
+			if err := w.AddDeviceSensor("root.d1", text); err != nil {
+				t.Fatal(err)
+			}
+			double, _ := sensorDescriptor.New("s1", constant.DOUBLE, constant.GORILLA)
+			w.RegisterTemplate("weather", double)
+			if !w.ApplyTemplate("weather", "root.d2") {
+				t.Fatal("cannot apply template")
+			}
+		}
+		if i >= 15 {
+			record, _ = NewTsRecordUseTimestamp(int64(i), "root.d1")
+			text, _ := NewString("s0", constant.TEXT, strconv.Itoa(i))
+			record.AddTuple(text)
+			w.Write(record)
+			record, _ = NewTsRecordUseTimestamp(int64(i), "root.d2")
+			double, _ := NewDouble("s1", constant.DOUBLE, float64(i))
+			record.AddTuple(double)
+			w.Write(record)
+		}
+	}
+	w.Close()
+
+	if len(sealed) != 5 || sealed[4] != filepath.Join(dir, "rolling_4_TsFile") {
+		t.Fatalf("expected 5 sealed files, got %v", sealed)
+	}
+	var d0, d1, d2 []interface{}
+	for _, path := range sealed {
+		points := readAllPoints(t, path)
+		d0 = append(d0, points["root.d0.s0"]...)
+		d1 = append(d1, points["root.d1.s0"]...)
+		d2 = append(d2, points["root.d2.s1"]...)
+	}
+	if len(d0) != 25 || len(d1) != 11 || len(d2) != 11 {
+		t.Fatalf("expected 25, 11 and 11 points, got %v %v %v", d0, d1, d2)
+	}
+	for i := range d1 {
+		if d1[i] != strconv.Itoa(i+15) || d2[i] != float64(i+15) {
+			t.Fatalf("expected %d, got %v and %v", i+15, d1[i], d2[i])
+		}
+	}
+}
+
+func TestRollingWriterExistingFile(t *testing.T) {
+	template := filepath.Join(t.TempDir(), "rolling_"+ROLLING_INDEX+"_TsFile")
+	des, _ := sensorDescriptor.New("s0", constant.INT64, constant.PLAIN)
+	w, _ := NewRollingWriter(template, RollingPolicy{MaxPointCount: 10})
+	w.AddSensor(des)
+	for i := 1; i < 6; i++ {
+		record, _ := NewTsRecordUseTimestamp(int64(i), "root.d0")
+		pt, _ := NewLong("s0", constant.INT64, int64(i))
+		record.AddTuple(pt)
+		w.Write(record)
+	}
+	w.Close()
+	sealed := strings.Replace(template, ROLLING_INDEX, "0", -1)
+	data, _ := os.ReadFile(sealed)
+
+	// a restarted writer starts at the same index again
+	w, _ = NewRollingWriter(template, RollingPolicy{MaxPointCount: 10})
+	w.AddSensor(des)
+	record, _ := NewTsRecordUseTimestamp(6, "root.d0")
+	pt, _ := NewLong("s0", constant.INT64, 6)
+	record.AddTuple(pt)
+	if err := w.Write(record); err == nil || !strings.Contains(err.Error(), "exists") {
+		t.Fatalf("expected the sealed file to be refused, got %v", err)
+	}
+	w.Close()
+	if again, _ := os.ReadFile(sealed); !bytes.Equal(again, data) {
+		t.Fatal("the sealed file should be left untouched")
+	}
+	if points := readAllPoints(t, sealed); len(points["root.d0.s0"]) != 5 {
+		t.Fatalf("expected 5 points, got %v", points)
+	}
+}
+
+func TestRollingWriterTimeSpan(t *testing.T) {
+	dir := t.TempDir()
+	sealed := make([]string, 0)
+
+	w, _ := NewRollingWriter(filepath.Join(dir, "rolling_time_"+ROLLING_TIME+"_TsFile"), RollingPolicy{MaxTimeSpan: 10})
+	w.SetSealedHook(func(path string) {
+		sealed = append(sealed, path)
+	})
+	des, _ := sensorDescriptor.New("s0", constant.INT64, constant.PLAIN)
+	w.AddSensor(des)
+	// 14 is right before the boundary of the first file, 15 and 25 are right on
+	// the boundaries of the first and the second file
+	for _, i := range []int64{5, 9, 14, 15, 19, 25, 26, 35} {
+		record, _ := NewTsRecordUseTimestamp(i, "root.d0")
+		pt, _ := NewLong("s0", constant.INT64, i)
+		record.AddTuple(pt)
+		if err := w.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+
+	expected := [][]interface{}{{int64(5), int64(9), int64(14)}, {int64(15), int64(19)}, {int64(25), int64(26)}, {int64(35)}}
+	if len(sealed) != len(expected) || sealed[1] != filepath.Join(dir, "rolling_time_15_TsFile") {
+		t.Fatalf("expected %d sealed files, got %v", len(expected), sealed)
+	}
+	for i, path := range sealed {
+		if points := readAllPoints(t, path)["root.d0.s0"]; !reflect.DeepEqual(points, expected[i]) {
+			t.Fatalf("file %s: expected %v, got %v", path, expected[i], points)
+		}
+	}
+}
+
+func TestRollingWriterSize(t *testing.T) {
+	dir := t.TempDir()
+	sealed := make([]string, 0)
+	defer func(groupSize int) { conf.GroupSizeInByte = groupSize }(conf.GroupSizeInByte)
+	conf.GroupSizeInByte = 4 * 1024
+
+	w, _ := NewRollingWriter(filepath.Join(dir, "rolling_size_"+ROLLING_INDEX+"_TsFile"), RollingPolicy{MaxFileSize: 16 * 1024})
+	w.SetSealedHook(func(path string) {
+		sealed = append(sealed, path)
+	})
+	des, _ := sensorDescriptor.New("s0", constant.INT64, constant.PLAIN)
+	w.AddSensor(des)
+	for i := 0; i < 10000; i++ {
+		record, _ := NewTsRecordUseTimestamp(int64(i), "root.d0")
+		pt, _ := NewLong("s0", constant.INT64, int64(i))
+		record.AddTuple(pt)
+		if err := w.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+
+	if len(sealed) < 2 {
+		t.Fatalf("expected the files to roll by size, got %v", sealed)
+	}
+	var count int
+	for _, path := range sealed {
+		stat, _ := os.Stat(path)
+		// a file goes beyond the limit by the records written until the next check
+		if stat.Size() > 2*16*1024 {
+			t.Fatalf("file %s of %d bytes is far beyond the limit", path, stat.Size())
+		}
+		count += len(readAllPoints(t, path)["root.d0.s0"])
+	}
+	if count != 10000 {
+		t.Fatalf("expected 10000 points, got %d", count)
+	}
+}
+
+func TestFlushInterval(t *testing.T) {
+	flushFilePath := tempFile(t, "flush_TsFile")
+
+	w, _ := NewTsFileWriter(flushFilePath)
+	des, _ := sensorDescriptor.New("s0", constant.INT64, constant.PLAIN)
+	w.AddSensor(des)
+	writeLongs(w, "root.d0", 1, 6)
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	synced := w.tsFileIoWriter.GetPos()
+	if stat, _ := os.Stat(flushFilePath); stat.Size() != synced || synced <= int64(len(conf.MAGIC_STRING)) {
+		t.Fatal("synced row group should be in the file")
+	}
+
+	w.SetFlushInterval(10 * time.Millisecond)
+	writeLongs(w, "root.d0", 6, 11)
+	time.Sleep(100 * time.Millisecond)
+	if stat, _ := os.Stat(flushFilePath); stat.Size() <= synced {
+		t.Fatal("buffered data should be flushed by time")
+	}
+	w.Close()
+
+	if points := readAllPoints(t, flushFilePath)["root.d0.s0"]; len(points) != 10 {
+		t.Fatalf("expected 10 points, got %v", points)
+	}
+}
+
+func TestFlushError(t *testing.T) {
+	flushFilePath := tempFile(t, "flush_error_TsFile")
+
+	w, _ := NewTsFileWriter(flushFilePath)
+	des, _ := sensorDescriptor.New("s0", constant.INT64, constant.PLAIN)
+	w.AddSensor(des)
+	writeLongs(w, "root.d0", 1, 6)
+	// the row groups can not reach the file any more
+	w.tsFileIoWriter.GetTsIoFile().Close()
+	if err := w.Flush(); err == nil {
+		t.Fatal("expected the error writing the row groups")
+	}
+	if w.Close() {
+		t.Fatal("a file with lost row groups should not be sealed")
+	}
+}
+
+func TestDeviceSchema(t *testing.T) {
+	schemaFilePath := tempFile(t, "schema_TsFile")
+
+	w, _ := NewTsFileWriter(schemaFilePath)
+	des, _ := sensorDescriptor.New("s0", constant.INT64, constant.PLAIN)
+	w.AddSensor(des)
+	floatDes, _ := sensorDescriptor.New("s0", constant.FLOAT, constant.GORILLA)
+	w.RegisterTemplate("float", floatDes)
+	if !w.ApplyTemplate("float", "root.d1", "root.d2") || w.ApplyTemplate("none", "root.d1") {
+		t.Fatal("only a registered template can be applied")
+	}
+	writeLongs(w, "root.d0", 1, 4)
+	for i := 1; i < 4; i++ {
+		record, _ := NewTsRecordUseTimestamp(int64(i), "root.d1")
+		pt, _ := NewFloat("s0", constant.FLOAT, float32(i)/2)
+		record.AddTuple(pt)
+		w.Write(record)
+	}
+	w.Close()
+
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(schemaFilePath); err != nil {
+		t.Fatal(err)
+	}
+	fileMetaData := f.ReadFileMetadata()
+	f.Close()
+	if fileMetaData.GetDataType("root.d0", "s0") != constant.INT64 || fileMetaData.GetDataType("root.d1", "s0") != constant.FLOAT ||
+		fileMetaData.GetDataType("root.d2", "s0") != constant.FLOAT || fileMetaData.GetDataType("root.d0", "s1") != constant.INVALID {
+		t.Fatal("footer should keep the schema of every device")
+	}
+
+	// the device schema survives appending
+	w, err := OpenTsFileWriterForAppend(schemaFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sd, _ := w.schema.GetSensorDescriptor("root.d1", "s0"); sd.GetTsDataType() != int16(constant.FLOAT) || sd.GetTsEncoding() != int16(constant.GORILLA) {
+		t.Fatal("device schema of the appended file should be kept")
+	}
+	writeLongs(w, "root.d0", 4, 6)
+	w.Close()
+
+	points := readAllPoints(t, schemaFilePath)
+	if len(points["root.d0.s0"]) != 5 || len(points["root.d1.s0"]) != 3 || points["root.d1.s0"][2] != float32(1.5) {
+		t.Fatalf("unexpected points %v", points)
+	}
+}
+
+func TestDictionaryEncoding(t *testing.T) {
+	dictFilePath := tempFile(t, "dict_TsFile")
+	defer func(maxSize int) { conf.DictionaryMaxSizeInByte = maxSize }(conf.DictionaryMaxSizeInByte)
+
+	states := []string{"RUNNING", "IDLE", "FAULT"}
+	write := func(count int, value func(i int) string) []interface{} {
+		os.Remove(dictFilePath)
+		w, _ := NewTsFileWriter(dictFilePath)
+		des, _ := sensorDescriptor.New("s0", constant.TEXT, constant.PLAIN_DICTIONARY)
+		w.AddSensor(des)
+		for i := 0; i < count; i++ {
+			record, _ := NewTsRecordUseTimestamp(int64(i+1), "root.d0")
+			pt, _ := NewString("s0", constant.TEXT, value(i))
+			record.AddTuple(pt)
+			w.Write(record)
+		}
+		w.Close()
+		return readAllPoints(t, dictFilePath)["root.d0.s0"]
+	}
+
+	// long runs and short repeats mix rle runs and bit packed runs
+	value := func(i int) string {
+		if i < 50 {
+			return states[0]
+		}
+		return states[i%3]
+	}
+	points := write(101, value)
+	if len(points) != 101 {
+		t.Fatalf("expected 101 points, got %d", len(points))
+	}
+	for i, v := range points {
+		if v != value(i) {
+			t.Fatalf("point %d: expected %s got %v", i, value(i), v)
+		}
+	}
+
+	// the page falls back to plain once the dictionary is too large
+	conf.DictionaryMaxSizeInByte = 64
+	distinct := func(i int) string { return "state-" + strconv.Itoa(i) }
+	points = write(20, distinct)
+	if len(points) != 20 || points[19] != distinct(19) {
+		t.Fatalf("unexpected points %v", points)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	compressionFilePath := tempFile(t, "compression_TsFile")
+
+	w, _ := NewTsFileWriter(compressionFilePath)
+	des, _ := sensorDescriptor.NewWithCompress("s0", constant.INT64, constant.PLAIN, constant.LZO)
+	if w.AddSensor(des) == nil {
+		t.Fatal("LZO has no compressor, the sensor should be rejected")
+	}
+	if _, err := des.GetCompressor().GetEncompressor(int16(constant.LZO), 0); err == nil {
+		t.Fatal("expected no compressor of LZO")
+	}
+	// the configured compressor is the one of the tool, New stays uncompressed
+	defer func(compressor string) { conf.Compressor = compressor }(conf.Compressor)
+	conf.Compressor = "SNAPPY"
+	if des, _ = sensorDescriptor.New("s0", constant.INT64, constant.PLAIN); des.GetCompresstionType() != int16(constant.UNCOMPRESSED) {
+		t.Fatalf("expected an uncompressed sensor, got compression %d", des.GetCompresstionType())
+	}
+	des, _ = sensorDescriptor.NewWithCompress("s0", constant.INT64, constant.PLAIN, constant.GZIP)
+	des.SetCompressionLevel(42)
+	if w.AddSensor(des) == nil {
+		t.Fatal("the gzip level is invalid, the sensor should be rejected")
+	}
+
+	for i, compression := range []constant.CompressionType{constant.GZIP, constant.LZ4, constant.ZSTD} {
+		des, _ = sensorDescriptor.NewWithCompress("s0", constant.INT64, constant.PLAIN, compression)
+		des.SetCompressionLevel(i * 3)
+		if err := w.AddDeviceSensor("root.d"+strconv.Itoa(i), des); err != nil {
+			t.Fatal(err)
+		}
+		writeLongs(w, "root.d"+strconv.Itoa(i), 1, 1001)
+	}
+	w.Close()
+
+	points := readAllPoints(t, compressionFilePath)
+	for i := 0; i < 3; i++ {
+		values := points["root.d"+strconv.Itoa(i)+".s0"]
+		if len(values) != 1000 {
+			t.Fatalf("expected 1000 points of root.d%d, got %d", i, len(values))
+		}
+		for j, v := range values {
+			if v != int64(j+1) {
+				t.Fatalf("expected %d got %v", j+1, v)
+			}
+		}
+	}
+}
+
+func TestLossyFilter(t *testing.T) {
+	lossyFilePath := tempFile(t, "lossy_TsFile")
+
+	w, _ := NewTsFileWriter(lossyFilePath)
+	des, _ := sensorDescriptor.New("s0", constant.BOOLEAN, constant.PLAIN)
+	des.SetLossyFilter(constant.SDT, map[string]string{"deviation": "1"})
+	if w.AddSensor(des) == nil {
+		t.Fatal("a boolean sensor can not be filtered, it should be rejected")
+	}
+	des, _ = sensorDescriptor.New("s0", constant.DOUBLE, constant.PLAIN)
+	des.SetLossyFilter(constant.PAA, map[string]string{"window": "0"})
+	if w.AddSensor(des) == nil {
+		t.Fatal("the window is invalid, the sensor should be rejected")
+	}
+
+	filters := []map[string]string{
+		{"deviation": "0.5", "max_time": "300"},
+		{"window": "10"},
+		{"error_bound": "0.5"},
+	}
+	for i, filterType := range []constant.CompressionType{constant.SDT, constant.PAA, constant.PLA} {
+		des, _ = sensorDescriptor.New("s0", constant.DOUBLE, constant.GORILLA)
+		des.SetLossyFilter(filterType, filters[i])
+		if err := w.AddDeviceSensor("root.d"+strconv.Itoa(i), des); err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < 1000; j++ {
+			record, _ := NewTsRecordUseTimestamp(int64(j), "root.d"+strconv.Itoa(i))
+			pt, _ := NewDouble("s0", constant.DOUBLE, float64(j%10)*0.01+float64(j)*2)
+			record.AddTuple(pt)
+			w.Write(record)
+		}
+	}
+	w.Close()
+
+	points := readAllPoints(t, lossyFilePath)
+	// the noise is below the deviation, the doors are closed by max_time only
+	if sdt := points["root.d0.s0"]; len(sdt) != 5 || sdt[4] != float64(9)*0.01+999*2 {
+		t.Fatalf("expected 5 points of sdt ending at the last one, got %v", sdt)
+	}
+	paa := points["root.d1.s0"]
+	if len(paa) != 100 {
+		t.Fatalf("expected 100 points of paa, got %d", len(paa))
+	}
+	for j, v := range paa {
+		if expected := 0.045 + float64(j*10+4)*2 + 1; math.Abs(v.(float64)-expected) > 1e-9 {
+			t.Fatalf("expected mean %v of window %d, got %v", expected, j, v)
+		}
+	}
+	if pla := points["root.d2.s0"]; len(pla) != 2 || pla[0] != float64(0) || math.Abs(pla[1].(float64)-1998.045) > 0.5 {
+		t.Fatalf("expected a single segment of pla, got %v", pla)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(lossyFilePath); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	deviceMap := f.ReadFileMetadata().DeviceMap()
+	for i, filterType := range []constant.CompressionType{constant.SDT, constant.PAA, constant.PLA} {
+		digest := deviceMap["root.d"+strconv.Itoa(i)].GetRowGroups()[0].GetChunkMetaDataSli()[0].GetDigest()
+		ft, params, ok := digest.GetLossyFilter()
+		if !ok || ft != filterType || len(params) != len(filters[i]) {
+			t.Fatalf("expected lossy filter %d %v in the chunk metadata, got %d %v", filterType, filters[i], ft, params)
+		}
+		for k, v := range filters[i] {
+			if params[k] != v {
+				t.Fatalf("expected %s=%s, got %v", k, v, params)
+			}
+		}
+	}
+}
+
+func TestStatistics(t *testing.T) {
+	statisticsFilePath := tempFile(t, "statistics_TsFile")
+	defer func(maxPoints int) { conf.MaxNumberOfPointsInPage = maxPoints }(conf.MaxNumberOfPointsInPage)
+	conf.MaxNumberOfPointsInPage = 100
+
+	w, _ := NewTsFileWriter(statisticsFilePath)
+	des, _ := sensorDescriptor.New("s0", constant.INT64, constant.PLAIN)
+	w.AddSensor(des)
+	write := func(from int, to int) {
+		for i := from; i < to; i++ {
+			record, _ := NewTsRecordUseTimestamp(int64(i), "root.d0")
+			pt, _ := NewLong("s0", constant.INT64, int64(i*7%1000))
+			record.AddTuple(pt)
+			w.Write(record)
+		}
+	}
+	write(10, 510)
+	w.flushAllRowGroups(false)
+	write(510, 1010)
+	w.Close()
+
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(statisticsFilePath); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	device := f.ReadFileMetadata().DeviceMap()["root.d0"]
+	if device.GetStartTime() != 10 || device.GetEndTime() != 1009 {
+		t.Fatalf("expected device time range [10, 1009], got [%d, %d]", device.GetStartTime(), device.GetEndTime())
+	}
+	for i, rowGroup := range device.GetRowGroups() {
+		chunk := rowGroup.GetChunkMetaDataSli()[0]
+		from := int64(10 + i*500)
+		if chunk.GetStartTime() != from || chunk.GetEndTime() != from+499 {
+			t.Fatalf("expected chunk time range [%d, %d], got [%d, %d]", from, from+499, chunk.GetStartTime(), chunk.GetEndTime())
+		}
+		s := chunk.GetStatistics(constant.INT64).(statistics.TypedStatistics[int64])
+		if s.Count() != 500 || s.First() != from*7%1000 || s.Last() != (from+499)*7%1000 {
+			t.Fatalf("unexpected chunk statistics: count %d, first %d, last %d", s.Count(), s.First(), s.Last())
+		}
+	}
+
+	s := device.GetStatistics("s0", constant.INT64).(statistics.TypedStatistics[int64])
+	var sum float64
+	for i := 10; i < 1010; i++ {
+		sum += float64(i * 7 % 1000)
+	}
+	if s.Min() != 0 || s.Max() != 999 || s.First() != 70 || s.Last() != 1009*7%1000 || s.Sum() != sum ||
+		s.Count() != 1000 || s.FirstTime() != 10 || s.LastTime() != 1009 {
+		t.Fatalf("unexpected device statistics: min %d, max %d, first %d, last %d, sum %v, count %d, time [%d, %d]",
+			s.Min(), s.Max(), s.First(), s.Last(), s.Sum(), s.Count(), s.FirstTime(), s.LastTime())
+	}
+
+	// page statistics are read back with the time range of the page
+	if !f.HasNextRowGroup() {
+		t.Fatal("expected a row group")
+	}
+	f.ReadRowGroupHeader()
+	chunkHeader := f.ReadChunkHeader()
+	if chunkHeader.GetNumberOfPages() != 5 {
+		t.Fatalf("expected 5 pages, got %d", chunkHeader.GetNumberOfPages())
+	}
+	pageHeader := f.ReadPageHeader(chunkHeader.GetDataType())
+	page := (*pageHeader.GetStatistics()).(statistics.TypedStatistics[int64])
+	if page.Count() != 100 || page.FirstTime() != 10 || page.LastTime() != 109 || page.Min() != 70 || page.Max() != 763 {
+		t.Fatalf("unexpected page statistics: count %d, time [%d, %d], min %d, max %d",
+			page.Count(), page.FirstTime(), page.LastTime(), page.Min(), page.Max())
+	}
+}
+
+func TestChecksums(t *testing.T) {
+	checksumFilePath := tempFile(t, "checksum_TsFile")
+
+	w, _ := NewTsFileWriter(checksumFilePath)
+	des, _ := sensorDescriptor.New("s0", constant.INT64, constant.PLAIN)
+	w.AddSensor(des)
+	writeLongs(w, "root.d0", 1, 101)
+	w.Close()
+
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(checksumFilePath); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	f.ReadFileMetadata()
+	f.ReadRowGroupHeader()
+	f.ReadChunkHeader()
+	pageOffset := f.Pos()
+	f.ReadPageHeader(constant.INT64)
+	dataOffset := f.Pos()
+	footerOffset := f.MetadataPos()
+	f.Close()
+
+	flip := func(offset int64) {
+		file, _ := os.OpenFile(checksumFilePath, os.O_RDWR, 0666)
+		defer file.Close()
+		b := make([]byte, 1)
+		file.ReadAt(b, offset)
+		b[0] ^= 0x10
+		file.WriteAt(b, offset)
+	}
+
+	// a bit of a value in the page
+	flip(dataOffset + 10)
+	f = new(read.TsFileSequenceReader)
+	if err := f.Open(checksumFilePath); err != nil {
+		t.Fatal(err)
+	}
+	err := f.Verify()
+	corruption, ok := err.(*read.CorruptionError)
+	if !ok || corruption.Block != "page" || corruption.Device != "root.d0" || corruption.Sensor != "s0" || corruption.Offset != pageOffset {
+		t.Fatalf("expected a corrupted page of root.d0.s0 at %d, got %v", pageOffset, err)
+	}
+	// the verified reads stop at the page and return its error
+	if err := f.SetVerifyChecksums(true); err != nil {
+		t.Fatal(err)
+	}
+	f.ReadFileMetadata()
+	for f.HasNextRowGroup() {
+		rowGroupHeader := f.ReadRowGroupHeader()
+		for i := 0; i < int(rowGroupHeader.GetNumberOfChunks()); i++ {
+			chunkHeader := f.ReadChunkHeader()
+			for j := 0; j < chunkHeader.GetNumberOfPages(); j++ {
+				f.SkipPage(f.ReadPageHeader(chunkHeader.GetDataType()))
+			}
+		}
+	}
+	if corruption, ok := f.Err().(*read.CorruptionError); !ok || corruption.Block != "page" || corruption.Offset != pageOffset {
+		t.Fatalf("expected a corrupted page at %d, got %v", pageOffset, f.Err())
+	}
+	f.Close()
+	flip(dataOffset + 10)
+
+	// a bit of the footer
+	flip(footerOffset + 5)
+	f = new(read.TsFileSequenceReader)
+	if err := f.Open(checksumFilePath); err != nil {
+		t.Fatal(err)
+	}
+	err = f.SetVerifyChecksums(true)
+	if corruption, ok := err.(*read.CorruptionError); !ok || corruption.Block != "footer" || corruption.Offset != footerOffset {
+		t.Fatalf("expected a corrupted footer at %d, got %v", footerOffset, err)
+	}
+	f.Close()
+}
+
+func TestDeterministicFile(t *testing.T) {
+	write := func(path string) [sha256.Size]byte {
+		w, _ := NewTsFileWriter(path)
+		for _, sensorId := range []string{"s0", "s1", "s2", "s3"} {
+			des, _ := sensorDescriptor.New(sensorId, constant.INT64, constant.PLAIN)
+			w.AddSensor(des)
+		}
+		des, _ := sensorDescriptor.New("s4", constant.DOUBLE, constant.GORILLA)
+		des.SetLossyFilter(constant.SDT, map[string]string{"deviation": "0.5", "max_time": "10"})
+		w.AddDeviceSensor("root.d0", des)
+		for i := 0; i < 200; i++ {
+			for _, device := range []string{"root.d0", "root.d1", "root.d2", "root.d3"} {
+				record, _ := NewTsRecordUseTimestamp(int64(i), device)
+				for j, sensorId := range []string{"s0", "s1", "s2", "s3"} {
+					pt, _ := NewLong(sensorId, constant.INT64, int64(i*j))
+					record.AddTuple(pt)
+				}
+				if device == "root.d0" {
+					pt, _ := NewDouble("s4", constant.DOUBLE, float64(i)/3)
+					record.AddTuple(pt)
+				}
+				w.Write(record)
+			}
+			if i == 100 {
+				w.flushAllRowGroups(false)
+			}
+		}
+		w.Close()
+		data, _ := os.ReadFile(path)
+		return sha256.Sum256(data)
+	}
+
+	first := write(tempFile(t, "deterministic_TsFile"))
+	for i := 0; i < 5; i++ {
+		if write(tempFile(t, "deterministic_TsFile")) != first {
+			t.Fatal("the same data was written to files with different bytes")
+		}
+	}
+}
+
+func TestFileIndex(t *testing.T) {
+	indexFilePath := tempFile(t, "index_TsFile")
+
+	w, _ := NewTsFileWriter(indexFilePath)
+	des, _ := sensorDescriptor.New("s0", constant.INT64, constant.PLAIN)
+	w.AddSensor(des)
+	des, _ = sensorDescriptor.New("s1", constant.DOUBLE, constant.PLAIN)
+	w.AddDeviceSensor("root.d1", des)
+	for i := 0; i < 100; i++ {
+		for _, device := range []string{"root.d0", "root.d1", "root.d2"} {
+			record, _ := NewTsRecordUseTimestamp(int64(i), device)
+			pt, _ := NewLong("s0", constant.INT64, int64(i))
+			record.AddTuple(pt)
+			if device == "root.d1" {
+				pt, _ := NewDouble("s1", constant.DOUBLE, float64(i))
+				record.AddTuple(pt)
+			}
+			w.Write(record)
+		}
+		if i == 50 {
+			w.flushAllRowGroups(false)
+		}
+	}
+	w.Close()
+
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(indexFilePath); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fileMetadata := f.ReadFileMetadata()
+	index := f.ReadIndex()
+	if index == nil {
+		t.Fatal("expected the file to have an index")
+	}
+	if devices := index.Devices(); len(devices) != 3 {
+		t.Fatalf("expected 3 devices in the index, got %v", devices)
+	}
+	for deviceId, deviceMetadata := range fileMetadata.DeviceMap() {
+		device, ok := index.GetDevice(deviceId)
+		if !ok {
+			t.Fatalf("%s is not in the index", deviceId)
+		}
+		if device.StartTime != 0 || device.EndTime != 99 {
+			t.Fatalf("expected %s from 0 to 99, got %d to %d", deviceId, device.StartTime, device.EndTime)
+		}
+		lazy := f.ReadDeviceMetadata(device)
+		if len(lazy.GetRowGroups()) != 2 || len(lazy.GetRowGroups()) != len(deviceMetadata.GetRowGroups()) {
+			t.Fatalf("expected 2 row groups of %s, got %d", deviceId, len(lazy.GetRowGroups()))
+		}
+		if lazy.GetStatistics("s0", constant.INT64).Count() != 100 {
+			t.Fatalf("expected 100 points of %s.s0", deviceId)
+		}
+
+		series := f.ReadSeriesIndex(device)
+		if s1, ok := series["s1"]; ok != (deviceId == "root.d1") || ok && s1.DataType != constant.DOUBLE {
+			t.Fatalf("unexpected s1 of %s: %v", deviceId, s1)
+		}
+		s0 := series["s0"]
+		if s0 == nil || s0.DataType != constant.INT64 || len(s0.Chunks) != 2 {
+			t.Fatalf("expected 2 INT64 chunks of %s.s0, got %v", deviceId, s0)
+		}
+		for i, chunk := range s0.Chunks {
+			chunkHeader := f.ReadChunkHeaderAt(chunk.Offset)
+			if chunkHeader.GetSensor() != "s0" {
+				t.Fatalf("chunk %d of %s.s0 at %d is %s", i, deviceId, chunk.Offset, chunkHeader.GetSensor())
+			}
+		}
+		if s0.Chunks[0].StartTime != 0 || s0.Chunks[0].EndTime != 50 || s0.Chunks[1].StartTime != 51 || s0.Chunks[1].EndTime != 99 {
+			t.Fatalf("unexpected chunks of %s.s0: %v", deviceId, s0.Chunks)
+		}
+	}
+}
+
+func TestBloomFilter(t *testing.T) {
+	bloomFilePath := tempFile(t, "bloom_TsFile")
+	defer func(errorRate float64) { conf.BloomFilterErrorRate = errorRate }(conf.BloomFilterErrorRate)
+	conf.BloomFilterErrorRate = 0.01
+
+	write := func() {
+		os.Remove(bloomFilePath)
+		w, _ := NewTsFileWriter(bloomFilePath)
+		des, _ := sensorDescriptor.New("s0", constant.INT64, constant.PLAIN)
+		w.AddSensor(des)
+		for i := 0; i < 100; i++ {
+			writeLongs(w, "root.d"+strconv.Itoa(i), 1, 3)
+		}
+		w.Close()
+	}
+
+	write()
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(bloomFilePath); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if path := "root.d" + strconv.Itoa(i) + ".s0"; !f.MayContain(path) {
+			t.Fatalf("expected the file to may contain %s", path)
+		}
+	}
+	falsePositives := 0
+	for i := 0; i < 1000; i++ {
+		if f.MayContain("root.d" + strconv.Itoa(i) + ".s1") {
+			falsePositives++
+		}
+	}
+	if falsePositives > 30 {
+		t.Fatalf("expected about 10 false positives of 1000 paths, got %d", falsePositives)
+	}
+	if f.ReadFileMetadata().GetBloomFilter() == nil {
+		t.Fatal("expected the footer to have the bloom filter")
+	}
+	if index := f.ReadIndex(); index == nil || len(index.Devices()) != 100 {
+		t.Fatal("expected the index before the bloom filter")
+	}
+	f.Close()
+
+	// without a bloom filter any path may be in the file
+	conf.BloomFilterErrorRate = 0
+	write()
+	f = new(read.TsFileSequenceReader)
+	if err := f.Open(bloomFilePath); err != nil {
+		t.Fatal(err)
+	}
+	if !f.MayContain("root.d0.s1") || f.ReadFileMetadata().GetBloomFilter() != nil {
+		t.Fatal("expected no bloom filter")
+	}
+	if index := f.ReadIndex(); index == nil || len(index.Devices()) != 100 {
+		t.Fatal("expected the index without a bloom filter")
+	}
+	f.Close()
+}
+
+func TestFormatVersion(t *testing.T) {
+	versionFilePath := tempFile(t, "version_TsFile")
+
+	w, _ := NewTsFileWriter(versionFilePath)
+	if err := w.SetVersion(2); err == nil {
+		t.Fatal("expected version 2 to be unsupported")
+	}
+	if err := w.SetVersion(metadata.VERSION_3); err != nil {
+		t.Fatal(err)
+	}
+	des, _ := sensorDescriptor.New("chimp", constant.DOUBLE, constant.CHIMP)
+	if err := w.AddSensor(des); err == nil {
+		t.Fatal("expected CHIMP not to be in version 3")
+	}
+	des, _ = sensorDescriptor.NewWithCompress("s1", constant.INT64, constant.PLAIN, constant.ZSTD)
+	if err := w.AddSensor(des); err == nil {
+		t.Fatal("expected ZSTD not to be in version 3")
+	}
+	des, _ = sensorDescriptor.New("s1", constant.INT64, constant.PLAIN)
+	des.SetTimeEncoding(constant.PLAIN)
+	if err := w.AddSensor(des); err == nil {
+		t.Fatal("expected times other than TS_2DIFF not to be in version 3")
+	}
+	des, _ = sensorDescriptor.New("s1", constant.INT64, constant.PLAIN)
+	if err := w.AddDeviceSensor("root.d1", des); err == nil {
+		t.Fatal("expected per device sensors not to be in version 3")
+	}
+	des, _ = sensorDescriptor.NewWithCompress("s0", constant.INT64, constant.TS_2DIFF, constant.SNAPPY)
+	w.AddSensor(des)
+	writeLongs(w, "root.d0", 1, 6)
+	w.Close()
+
+	// appending keeps the version
+	w, err := OpenTsFileWriterForAppend(versionFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeLongs(w, "root.d0", 6, 11)
+	w.Close()
+
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(versionFilePath); err != nil {
+		t.Fatal(err)
+	}
+	if f.Version() != metadata.VERSION_3 || f.ReadFileMetadata().GetCurrentVersion() != metadata.VERSION_3 {
+		t.Fatalf("expected version 3, got %d", f.Version())
+	}
+	if f.ReadIndex() != nil || !f.MayContain("root.d9.s9") || f.SetVerifyChecksums(true) == nil {
+		t.Fatal("expected no footer sections in version 3")
+	}
+	f.Close()
+	if points := readAllPoints(t, versionFilePath)["root.d0.s0"]; len(points) != 10 {
+		t.Fatalf("expected 10 points, got %v", points)
+	}
+
+	os.Remove(versionFilePath)
+	w, _ = NewTsFileWriter(versionFilePath)
+	des, _ = sensorDescriptor.New("s0", constant.INT64, constant.PLAIN)
+	w.AddSensor(des)
+	writeLongs(w, "root.d0", 1, 6)
+	w.Close()
+	f = new(read.TsFileSequenceReader)
+	if err := f.Open(versionFilePath); err != nil {
+		t.Fatal(err)
+	}
+	if f.Version() != metadata.LATEST_VERSION {
+		t.Fatalf("expected version %d, got %d", metadata.LATEST_VERSION, f.Version())
+	}
+	f.Close()
+
+	// a version from the future in the FOOTER_CRC section, before the footer size and the magic
+	stat, _ := os.Stat(versionFilePath)
+	file, _ := os.OpenFile(versionFilePath, os.O_RDWR, 0666)
+	file.WriteAt([]byte{0, 0, 0, 9}, stat.Size()-int64(len(conf.MAGIC_STRING)+2*constant.INT_LEN))
+	file.Close()
+	if err := new(read.TsFileSequenceReader).Open(versionFilePath); err == nil || !strings.Contains(err.Error(), "unsupported format version 9") {
+		t.Fatalf("expected version 9 to be unsupported, got %v", err)
+	}
+
+	// not sealed
+	os.Truncate(versionFilePath, stat.Size()-1)
+	if err := new(read.TsFileSequenceReader).Open(versionFilePath); err == nil || !strings.Contains(err.Error(), "not sealed") {
+		t.Fatalf("expected an unsealed file, got %v", err)
+	}
+	os.WriteFile(versionFilePath, []byte("not a tsfile at all, not a tsfile at all"), 0666)
+	if err := new(read.TsFileSequenceReader).Open(versionFilePath); err == nil || !strings.Contains(err.Error(), "not a tsfile") {
+		t.Fatalf("expected no tsfile, got %v", err)
+	}
+}
+
+func TestMergeTsFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{filepath.Join(dir, "merge_TsFile_0"), filepath.Join(dir, "merge_TsFile_1"), filepath.Join(dir, "merge_TsFile_2")}
+	mergedFilePath := filepath.Join(dir, "merged_TsFile")
+
+	plain, _ := sensorDescriptor.New("s0", constant.INT64, constant.PLAIN)
+	w, _ := NewTsFileWriter(files[0])
+	w.AddSensor(plain)
+	writeLongs(w, "root.d0", 1, 101)
+	writeLongs(w, "root.d1", 1, 51)
+	w.Close()
+	// overlaps the points 51 to 100 of root.d0 with other values and encoding
+	rle, _ := sensorDescriptor.New("s0", constant.INT64, constant.RLE)
+	w, _ = NewTsFileWriter(files[1])
+	w.AddSensor(rle)
+	for i := 51; i < 151; i++ {
+		record, _ := NewTsRecordUseTimestamp(int64(i), "root.d0")
+		pt, _ := NewLong("s0", constant.INT64, int64(i*10))
+		record.AddTuple(pt)
+		w.Write(record)
+	}
+	w.Close()
+	// the latest file of root.d0 decides the encoding of its merged chunks
+	diff, _ := sensorDescriptor.New("s0", constant.INT64, constant.TS_2DIFF)
+	w, _ = NewTsFileWriter(files[2])
+	w.AddSensor(diff)
+	writeLongs(w, "root.d0", 200, 251)
+	w.Close()
+
+	report, err := MergeTsFiles(files, mergedFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.RowGroups != 3 || report.CopiedChunks != 2 || report.RewrittenChunks != 1 ||
+		report.Points != 251 || report.DuplicatePoints != 50 {
+		t.Fatalf("expected the chunks of root.d0 of the first two files merged, got %+v", report)
+	}
+	if problems := read.Check(mergedFilePath); len(problems) != 0 {
+		t.Fatalf("expected a valid file, got %v", problems)
+	}
+	points := readAllPoints(t, mergedFilePath)
+	d0 := points["root.d0.s0"]
+	if len(d0) != 201 || len(points["root.d1.s0"]) != 50 {
+		t.Fatalf("expected 201 and 50 points, got %d and %d", len(d0), len(points["root.d1.s0"]))
+	}
+	if d0[49] != int64(50) || d0[50] != int64(510) || d0[149] != int64(1500) || d0[150] != int64(200) {
+		t.Fatalf("expected the values of the later file, got %v", d0)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(mergedFilePath); err != nil {
+		t.Fatal(err)
+	}
+	f.ReadRowGroupHeader()
+	if chunkHeader := f.ReadChunkHeader(); chunkHeader.GetEncodingType() != constant.TS_2DIFF {
+		t.Fatalf("expected the merged chunk in the encoding of the latest file, got %v", chunkHeader.GetEncodingType())
+	}
+	f.Close()
+
+	// the out file is never appended to
+	if _, err := MergeTsFiles(files, mergedFilePath); err == nil {
+		t.Fatal("expected the out file to exist")
+	}
+	data, _ := os.ReadFile(files[0])
+	if _, err := MergeTsFiles(files, files[0]); err == nil {
+		t.Fatal("expected an input not to be the out file")
+	}
+	if again, _ := os.ReadFile(files[0]); !bytes.Equal(again, data) {
+		t.Fatal("the input should be left untouched")
+	}
+
+	os.Remove(mergedFilePath)
+	if _, err := MergeTsFiles([]string{files[0], filepath.Join(dir, "missing_TsFile")}, mergedFilePath); err == nil {
+		t.Fatal("expected a missing file")
+	}
+}
+
+func TestMergeTsFilesPages(t *testing.T) {
+	defer func(pageSize int) { conf.PageSizeInByte = pageSize }(conf.PageSizeInByte)
+	conf.PageSizeInByte = 256
+	dir := t.TempDir()
+	files := []string{filepath.Join(dir, "merge_TsFile_0"), filepath.Join(dir, "merge_TsFile_1")}
+	mergedFilePath := filepath.Join(dir, "merged_TsFile")
+
+	des, _ := sensorDescriptor.New("s0", constant.INT64, constant.PLAIN)
+	w, _ := NewTsFileWriter(files[0])
+	w.AddSensor(des)
+	writeLongs(w, "root.d0", 0, 1000)
+	w.Close()
+	// the odd times again, negated
+	w, _ = NewTsFileWriter(files[1])
+	w.AddSensor(des)
+	for i := 1; i < 1000; i += 2 {
+		record, _ := NewTsRecordUseTimestamp(int64(i), "root.d0")
+		pt, _ := NewLong("s0", constant.INT64, int64(-i))
+		record.AddTuple(pt)
+		w.Write(record)
+	}
+	w.Close()
+
+	// the chunks of both files have many pages, walked together
+	report, err := MergeTsFiles(files, mergedFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.RewrittenChunks != 1 || report.Points != 1000 || report.DuplicatePoints != 500 {
+		t.Fatalf("expected one merged chunk of 1000 points, got %+v", report)
+	}
+	points := readAllPoints(t, mergedFilePath)["root.d0.s0"]
+	if len(points) != 1000 {
+		t.Fatalf("expected 1000 points, got %d", len(points))
+	}
+	for i, v := range points {
+		if expected := int64(i); (i%2 == 0 && v != expected) || (i%2 == 1 && v != -expected) {
+			t.Fatalf("point %d: got %v", i, v)
+		}
+	}
+}
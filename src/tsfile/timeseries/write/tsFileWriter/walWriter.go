@@ -0,0 +1,304 @@
+package tsFileWriter
+
+import (
+	"bytes"
+	"errors"
+	"hash/crc32"
+	"io/ioutil"
+	"math"
+	"os"
+	"tsfile/common/constant"
+	"tsfile/common/utils"
+	"tsfile/timeseries/write/fileSchema"
+	"tsfile/timeseries/write/sensorDescriptor"
+)
+
+/**
+ * write ahead log of a TsFileWriter. Every sensor and record is appended here
+ * before it is buffered in memory, so the data that has not reached a row group
+ * yet can be replayed after a crash. The log is rewritten after each row group
+ * flush, it only keeps the format version, the schema and the records written
+ * since then.
+ *
+ * layout: magic | basePos(int64) | entry ...
+ * entry:  length(int32) | type(byte) | payload | crc32(int32)
+ * basePos is the file position where the logged records start, i.e. the end
+ * of the last row group flushed before the log was reset.
+ */
+
+const (
+	WAL_SUFFIX = ".wal"
+	WAL_MAGIC  = "TsFileWAL"
+
+	walSensorEntry       byte = 1
+	walRecordEntry       byte = 2
+	walDeviceSensorEntry byte = 3
+	walVersionEntry      byte = 4
+)
+
+type WalWriter struct {
+	path    string
+	walFile *os.File
+	memBuf  *bytes.Buffer
+}
+
+func (w *WalWriter) GetPath() string {
+	return w.path
+}
+
+func (w *WalWriter) WriteSensor(sd *sensorDescriptor.SensorDescriptor) error {
+	payload := bytes.NewBuffer([]byte{})
+	writeWalSensor(payload, sd)
+	return w.writeEntry(walSensorEntry, payload.Bytes())
+}
+
+func (w *WalWriter) WriteDeviceSensor(deviceId string, sd *sensorDescriptor.SensorDescriptor) error {
+	payload := bytes.NewBuffer([]byte{})
+	writeWalString(payload, deviceId)
+	writeWalSensor(payload, sd)
+	return w.writeEntry(walDeviceSensorEntry, payload.Bytes())
+}
+
+// WriteVersion logs the version of the format the tsfile is written in
+func (w *WalWriter) WriteVersion(version int) error {
+	return w.writeEntry(walVersionEntry, utils.Int32ToByte(int32(version), 0))
+}
+
+func (w *WalWriter) WriteRecord(tr *TsRecord) error {
+	payload := bytes.NewBuffer([]byte{})
+	writeWalString(payload, tr.GetDeviceId())
+	payload.Write(utils.Int64ToByte(tr.GetTime(), 0))
+	payload.Write(utils.Int32ToByte(int32(len(tr.GetDataPointSli())), 0))
+	for _, dp := range tr.GetDataPointSli() {
+		writeWalString(payload, dp.GetSensorId())
+		switch value := dp.value.(type) {
+		case bool:
+			payload.WriteByte(byte(constant.BOOLEAN))
+			payload.Write(utils.BoolToByte(value, 0))
+		case int32:
+			payload.WriteByte(byte(constant.INT32))
+			payload.Write(utils.Int32ToByte(value, 0))
+		case int64:
+			payload.WriteByte(byte(constant.INT64))
+			payload.Write(utils.Int64ToByte(value, 0))
+		case float32:
+			payload.WriteByte(byte(constant.FLOAT))
+			payload.Write(utils.Float32ToByte(value, 0))
+		case float64:
+			payload.WriteByte(byte(constant.DOUBLE))
+			payload.Write(utils.Float64ToByte(value, 0))
+		case string:
+			payload.WriteByte(byte(constant.TEXT))
+			writeWalString(payload, value)
+		default:
+			return errors.New("wal: unsupported value type of sensor " + dp.GetSensorId())
+		}
+	}
+	return w.writeEntry(walRecordEntry, payload.Bytes())
+}
+
+func (w *WalWriter) writeEntry(entryType byte, payload []byte) error {
+	w.memBuf.Reset()
+	w.memBuf.Write(utils.Int32ToByte(int32(len(payload)), 0))
+	w.memBuf.WriteByte(entryType)
+	w.memBuf.Write(payload)
+	crc := crc32.ChecksumIEEE(w.memBuf.Bytes()[constant.INT_LEN:])
+	w.memBuf.Write(utils.Int32ToByte(int32(crc), 0))
+	// one write call per entry, a crash can only tear the last entry
+	_, err := w.walFile.Write(w.memBuf.Bytes())
+	return err
+}
+
+// Reset drops the logged records once they are flushed to the tsfile ending
+// at basePos. The new log is written aside and renamed, so a crash in between
+// leaves either the old or the new log.
+func (w *WalWriter) Reset(basePos int64, version int, fs *fileSchema.FileSchema) error {
+	tmpPath := w.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	w.walFile.Close()
+	w.walFile = tmp
+	if err := w.writeHeader(basePos); err != nil {
+		return err
+	}
+	if err := w.WriteVersion(version); err != nil {
+		return err
+	}
+	for _, sd := range fs.GetSensorDescriptiorMap() {
+		if err := w.WriteSensor(sd); err != nil {
+			return err
+		}
+	}
+	for deviceId, sensors := range fs.GetDeviceSensorDescriptorMap() {
+		for _, sd := range sensors {
+			if err := w.WriteDeviceSensor(deviceId, sd); err != nil {
+				return err
+			}
+		}
+	}
+	return os.Rename(tmpPath, w.path)
+}
+
+func (w *WalWriter) writeHeader(basePos int64) error {
+	w.memBuf.Reset()
+	w.memBuf.Write([]byte(WAL_MAGIC))
+	w.memBuf.Write(utils.Int64ToByte(basePos, 0))
+	_, err := w.walFile.Write(w.memBuf.Bytes())
+	return err
+}
+
+func (w *WalWriter) Sync() error {
+	return w.walFile.Sync()
+}
+
+func (w *WalWriter) Close() error {
+	return w.walFile.Close()
+}
+
+// Remove closes and deletes the log, called once the tsfile is sealed.
+func (w *WalWriter) Remove() error {
+	w.walFile.Close()
+	return os.Remove(w.path)
+}
+
+func writeWalSensor(buf *bytes.Buffer, sd *sensorDescriptor.SensorDescriptor) {
+	writeWalString(buf, sd.GetSensorId())
+	buf.Write(utils.Int16ToByte(sd.GetTsDataType(), 0))
+	buf.Write(utils.Int16ToByte(sd.GetTsEncoding(), 0))
+	buf.Write(utils.Int16ToByte(sd.GetCompresstionType(), 0))
+	buf.Write(utils.Int16ToByte(sd.GetTimeEncoding(), 0))
+	filterType, params := sd.GetLossyFilter()
+	buf.Write(utils.Int16ToByte(filterType, 0))
+	buf.Write(utils.Int32ToByte(int32(len(params)), 0))
+	for _, k := range utils.SortedKeys(params) {
+		writeWalString(buf, k)
+		writeWalString(buf, params[k])
+	}
+	buf.Write(utils.Int32ToByte(int32(sd.GetCompressionLevel()), 0))
+}
+
+func writeWalString(buf *bytes.Buffer, s string) {
+	buf.Write(utils.Int32ToByte(int32(len(s)), 0))
+	buf.Write([]byte(s))
+}
+
+func NewWalWriter(tsFilePath string, basePos int64) (*WalWriter, error) {
+	path := tsFilePath + WAL_SUFFIX
+	walFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, err
+	}
+	w := &WalWriter{
+		path:    path,
+		walFile: walFile,
+		memBuf:  bytes.NewBuffer([]byte{}),
+	}
+	if err := w.writeHeader(basePos); err != nil {
+		walFile.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// WalLog is the content of a write ahead log read back for recovery.
+type WalLog struct {
+	BasePos int64
+	// the version of the format, 0 if not logged
+	Version       int
+	Sensors       []*sensorDescriptor.SensorDescriptor
+	DeviceSensors map[string][]*sensorDescriptor.SensorDescriptor
+	Records       []*TsRecord
+}
+
+// ReadWal parses the log of the given tsfile. A torn or corrupted entry at the
+// tail is the last write before a crash, it and everything after it is ignored.
+func ReadWal(tsFilePath string) (*WalLog, error) {
+	data, err := ioutil.ReadFile(tsFilePath + WAL_SUFFIX)
+	if err != nil {
+		return nil, err
+	}
+	headerSize := len(WAL_MAGIC) + constant.LONG_LEN
+	if len(data) < headerSize || string(data[:len(WAL_MAGIC)]) != WAL_MAGIC {
+		return nil, errors.New("wal: invalid header of " + tsFilePath + WAL_SUFFIX)
+	}
+	reader := utils.NewBytesReader(data[len(WAL_MAGIC):])
+	walLog := &WalLog{BasePos: reader.ReadLong(), DeviceSensors: make(map[string][]*sensorDescriptor.SensorDescriptor)}
+	for reader.Len() >= constant.INT_LEN+1+constant.INT_LEN {
+		length := int(reader.ReadInt())
+		if length < 0 || reader.Len() < 1+length+constant.INT_LEN {
+			break
+		}
+		body := reader.ReadSlice(1 + length)
+		if uint32(reader.ReadInt()) != crc32.ChecksumIEEE(body) {
+			break
+		}
+		switch body[0] {
+		case walSensorEntry:
+			walLog.Sensors = append(walLog.Sensors, readWalSensor(utils.NewBytesReader(body[1:])))
+		case walDeviceSensorEntry:
+			entryReader := utils.NewBytesReader(body[1:])
+			deviceId := entryReader.ReadString()
+			walLog.DeviceSensors[deviceId] = append(walLog.DeviceSensors[deviceId], readWalSensor(entryReader))
+		case walRecordEntry:
+			walLog.Records = append(walLog.Records, readWalRecord(utils.NewBytesReader(body[1:])))
+		case walVersionEntry:
+			walLog.Version = int(utils.NewBytesReader(body[1:]).ReadInt())
+		}
+	}
+	return walLog, nil
+}
+
+func readWalSensor(reader *utils.BytesReader) *sensorDescriptor.SensorDescriptor {
+	sensorId := reader.ReadString()
+	tsDataType := reader.ReadShort()
+	tsEncoding := reader.ReadShort()
+	compressionType := reader.ReadShort()
+	sd, _ := sensorDescriptor.NewWithCompress(sensorId, constant.TSDataType(tsDataType),
+		constant.TSEncoding(tsEncoding), constant.CompressionType(compressionType))
+	// logs written before the time encoding was logged use the configured one
+	if reader.Len() >= constant.SHORT_LEN {
+		sd.SetTimeEncoding(constant.TSEncoding(reader.ReadShort()))
+	}
+	if reader.Len() >= constant.SHORT_LEN+constant.INT_LEN {
+		filterType := constant.CompressionType(reader.ReadShort())
+		size := int(reader.ReadInt())
+		params := make(map[string]string, size)
+		for i := 0; i < size; i++ {
+			k := reader.ReadString()
+			params[k] = reader.ReadString()
+		}
+		sd.SetLossyFilter(filterType, params)
+	}
+	if reader.Len() >= constant.INT_LEN {
+		sd.SetCompressionLevel(int(reader.ReadInt()))
+	}
+	return sd
+}
+
+func readWalRecord(reader *utils.BytesReader) *TsRecord {
+	deviceId := reader.ReadString()
+	tr, _ := NewTsRecordUseTimestamp(reader.ReadLong(), deviceId)
+	size := int(reader.ReadInt())
+	for i := 0; i < size; i++ {
+		dp, _ := NewDataPoint()
+		sensorId := reader.ReadString()
+		switch constant.TSDataType(reader.Read()) {
+		case constant.BOOLEAN:
+			dp.SetValue(sensorId, reader.ReadBool())
+		case constant.INT32:
+			dp.SetValue(sensorId, reader.ReadInt())
+		case constant.INT64:
+			dp.SetValue(sensorId, reader.ReadLong())
+		case constant.FLOAT:
+			dp.SetValue(sensorId, math.Float32frombits(uint32(reader.ReadInt())))
+		case constant.DOUBLE:
+			dp.SetValue(sensorId, math.Float64frombits(uint64(reader.ReadLong())))
+		case constant.TEXT:
+			dp.SetValue(sensorId, reader.ReadString())
+		}
+		tr.AddTuple(dp)
+	}
+	return tr
+}